@@ -0,0 +1,99 @@
+package dsse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strconv"
+)
+
+// ErrAADMismatch indicates that the additional authenticated data of an
+// envelope is not the expected one.
+var ErrAADMismatch = errors.New("additional authenticated data mismatch")
+
+/*
+PAEWithAAD extends the pre-authentication encoding with additional
+authenticated data, which binds a signature to context that is not part of
+the payload, such as a workflow run ID. The data is appended to PAE as a
+further length-prefixed field:
+
+	PAE(payloadType, payload) + SP + LEN(aad) + SP + aad
+
+Without additional authenticated data the result is identical to PAE, so
+envelopes without it remain interoperable with other DSSE implementations.
+*/
+func PAEWithAAD(payloadType string, payload, aad []byte) []byte {
+	if len(aad) == 0 {
+		return PAE(payloadType, payload)
+	}
+
+	n := len("DSSEv1") + 5 + 3*20 + len(payloadType) + len(payload) + len(aad)
+	buf := PAEInto(make([]byte, 0, n), payloadType, payload)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(len(aad)), 10)
+	buf = append(buf, ' ')
+
+	return append(buf, aad...)
+}
+
+/*
+SignPayloadWithAAD is like SignPayload, but the signatures also cover the
+additional authenticated data aad, see PAEWithAAD. The data is stored in the
+AAD field of the envelope, and a verifier must expect the same data, see
+VerifyWithAAD. An empty aad yields the same envelope as SignPayload.
+*/
+func (es *EnvelopeSigner) SignPayloadWithAAD(payloadType string, body, aad []byte) (*Envelope, error) {
+	payloadType = es.opts.normalizePayloadType(payloadType)
+	if err := ValidatePayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	if err := es.checkSignerPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	es.opts.notePayloadType(payloadType)
+
+	signatures, err := es.signPAE(PAEWithAAD(payloadType, body, aad))
+	if err != nil {
+		return nil, err
+	}
+
+	var encoded string
+	if len(aad) > 0 {
+		encoded = base64.StdEncoding.EncodeToString(aad)
+	}
+
+	return &Envelope{
+		PayloadType: payloadType,
+		Payload:     es.opts.encode(body),
+		AAD:         encoded,
+		Signatures:  signatures,
+	}, nil
+}
+
+// VerifyWithAAD verifies e, which must carry the additional authenticated
+// data aad. See the VerifyWithAAD method of the envelope verifier.
+func (es *EnvelopeSigner) VerifyWithAAD(e *Envelope, aad []byte) ([]AcceptedKey, error) {
+	return es.ev.VerifyWithAAD(e, aad)
+}
+
+// decodedAAD returns the additional authenticated data of the envelope.
+func (e *Envelope) decodedAAD() ([]byte, error) {
+	if e.AAD == "" {
+		return nil, nil
+	}
+
+	return b64Decode(e.AAD)
+}
+
+// checkAAD returns ErrAADMismatch unless the envelope carries aad.
+func (e *Envelope) checkAAD(aad []byte) error {
+	got, err := e.decodedAAD()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(got, aad) {
+		return ErrAADMismatch
+	}
+
+	return nil
+}
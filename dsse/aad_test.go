@@ -0,0 +1,86 @@
+package dsse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPAEWithAAD(t *testing.T) {
+	assert.Equal(t, PAE("type", []byte("payload")), PAEWithAAD("type", []byte("payload"), nil), "wrong pae without aad")
+	assert.Equal(t, []byte("DSSEv1 4 type 7 payload 6 run-42"), PAEWithAAD("type", []byte("payload"), []byte("run-42")), "wrong pae")
+}
+
+func TestSignPayloadWithAAD(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+	var aad = []byte("workflow run 42")
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	es, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := es.SignPayloadWithAAD(payloadType, payload, aad)
+	assert.Nil(t, err, "sign failed")
+	assert.NotEmpty(t, env.AAD, "aad not stored")
+
+	acceptedKeys, err := es.VerifyWithAAD(env, aad)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ed", acceptedKeys[0].KeyID, "wrong keyid")
+
+	t.Run("Wrong AAD", func(t *testing.T) {
+		_, err := es.VerifyWithAAD(env, []byte("workflow run 43"))
+		assert.Equal(t, ErrAADMismatch, err, "wrong error")
+	})
+
+	t.Run("AAD not expected", func(t *testing.T) {
+		_, err := es.Verify(env)
+		assert.Equal(t, ErrAADMismatch, err, "wrong error")
+	})
+
+	t.Run("AAD replaced", func(t *testing.T) {
+		other, err := es.SignPayloadWithAAD(payloadType, payload, []byte("workflow run 43"))
+		assert.Nil(t, err, "sign failed")
+		tampered := *env
+		tampered.Signatures = other.Signatures
+
+		_, err = es.VerifyWithAAD(&tampered, aad)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, ErrSignatureInvalid, verr.Err, "wrong error")
+	})
+
+	t.Run("AAD stripped", func(t *testing.T) {
+		stripped := *env
+		stripped.AAD = ""
+
+		_, err := es.Verify(&stripped)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+	})
+
+	t.Run("No AAD", func(t *testing.T) {
+		got, err := es.SignPayloadWithAAD(payloadType, payload, nil)
+		assert.Nil(t, err, "sign failed")
+		want, err := es.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+		assert.Equal(t, want, got, "wrong envelope")
+	})
+
+	t.Run("Append signature", func(t *testing.T) {
+		other, err := NewECDSASignerVerifier("ec", newEcdsaKey())
+		assert.Nil(t, err, "unexpected error")
+		second, err := NewEnvelopeSigner(other)
+		assert.Nil(t, err, "unexpected error")
+
+		got, err := second.AppendSignature(env, sv)
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierRequireAll(sv, other)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.VerifyWithAAD(got, aad)
+		assert.Nil(t, err, "unexpected error")
+	})
+}
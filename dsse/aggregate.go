@@ -0,0 +1,48 @@
+package dsse
+
+/*
+AggregateVerifier is implemented by verifiers for schemes that do not fit the
+model of one signature per key, such as aggregate or threshold signatures. An
+envelope verifier passes all signatures of an envelope to VerifyAggregate at
+once, instead of offering each signature to Verify. The keys it accepts count
+towards the threshold like the keys of other verifiers, and signatures with
+their key IDs are not offered to other verifiers.
+The accepted keys record the signature with the same key ID, if any.
+Aggregate verifiers need the full pre-authentication encoding, so VerifyStream
+buffers the payload if one is configured.
+*/
+type AggregateVerifier interface {
+	Verifier
+	// VerifyAggregate verifies the signatures over the pre-authentication
+	// encoding pae and returns the key IDs of the keys it accepts. An error
+	// means that the signatures were rejected.
+	VerifyAggregate(pae []byte, signatures []Signature) ([]string, error)
+}
+
+// verifyAggregate returns the keys of the aggregate verifier v that accept the
+// signatures.
+func (ev *envelopeVerifier) verifyAggregate(v AggregateVerifier, msg *message, signatures []Signature) ([]AcceptedKey, error) {
+	if msg.pae == nil {
+		return nil, ErrStreamingUnsupported
+	}
+
+	ev.opts.debugVerify(verifierKeyID(v), msg.pae)
+	keyIDs, err := v.VerifyAggregate(msg.pae, signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make([]AcceptedKey, 0, len(keyIDs))
+	for _, keyID := range keyIDs {
+		k := AcceptedKey{Public: v.Public(), KeyID: keyID}
+		for _, s := range signatures {
+			if s.KeyID == keyID {
+				k.Sig = s
+				break
+			}
+		}
+		accepted = append(accepted, k)
+	}
+
+	return accepted, nil
+}
@@ -0,0 +1,131 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+groupVerifier is a toy aggregate scheme: the signature of the group is the
+concatenation of Ed25519 signatures of its members, in order.
+*/
+type groupVerifier struct {
+	members map[string]ed25519.PublicKey
+	order   []string
+}
+
+func (g *groupVerifier) VerifyAggregate(pae []byte, signatures []Signature) ([]string, error) {
+	for _, s := range signatures {
+		if s.KeyID != "group" {
+			continue
+		}
+		sig, err := b64Decode(s.Sig)
+		if err != nil {
+			return nil, err
+		}
+		if len(sig) != len(g.order)*ed25519.SignatureSize {
+			return nil, ErrSignatureInvalid
+		}
+
+		var accepted []string
+		for i, keyID := range g.order {
+			part := sig[i*ed25519.SignatureSize : (i+1)*ed25519.SignatureSize]
+			if ed25519.Verify(g.members[keyID], pae, part) {
+				accepted = append(accepted, keyID)
+			}
+		}
+		return accepted, nil
+	}
+
+	return nil, nil
+}
+
+func (g *groupVerifier) Verify(data, sig []byte) error {
+	return errors.New("aggregate only")
+}
+
+func (g *groupVerifier) KeyID() (string, error) {
+	return "group", nil
+}
+
+func (g *groupVerifier) Public() crypto.PublicKey {
+	return nil
+}
+
+func TestAggregateVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+	pae := PAE(payloadType, payload)
+
+	g := &groupVerifier{members: map[string]ed25519.PublicKey{}}
+	var keys []ed25519.PrivateKey
+	for _, keyID := range []string{"alice", "bob", "carol"} {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = keyID[0]
+		key := ed25519.NewKeyFromSeed(seed)
+		keys = append(keys, key)
+		g.members[keyID] = key.Public().(ed25519.PublicKey)
+		g.order = append(g.order, keyID)
+	}
+
+	var sig []byte
+	for _, key := range keys {
+		sig = append(sig, ed25519.Sign(key, pae)...)
+	}
+	env := &Envelope{
+		PayloadType: payloadType,
+		Payload:     "aGVsbG8gd29ybGQ=",
+		Signatures:  []Signature{{KeyID: "group", Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}
+
+	ev, err := NewMultiEnvelopeVerifier(3, g)
+	assert.Nil(t, err, "unexpected error")
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 3, "unexpected keys")
+	assert.Equal(t, "alice", acceptedKeys[0].KeyID, "wrong keyid")
+	assert.Empty(t, acceptedKeys[0].Sig.Sig, "unexpected signature")
+
+	t.Run("Mixed with other verifiers", func(t *testing.T) {
+		sv, err := NewEd25519SignerVerifier("dave", newEd25519Key())
+		assert.Nil(t, err, "unexpected error")
+		daveSig, err := sv.Sign(pae)
+		assert.Nil(t, err, "sign failed")
+
+		mixed := *env
+		mixed.Signatures = append([]Signature{{KeyID: "dave", Sig: base64.StdEncoding.EncodeToString(daveSig)}}, env.Signatures...)
+		var reported []string
+		ev, err := NewEnvelopeVerifierWithOptions(4, []Verifier{sv, g},
+			WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+				assert.True(t, ok, "signature rejected")
+				reported = append(reported, keyID)
+			}))
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(&mixed)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 4, "unexpected keys")
+		assert.Equal(t, []string{"dave", "group"}, reported, "unexpected callback results")
+	})
+
+	t.Run("Threshold not met", func(t *testing.T) {
+		partial := *env
+		copy(sig[ed25519.SignatureSize:], make([]byte, ed25519.SignatureSize))
+		partial.Signatures = []Signature{{KeyID: "group", Sig: base64.StdEncoding.EncodeToString(sig)}}
+		_, err := ev.Verify(&partial)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, 2, verr.Found, "wrong number of accepted keys")
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		bad := *env
+		bad.Signatures = []Signature{{KeyID: "group", Sig: "c2ln"}}
+		_, err := ev.Verify(&bad)
+		assert.True(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
+	})
+}
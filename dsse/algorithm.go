@@ -0,0 +1,134 @@
+package dsse
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupportedHash indicates that a hash function cannot be used by a
+// signer.
+var ErrUnsupportedHash = errors.New("unsupported hash")
+
+// ErrDisallowedAlgorithm indicates that a signature uses an algorithm that is
+// not allowed by the verifier.
+var ErrDisallowedAlgorithm = errors.New("disallowed algorithm")
+
+// ErrAlgorithmMismatch indicates that a signature was made with a different
+// algorithm than the verifier uses.
+var ErrAlgorithmMismatch = errors.New("algorithm mismatch")
+
+// ExtensionAlgorithm is the signature extension that records the algorithm
+// of a signature.
+const ExtensionAlgorithm = "alg"
+
+/*
+AlgorithmProvider is implemented by signers and verifiers that can name their
+signature algorithm, for example "ecdsa-p256-sha256" or "rsa-pss-sha512".
+When signing, the name is recorded in the ExtensionAlgorithm extension of the
+signature. When verifying, a signature whose recorded algorithm differs from
+the one of the verifier is rejected with ErrAlgorithmMismatch instead of being
+checked with the wrong hash.
+*/
+type AlgorithmProvider interface {
+	Algorithm() string
+}
+
+// checkHash returns an error unless h is one of the hashes supported by the
+// bundled signers.
+func checkHash(h crypto.Hash) error {
+	switch h {
+	case crypto.SHA256, crypto.SHA384, crypto.SHA512:
+		if h.Available() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnsupportedHash, h)
+}
+
+// hashName returns the name of h as used in algorithm names.
+func hashName(h crypto.Hash) string {
+	switch h {
+	case crypto.SHA256:
+		return "sha256"
+	case crypto.SHA384:
+		return "sha384"
+	case crypto.SHA512:
+		return "sha512"
+	}
+
+	return ""
+}
+
+// algorithmHash returns the hash named at the end of an algorithm name, such
+// as SHA-384 for "rsa-pss-sha384".
+func algorithmHash(alg string) (crypto.Hash, bool) {
+	for _, h := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		if strings.HasSuffix(alg, "-"+hashName(h)) {
+			return h, true
+		}
+	}
+
+	return 0, false
+}
+
+// algorithmExtensions returns the extensions recording the algorithm of p, if
+// any.
+func algorithmExtensions(p interface{}) map[string]json.RawMessage {
+	ap, ok := p.(AlgorithmProvider)
+	if !ok || ap.Algorithm() == "" {
+		return nil
+	}
+
+	alg, err := json.Marshal(ap.Algorithm())
+	if err != nil {
+		return nil
+	}
+
+	return map[string]json.RawMessage{ExtensionAlgorithm: alg}
+}
+
+// signatureAlgorithm returns the algorithm recorded in s, if any.
+func signatureAlgorithm(s Signature) (string, bool, error) {
+	raw, ok := s.Extensions[ExtensionAlgorithm]
+	if !ok {
+		return "", false, nil
+	}
+
+	var alg string
+	if err := json.Unmarshal(raw, &alg); err != nil {
+		return "", true, fmt.Errorf("%w: invalid %s extension", ErrAlgorithmMismatch, ExtensionAlgorithm)
+	}
+
+	return alg, true, nil
+}
+
+// verifierAlgorithm returns the algorithm named by v, or the empty string.
+func verifierAlgorithm(v Verifier) string {
+	if ap, ok := v.(AlgorithmProvider); ok {
+		return ap.Algorithm()
+	}
+
+	return ""
+}
+
+// checkAlgorithm returns ErrAlgorithmMismatch if s records an algorithm that
+// differs from the one of v.
+func checkAlgorithm(v Verifier, s Signature) error {
+	alg, ok, err := signatureAlgorithm(s)
+	if !ok || verifierAlgorithm(v) == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if alg != verifierAlgorithm(v) {
+		return fmt.Errorf("%w: signature uses %s, verifier uses %s", ErrAlgorithmMismatch, alg, verifierAlgorithm(v))
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignerHash(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, h := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+		ecSV, err := NewECDSASignerVerifier("ec", ecKey, WithECDSAHash(h))
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, h, ecSV.HashFunc(), "wrong hash")
+		assert.Equal(t, "ecdsa-p256-"+hashName(h), ecSV.Algorithm(), "wrong algorithm")
+
+		rsaSV, err := NewRSAPSSSignerVerifier("rsa", rsaKey, WithRSAPSSHash(h))
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, h, rsaSV.HashFunc(), "wrong hash")
+		assert.Equal(t, "rsa-pss-"+hashName(h), rsaSV.Algorithm(), "wrong algorithm")
+
+		signer, err := NewMultiEnvelopeSigner(2, ecSV, rsaSV)
+		assert.Nil(t, err, "unexpected error")
+
+		env, err := signer.SignPayload(payloadType, []byte(payload))
+		assert.Nil(t, err, "sign failed")
+
+		var alg string
+		assert.Nil(t, json.Unmarshal(env.Signatures[0].Extensions[ExtensionAlgorithm], &alg), "unexpected error")
+		assert.Equal(t, ecSV.Algorithm(), alg, "wrong recorded algorithm")
+
+		acceptedKeys, err := signer.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2, "unexpected keys")
+	}
+}
+
+func TestUnsupportedHash(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, h := range []crypto.Hash{0, crypto.MD5, crypto.SHA1, crypto.Hash(999)} {
+		_, err = NewECDSASignerVerifier("", ecKey, WithECDSAHash(h))
+		assert.True(t, errors.Is(err, ErrUnsupportedHash), "wrong error")
+
+		_, err = NewRSAPSSVerifier("", &rsaKey.PublicKey, WithRSAPSSHash(h))
+		assert.True(t, errors.Is(err, ErrUnsupportedHash), "wrong error")
+	}
+}
+
+func TestAlgorithmMismatch(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+
+	sv, err := NewECDSASignerVerifier("ec", key, WithECDSAHash(crypto.SHA512))
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	v, err := NewECDSAVerifier("ec", &key.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	ev, err := NewEnvelopeVerifier(v)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = ev.Verify(env)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch), "wrong error")
+
+	var verr *VerificationError
+	assert.True(t, errors.As(err, &verr), "wrong error type")
+
+	// Without the recorded algorithm the signature simply does not verify.
+	env.Signatures[0].Extensions = nil
+	_, err = ev.Verify(env)
+	assert.True(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
+
+	err = v.VerifyDigest(make([]byte, 64), crypto.SHA512, []byte("sig"))
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch), "wrong error")
+}
+
+func TestWithAllowedAlgorithms(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	ecSV, err := NewECDSASignerVerifier("ec", ecKey)
+	assert.Nil(t, err, "unexpected error")
+	edSV, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	signer, err := NewEnvelopeSigner(ecSV)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	t.Run("allowed", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ecSV}, WithAllowedAlgorithms("ecdsa-p256-sha256", "ed25519"))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("disallowed signature", func(t *testing.T) {
+		var cbErr error
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ecSV, edSV},
+			WithAllowedAlgorithms("ed25519"),
+			WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+				cbErr = err
+			}))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrDisallowedAlgorithm), "wrong error")
+		assert.True(t, errors.Is(cbErr, ErrDisallowedAlgorithm), "wrong callback error")
+	})
+
+	t.Run("invalid signature algorithm", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ecSV}, WithAllowedAlgorithms("ecdsa-p256-sha256"))
+		assert.Nil(t, err, "unexpected error")
+
+		invalid := *env
+		invalid.Signatures = []Signature{env.Signatures[0]}
+		invalid.Signatures[0].Extensions = map[string]json.RawMessage{ExtensionAlgorithm: json.RawMessage(`1`)}
+		_, err = ev.Verify(&invalid)
+		assert.True(t, errors.Is(err, ErrDisallowedAlgorithm), "wrong error")
+	})
+
+	t.Run("disallowed verifier", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ecSV}, WithAllowedAlgorithms("ed25519"))
+		assert.Nil(t, err, "unexpected error")
+
+		unrecorded := *env
+		unrecorded.Signatures = []Signature{env.Signatures[0]}
+		unrecorded.Signatures[0].Extensions = nil
+		_, err = ev.Verify(&unrecorded)
+		assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+	})
+}
@@ -0,0 +1,114 @@
+package dsse
+
+import (
+	"encoding/hex"
+	"time"
+)
+
+/*
+AuditSink receives a record of every verification decision of an envelope
+verifier, see WithAuditSink. Record is called synchronously, once per call to
+Verify, VerifyStream or VerifyMultiPayload, and must be safe for concurrent
+use if the verifier is.
+*/
+type AuditSink interface {
+	Record(event VerifyEvent)
+}
+
+// SignatureDecision records the outcome for one signature of an envelope.
+// KeyID is as for the callback of WithPerSignatureCallback.
+type SignatureDecision struct {
+	KeyID    string
+	Accepted bool
+	Err      error
+}
+
+/*
+VerifyEvent describes a verification. EnvelopeHash is the hex encoded
+CanonicalHash of the envelope, computed with an empty payload for
+VerifyStream and left empty for multi-payload envelopes, envelopes that
+exceed the limits of WithMaxSignatures or WithMaxPayloadSize, and envelopes
+that cannot be hashed. Err is the error returned to the caller, and nil if the
+envelope was accepted.
+*/
+type VerifyEvent struct {
+	Time           time.Time
+	EnvelopeHash   string
+	PayloadType    string
+	Signatures     []SignatureDecision
+	AcceptedKeyIDs []string
+	Err            error
+}
+
+// WithAuditSink makes the verifier record each verification with sink. The
+// event is only assembled if a sink is set.
+func WithAuditSink(sink AuditSink) Option {
+	return func(o *options) {
+		o.auditSink = sink
+	}
+}
+
+/*
+envelopeEvent returns the event for the verification of e. The envelope is
+only hashed if it is within the signature count and payload size limits of o,
+so that hashing does not decode input that verification rejects unread.
+*/
+func (o *options) envelopeEvent(e *Envelope) VerifyEvent {
+	var event VerifyEvent
+	if e == nil {
+		return event
+	}
+
+	event.PayloadType = e.PayloadType
+	if o.checkSignatureCount(len(e.Signatures)) != nil || o.checkPayloadSize(e.Payload) != nil {
+		return event
+	}
+	if hash, err := e.CanonicalHash(); err == nil {
+		event.EnvelopeHash = hex.EncodeToString(hash[:])
+	}
+
+	return event
+}
+
+/*
+audit runs verify with a copy of the verifier that records the decision for
+each signature in event, and records the completed event with the audit sink
+of ev.
+*/
+func (ev *envelopeVerifier) audit(event VerifyEvent, verify func(*envelopeVerifier) ([]AcceptedKey, error)) ([]AcceptedKey, error) {
+	av := ev.recording(func(d SignatureDecision) {
+		event.Signatures = append(event.Signatures, d)
+	})
+	av.opts.auditSink = nil
+
+	event.Time = ev.opts.now()
+	acceptedKeys, err := verify(av)
+	for _, k := range acceptedKeys {
+		event.AcceptedKeyIDs = append(event.AcceptedKeyIDs, k.KeyID)
+	}
+	event.Err = err
+	ev.opts.auditSink.Record(event)
+
+	return acceptedKeys, err
+}
+
+/*
+recording returns a copy of ev that passes the decision for each signature to
+record, in addition to calling the per-signature callback of ev.
+*/
+func (ev *envelopeVerifier) recording(record func(SignatureDecision)) *envelopeVerifier {
+	rv := *ev
+	cb := ev.opts.perSignatureCallback
+	rv.opts.perSignatureCallback = func(keyID string, ok bool, err error) {
+		record(SignatureDecision{
+			KeyID:    keyID,
+			Accepted: ok,
+			Err:      err,
+		})
+		if cb != nil {
+			cb(keyID, ok, err)
+		}
+	}
+
+	return &rv
+}
@@ -0,0 +1,94 @@
+package dsse
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	events []VerifyEvent
+}
+
+func (s *recordingSink) Record(event VerifyEvent) {
+	s.events = append(s.events, event)
+}
+
+func TestAuditSink(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	var ns nilsigner
+	var null nullsigner
+	signer, err := NewEnvelopeSigner(ns, null)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	sink := &recordingSink{}
+	var callbacks int
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns},
+		WithAuditSink(sink),
+		WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+			callbacks++
+		}))
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 2, callbacks, "callback not called")
+	assert.Len(t, sink.events, 1, "wrong number of events")
+
+	hash, err := env.CanonicalHash()
+	assert.Nil(t, err, "unexpected error")
+	event := sink.events[0]
+	assert.False(t, event.Time.IsZero(), "missing time")
+	assert.Equal(t, hex.EncodeToString(hash[:]), event.EnvelopeHash, "wrong hash")
+	assert.Equal(t, payloadType, event.PayloadType, "wrong payload type")
+	assert.Equal(t, []SignatureDecision{
+		{KeyID: "nil", Accepted: true},
+		{KeyID: "null", Accepted: false, Err: ErrUnknownKey},
+	}, event.Signatures, "wrong decisions")
+	assert.Equal(t, []string{"nil"}, event.AcceptedKeyIDs, "wrong accepted keys")
+	assert.Nil(t, event.Err, "unexpected error")
+
+	_, err = ev.Verify(nil)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	assert.Len(t, sink.events, 2, "wrong number of events")
+	assert.Equal(t, ErrNoEnvelopes, sink.events[1].Err, "wrong error")
+
+	detached := *env
+	detached.Payload = ""
+	_, err = ev.VerifyStream(&detached, strings.NewReader("goodbye world"))
+	assert.Len(t, sink.events, 3, "wrong number of events")
+	assert.True(t, errors.Is(sink.events[2].Err, ErrSignatureInvalid), "wrong error")
+	assert.Equal(t, err, sink.events[2].Err, "wrong error")
+	assert.NotEmpty(t, sink.events[2].EnvelopeHash, "missing hash")
+
+	_, err = ev.VerifyMultiPayload(&MultiPayloadEnvelope{})
+	assert.Len(t, sink.events, 4, "wrong number of events")
+	assert.Equal(t, err, sink.events[3].Err, "wrong error")
+
+	t.Run("Limits", func(t *testing.T) {
+		sink := &recordingSink{}
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns},
+			WithAuditSink(sink), WithMaxPayloadSize(4), WithMaxSignatures(1))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrTooManySignatures), "wrong error")
+		small := *env
+		small.Signatures = env.Signatures[:1]
+		_, err = ev.Verify(&small)
+		assert.Equal(t, ErrPayloadTooLarge, err, "wrong error")
+
+		assert.Len(t, sink.events, 2, "wrong number of events")
+		for _, event := range sink.events {
+			assert.Empty(t, event.EnvelopeHash, "envelope hashed")
+			assert.Equal(t, payloadType, event.PayloadType, "wrong payload type")
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package dsse
+
+import "sync"
+
+// EnvelopeFailure records why an envelope of a batch did not verify. Index
+// is the position of the envelope in the batch.
+type EnvelopeFailure struct {
+	Index int
+	Err   error
+}
+
+/*
+VerifyBatch verifies each envelope of envs and partitions them into the
+envelopes that verify, in their original order, and the failures of the
+others, ordered by index. A malformed or untrusted envelope, including a nil
+one, is recorded as a failure and does not abort the batch. The error is only
+set if the batch as a whole cannot be processed, which is the case when envs
+is empty. Envelopes are verified one after the other unless
+WithBatchConcurrency is given.
+*/
+func (ev *envelopeVerifier) VerifyBatch(envs []*Envelope) ([]*Envelope, []EnvelopeFailure, error) {
+	if len(envs) == 0 {
+		return nil, nil, ErrNoEnvelopes
+	}
+
+	errs := make([]error, len(envs))
+	verify := func(i int) {
+		if envs[i] == nil {
+			errs[i] = ErrNoEnvelopes
+			return
+		}
+		_, errs[i] = ev.Verify(envs[i])
+	}
+
+	if n := ev.opts.batchConcurrency; n > 1 {
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, n)
+		for i := range envs {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				verify(i)
+				<-sem
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range envs {
+			verify(i)
+		}
+	}
+
+	var valid []*Envelope
+	var failures []EnvelopeFailure
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, EnvelopeFailure{Index: i, Err: err})
+			continue
+		}
+		valid = append(valid, envs[i])
+	}
+
+	return valid, failures, nil
+}
+
+// VerifyBatch verifies a batch of envelopes. See the VerifyBatch method of
+// the envelope verifier.
+func (es *EnvelopeSigner) VerifyBatch(envs []*Envelope) ([]*Envelope, []EnvelopeFailure, error) {
+	return es.ev.VerifyBatch(envs)
+}
@@ -0,0 +1,62 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	var envs []*Envelope
+	for i := 0; i < 20; i++ {
+		env, err := signer.SignPayload(payloadType, []byte(fmt.Sprintf("payload %d", i)))
+		assert.Nil(t, err, "sign failed")
+		envs = append(envs, env)
+	}
+	envs[3].PayloadType = "application/json"
+	envs[7] = nil
+	envs[11] = &Envelope{PayloadType: payloadType, Payload: "not base64!", Signatures: envs[10].Signatures}
+	bad := map[int]bool{3: true, 7: true, 11: true}
+
+	for _, concurrency := range []int{0, 4} {
+		t.Run(fmt.Sprintf("Concurrency %d", concurrency), func(t *testing.T) {
+			ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, WithBatchConcurrency(concurrency))
+			assert.Nil(t, err, "unexpected error")
+
+			valid, failures, err := ev.VerifyBatch(envs)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, valid, len(envs)-len(bad), "wrong number of valid envelopes")
+			var expected []*Envelope
+			for i, env := range envs {
+				if !bad[i] {
+					expected = append(expected, env)
+				}
+			}
+			assert.Equal(t, expected, valid, "wrong valid envelopes")
+
+			assert.Len(t, failures, len(bad), "wrong number of failures")
+			for i, failure := range failures {
+				assert.Equal(t, []int{3, 7, 11}[i], failure.Index, "wrong index")
+				assert.NotNil(t, failure.Err, "expected error")
+			}
+			assert.True(t, errors.Is(failures[1].Err, ErrNoEnvelopes), "wrong error")
+		})
+	}
+
+	_, _, err = signer.VerifyBatch(nil)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+
+	valid, failures, err := signer.VerifyBatch(envs[:2])
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, valid, 2, "wrong number of valid envelopes")
+	assert.Empty(t, failures, "unexpected failures")
+}
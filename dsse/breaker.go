@@ -0,0 +1,157 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen indicates that a verifier is not called because its backend
+// failed repeatedly. It wraps ErrVerifierUnavailable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrVerifierUnavailable)
+
+// CircuitState is the state of a CircuitBreakerVerifier.
+type CircuitState int
+
+const (
+	// CircuitClosed passes every call to the wrapped verifier.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails calls without calling the wrapped verifier.
+	CircuitOpen
+	// CircuitHalfOpen passes a single trial call after the cooldown.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+/*
+CircuitBreakerVerifier wraps a Verifier backed by a remote service, such as a
+KMS, and stops calling it after repeated failures, so that a dead backend
+does not slow down every verification with timeouts. After threshold
+consecutive failures the circuit opens, and Verify fails immediately with an
+error wrapping ErrCircuitOpen and ErrVerifierUnavailable for the cooldown.
+Then a single trial call is let through: if it succeeds the circuit closes,
+otherwise it opens again.
+
+Only errors wrapping ErrVerifierUnavailable count as failures. An invalid
+signature says nothing about the health of the backend, and counting it
+would let anyone open the circuit by sending bad signatures. Successful
+calls and their results are unchanged.
+*/
+type CircuitBreakerVerifier struct {
+	Verifier
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	state    CircuitState
+}
+
+/*
+NewCircuitBreakerVerifier wraps v so that the circuit opens after threshold
+consecutive failures, for cooldown. A threshold below one is treated as one.
+*/
+func NewCircuitBreakerVerifier(v Verifier, threshold int, cooldown time.Duration) *CircuitBreakerVerifier {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &CircuitBreakerVerifier{
+		Verifier:  v,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Verify verifies sig over data with the wrapped verifier, unless the circuit
+// is open.
+func (c *CircuitBreakerVerifier) Verify(data, sig []byte) error {
+	if !c.allow() {
+		return ErrCircuitOpen
+	}
+
+	err := c.Verifier.Verify(data, sig)
+	c.record(errors.Is(err, ErrVerifierUnavailable))
+
+	return err
+}
+
+// Algorithm returns the algorithm of the wrapped verifier, if it names one.
+func (c *CircuitBreakerVerifier) Algorithm() string {
+	return verifierAlgorithm(c.Verifier)
+}
+
+// State returns the state of the circuit, for example to export it as a
+// metric.
+func (c *CircuitBreakerVerifier) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && !c.now().Before(c.openedAt.Add(c.cooldown)) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// ConsecutiveFailures returns the number of failures since the last success.
+func (c *CircuitBreakerVerifier) ConsecutiveFailures() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.failures
+}
+
+// allow reports whether a call may be made, starting a trial call if the
+// cooldown has passed.
+func (c *CircuitBreakerVerifier) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case CircuitOpen:
+		if c.now().Before(c.openedAt.Add(c.cooldown)) {
+			return false
+		}
+		c.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		// A trial call is in progress.
+		return false
+	}
+
+	return true
+}
+
+// record updates the circuit with the outcome of a call.
+func (c *CircuitBreakerVerifier) record(failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !failed {
+		c.failures = 0
+		c.state = CircuitClosed
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= c.threshold {
+		c.state = CircuitOpen
+		c.openedAt = c.now()
+	}
+}
@@ -0,0 +1,103 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyVerifier fails with ErrVerifierUnavailable while down.
+type flakyVerifier struct {
+	Verifier
+	down  bool
+	calls int
+}
+
+func (v *flakyVerifier) Verify(data, sig []byte) error {
+	v.calls++
+	if v.down {
+		return fmt.Errorf("%w: timeout", ErrVerifierUnavailable)
+	}
+	return v.Verifier.Verify(data, sig)
+}
+
+func TestCircuitBreakerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("kms", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	pae := PAE(payloadType, payload)
+	sig, err := sv.Sign(pae)
+	assert.Nil(t, err, "sign failed")
+
+	flaky := &flakyVerifier{Verifier: sv}
+	now := time.Unix(0, 0)
+	cb := NewCircuitBreakerVerifier(flaky, 3, time.Minute)
+	cb.now = func() time.Time { return now }
+
+	assert.Nil(t, cb.Verify(pae, sig), "unexpected error")
+	assert.Equal(t, CircuitClosed, cb.State(), "wrong state")
+	keyID, err := cb.KeyID()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "kms", keyID, "wrong keyid")
+
+	t.Run("Invalid signatures do not open", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, ErrSignatureInvalid, cb.Verify(pae, make([]byte, len(sig))), "wrong error")
+		}
+		assert.Equal(t, CircuitClosed, cb.State(), "wrong state")
+		assert.Equal(t, 0, cb.ConsecutiveFailures(), "wrong failures")
+	})
+
+	t.Run("Opens after failures", func(t *testing.T) {
+		flaky.down = true
+		for i := 0; i < 3; i++ {
+			err := cb.Verify(pae, sig)
+			assert.True(t, errors.Is(err, ErrVerifierUnavailable), "wrong error")
+			assert.False(t, errors.Is(err, ErrCircuitOpen), "circuit open too early")
+		}
+		assert.Equal(t, CircuitOpen, cb.State(), "wrong state")
+		assert.Equal(t, 3, cb.ConsecutiveFailures(), "wrong failures")
+
+		calls := flaky.calls
+		err := cb.Verify(pae, sig)
+		assert.True(t, errors.Is(err, ErrCircuitOpen), "wrong error")
+		assert.True(t, errors.Is(err, ErrVerifierUnavailable), "wrong error")
+		assert.Equal(t, calls, flaky.calls, "backend called while open")
+	})
+
+	t.Run("Trial failure reopens", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.Equal(t, CircuitHalfOpen, cb.State(), "wrong state")
+
+		err := cb.Verify(pae, sig)
+		assert.False(t, errors.Is(err, ErrCircuitOpen), "trial call not made")
+		assert.Equal(t, CircuitOpen, cb.State(), "wrong state")
+	})
+
+	t.Run("Trial success closes", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		flaky.down = false
+
+		assert.Nil(t, cb.Verify(pae, sig), "unexpected error")
+		assert.Equal(t, CircuitClosed, cb.State(), "wrong state")
+		assert.Equal(t, 0, cb.ConsecutiveFailures(), "wrong failures")
+	})
+
+	t.Run("Envelope verifier", func(t *testing.T) {
+		es, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+		env, err := es.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifier(cb)
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, "kms", acceptedKeys[0].KeyID, "wrong keyid")
+	})
+}
@@ -0,0 +1,236 @@
+/*
+Package bundle reads Sigstore bundles that wrap a DSSE envelope, in the JSON
+encoding of the Sigstore bundle protobuf, and verifies them with the cosign
+package. A bundle carries the envelope together with its verification
+material: the signing certificate, optionally with its chain, and the entries
+of the signature in the Rekor transparency log.
+*/
+package bundle
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse/cosign"
+)
+
+// ErrUnsupportedMediaType indicates that data is not a Sigstore bundle of a
+// supported version.
+var ErrUnsupportedMediaType = errors.New("unsupported bundle media type")
+
+// ErrNoEnvelope indicates that a bundle does not wrap a DSSE envelope, for
+// example because it carries a plain message signature.
+var ErrNoEnvelope = errors.New("bundle has no dsse envelope")
+
+// ErrInvalidBundle indicates that a bundle is malformed.
+var ErrInvalidBundle = errors.New("invalid sigstore bundle")
+
+// mediaTypePrefix is shared by the media types of all bundle versions, such as
+// "application/vnd.dev.sigstore.bundle+json;version=0.2" and
+// "application/vnd.dev.sigstore.bundle.v0.3+json".
+const mediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+
+// Bundle is a parsed Sigstore bundle.
+type Bundle struct {
+	MediaType string
+	Envelope  *dsse.Envelope
+	// Certificates holds the signing certificate followed by its chain, if
+	// the bundle includes one.
+	Certificates []*x509.Certificate
+	TlogEntries  []TlogEntry
+}
+
+// TlogEntry is an entry of the signature in a transparency log.
+type TlogEntry struct {
+	LogIndex int64
+	// LogID is the ID of the log, the SHA-256 digest of its public key.
+	LogID          []byte
+	Kind           string
+	Version        string
+	IntegratedTime int64
+	// SignedEntryTimestamp is the log's promise to include the entry, if
+	// the bundle carries one.
+	SignedEntryTimestamp []byte
+	CanonicalizedBody    []byte
+}
+
+// jsonBundle is the JSON encoding of the bundle protobuf.
+type jsonBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		X509CertificateChain *struct {
+			Certificates []jsonCertificate `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		Certificate *jsonCertificate `json:"certificate"`
+		TlogEntries []struct {
+			LogIndex jsonInt64 `json:"logIndex"`
+			LogID    struct {
+				KeyID []byte `json:"keyId"`
+			} `json:"logId"`
+			KindVersion struct {
+				Kind    string `json:"kind"`
+				Version string `json:"version"`
+			} `json:"kindVersion"`
+			IntegratedTime   jsonInt64 `json:"integratedTime"`
+			InclusionPromise *struct {
+				SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+			} `json:"inclusionPromise"`
+			CanonicalizedBody []byte `json:"canonicalizedBody"`
+		} `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *dsse.Envelope `json:"dsseEnvelope"`
+}
+
+type jsonCertificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// jsonInt64 is a 64-bit integer, which the protobuf JSON encoding writes as a
+// string.
+type jsonInt64 int64
+
+func (i *jsonInt64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*i = jsonInt64(n)
+		return nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+	*i = jsonInt64(n)
+	return nil
+}
+
+/*
+Parse parses a Sigstore bundle in JSON encoding. The bundle must wrap a DSSE
+envelope, otherwise ErrNoEnvelope is returned. Both the certificate chain of
+bundles up to version 0.2 and the single certificate of version 0.3 are
+supported.
+*/
+func Parse(data []byte) (*Bundle, error) {
+	var jb jsonBundle
+	if err := json.Unmarshal(data, &jb); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	if !strings.HasPrefix(jb.MediaType, mediaTypePrefix) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, jb.MediaType)
+	}
+	if jb.DSSEEnvelope == nil {
+		return nil, ErrNoEnvelope
+	}
+
+	b := &Bundle{MediaType: jb.MediaType, Envelope: jb.DSSEEnvelope}
+
+	var certs []jsonCertificate
+	if jb.VerificationMaterial.Certificate != nil {
+		certs = append(certs, *jb.VerificationMaterial.Certificate)
+	}
+	if chain := jb.VerificationMaterial.X509CertificateChain; chain != nil {
+		certs = append(certs, chain.Certificates...)
+	}
+	for _, c := range certs {
+		cert, err := x509.ParseCertificate(c.RawBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+		b.Certificates = append(b.Certificates, cert)
+	}
+
+	for _, e := range jb.VerificationMaterial.TlogEntries {
+		entry := TlogEntry{
+			LogIndex:          int64(e.LogIndex),
+			LogID:             e.LogID.KeyID,
+			Kind:              e.KindVersion.Kind,
+			Version:           e.KindVersion.Version,
+			IntegratedTime:    int64(e.IntegratedTime),
+			CanonicalizedBody: e.CanonicalizedBody,
+		}
+		if e.InclusionPromise != nil {
+			entry.SignedEntryTimestamp = e.InclusionPromise.SignedEntryTimestamp
+		}
+		b.TlogEntries = append(b.TlogEntries, entry)
+	}
+
+	return b, nil
+}
+
+/*
+Verify verifies the bundle with the trust roots and identities of cfg, as
+cosign.Verifier does for cosign's own bundles: the signing certificate must
+chain to a trusted root, possibly through the chain in the bundle, be issued
+to an expected identity and have signed the envelope. If cfg has a Rekor
+public key, a log entry with a signed entry timestamp is required, which must
+verify and reference the certificate and the payload, and the certificate is
+checked at the time of the entry. Inclusion proofs are not checked. opts
+are passed to cosign.NewVerifier, for example to check the revocation status
+of the certificate with cosign.WithRevocationCheck.
+*/
+func (b *Bundle) Verify(cfg cosign.Config, opts ...cosign.Option) (*cosign.Result, error) {
+	if len(b.Certificates) == 0 {
+		return nil, cosign.ErrNoCertificate
+	}
+
+	intermediates := x509.NewCertPool()
+	if cfg.Intermediates != nil {
+		intermediates = cfg.Intermediates.Clone()
+	}
+	for _, cert := range b.Certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	cfg.Intermediates = intermediates
+
+	envelope, err := json.Marshal(b.Envelope)
+	if err != nil {
+		return nil, err
+	}
+	a := cosign.Attestation{
+		Envelope:    envelope,
+		Certificate: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b.Certificates[0].Raw}),
+	}
+	for _, e := range b.TlogEntries {
+		if len(e.SignedEntryTimestamp) == 0 {
+			continue
+		}
+		if a.Bundle, err = e.rekorBundle(); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	v, err := cosign.NewVerifier(cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.Verify(a)
+}
+
+// rekorBundle returns the entry in the form of a cosign bundle.
+func (e TlogEntry) rekorBundle() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"rekorBundle": map[string]interface{}{
+			"SignedEntryTimestamp": e.SignedEntryTimestamp,
+			"Payload": map[string]interface{}{
+				"body":           base64.StdEncoding.EncodeToString(e.CanonicalizedBody),
+				"integratedTime": e.IntegratedTime,
+				"logIndex":       e.LogIndex,
+				"logID":          hex.EncodeToString(e.LogID),
+			},
+		},
+	})
+}
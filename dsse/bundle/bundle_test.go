@@ -0,0 +1,213 @@
+package bundle
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse/cosign"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse/internal/sigstoretest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testLogID = sha256.Sum256([]byte("test log"))
+
+type fixture struct {
+	*sigstoretest.Fixture
+}
+
+// newFixture creates a Fulcio-like root and intermediate, a short-lived
+// certificate that was valid an hour ago, and an envelope signed with it.
+func newFixture(t *testing.T) *fixture {
+	return &fixture{sigstoretest.New(t, sigstoretest.Options{
+		Intermediate: true,
+		Payload:      []byte(`{"_type":"https://in-toto.io/Statement/v1"}`),
+	})}
+}
+
+// dsseEntry returns a Rekor dsse entry body for the envelope.
+func (f *fixture) dsseEntry() []byte {
+	digest := sha256.Sum256(f.Payload)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: f.Chain[0]})
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "dsse",
+		"spec": map[string]interface{}{
+			"payloadHash": map[string]interface{}{
+				"algorithm": "sha256",
+				"value":     hex.EncodeToString(digest[:]),
+			},
+			"signatures": []interface{}{
+				map[string]interface{}{
+					"signature": f.Envelope.Signatures[0].Sig,
+					"verifier":  base64.StdEncoding.EncodeToString(certPEM),
+				},
+			},
+		},
+	})
+	return body
+}
+
+// bundle returns the JSON encoding of a Sigstore bundle of the given media
+// type with a log entry whose body is body.
+func (f *fixture) bundle(t *testing.T, mediaType string, body []byte) []byte {
+	payload := map[string]interface{}{
+		"body":           base64.StdEncoding.EncodeToString(body),
+		"integratedTime": f.SignedAt.Unix(),
+		"logIndex":       42,
+		"logID":          hex.EncodeToString(testLogID[:]),
+	}
+	canonical, err := cjson.EncodeCanonical(payload)
+	assert.Nil(t, err, "unexpected error")
+	digest := sha256.Sum256(canonical)
+	set, err := ecdsa.SignASN1(rand.Reader, f.RekorKey, digest[:])
+	assert.Nil(t, err, "unexpected error")
+
+	material := map[string]interface{}{
+		"tlogEntries": []interface{}{
+			map[string]interface{}{
+				"logIndex":          "42",
+				"logId":             map[string]interface{}{"keyId": testLogID[:]},
+				"kindVersion":       map[string]interface{}{"kind": "dsse", "version": "0.0.1"},
+				"integratedTime":    strconv.FormatInt(f.SignedAt.Unix(), 10),
+				"inclusionPromise":  map[string]interface{}{"signedEntryTimestamp": set},
+				"canonicalizedBody": body,
+			},
+		},
+	}
+	if mediaType == "application/vnd.dev.sigstore.bundle.v0.3+json" {
+		material["certificate"] = map[string]interface{}{"rawBytes": f.Chain[0]}
+	} else {
+		var certs []interface{}
+		for _, der := range f.Chain {
+			certs = append(certs, map[string]interface{}{"rawBytes": der})
+		}
+		material["x509CertificateChain"] = map[string]interface{}{"certificates": certs}
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"mediaType":            mediaType,
+		"verificationMaterial": material,
+		"dsseEnvelope":         f.Envelope,
+	})
+	assert.Nil(t, err, "unexpected error")
+	return data
+}
+
+func TestParse(t *testing.T) {
+	f := newFixture(t)
+
+	b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", f.dsseEntry()))
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, f.Envelope, b.Envelope, "wrong envelope")
+	assert.Len(t, b.Certificates, 2, "wrong number of certificates")
+	assert.Len(t, b.TlogEntries, 1, "wrong number of log entries")
+	entry := b.TlogEntries[0]
+	assert.Equal(t, int64(42), entry.LogIndex, "wrong log index")
+	assert.Equal(t, testLogID[:], entry.LogID, "wrong log ID")
+	assert.Equal(t, "dsse", entry.Kind, "wrong kind")
+	assert.Equal(t, f.SignedAt.Unix(), entry.IntegratedTime, "wrong integrated time")
+	assert.NotEmpty(t, entry.SignedEntryTimestamp, "missing signed entry timestamp")
+
+	t.Run("Version 0.3", func(t *testing.T) {
+		b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle.v0.3+json", f.dsseEntry()))
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, b.Certificates, 1, "wrong number of certificates")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := Parse([]byte(`{"mediaType": "application/json"}`))
+		assert.True(t, errors.Is(err, ErrUnsupportedMediaType), "wrong error")
+
+		_, err = Parse([]byte(`{"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json", "messageSignature": {}}`))
+		assert.Equal(t, ErrNoEnvelope, err, "wrong error")
+
+		_, err = Parse([]byte(`not json`))
+		assert.True(t, errors.Is(err, ErrInvalidBundle), "wrong error")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	f := newFixture(t)
+	cfg := cosign.Config{
+		Roots: f.Roots,
+		Identities: []cosign.Identity{{
+			Subject: regexp.MustCompile(`^signer@example\.com$`),
+			Issuer:  regexp.MustCompile(`^https://accounts\.example\.com$`),
+		}},
+		RekorPublicKey: &f.RekorKey.PublicKey,
+	}
+
+	b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", f.dsseEntry()))
+	assert.Nil(t, err, "unexpected error")
+	res, err := b.Verify(cfg)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, []string{sigstoretest.Subject}, res.Certificate.EmailAddresses, "wrong certificate")
+	assert.True(t, f.SignedAt.Equal(res.IntegratedTime), "wrong integrated time")
+
+	t.Run("Missing chain", func(t *testing.T) {
+		b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle.v0.3+json", f.dsseEntry()))
+		assert.Nil(t, err, "unexpected error")
+		_, err = b.Verify(cfg)
+		var unknown x509.UnknownAuthorityError
+		assert.True(t, errors.As(err, &unknown), "wrong error")
+	})
+
+	t.Run("Tampered envelope", func(t *testing.T) {
+		b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", f.dsseEntry()))
+		assert.Nil(t, err, "unexpected error")
+		b.Envelope.PayloadType = "application/json"
+		_, err = b.Verify(cfg)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Entry for other payload", func(t *testing.T) {
+		b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", []byte(`{"kind":"dsse"}`)))
+		assert.Nil(t, err, "unexpected error")
+		_, err = b.Verify(cfg)
+		assert.True(t, errors.Is(err, cosign.ErrInvalidBundle), "wrong error")
+	})
+
+	t.Run("No log entry", func(t *testing.T) {
+		b, err := Parse(f.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", f.dsseEntry()))
+		assert.Nil(t, err, "unexpected error")
+		b.TlogEntries = nil
+		_, err = b.Verify(cfg)
+		assert.Equal(t, cosign.ErrNoBundle, err, "wrong error")
+	})
+
+	t.Run("High-S signature", func(t *testing.T) {
+		fx := *f.Fixture
+		hs := &fixture{&fx}
+		hs.Envelope = f.Sign(t, func(digest []byte) ([]byte, error) {
+			sig, err := ecdsa.SignASN1(rand.Reader, f.LeafKey, digest)
+			return sigstoretest.HighS(t, elliptic.P256(), sig), err
+		})
+		b, err := Parse(hs.bundle(t, "application/vnd.dev.sigstore.bundle+json;version=0.2", hs.dsseEntry()))
+		assert.Nil(t, err, "unexpected error")
+		_, err = b.Verify(cfg)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Verifier options", func(t *testing.T) {
+		// The certificate names no OCSP responder or CRL.
+		_, err := b.Verify(cfg, cosign.WithRevocationCheck(cosign.RevocationHardFail))
+		assert.True(t, errors.Is(err, cosign.ErrRevocationUnknown), "wrong error")
+	})
+
+	t.Run("No certificate", func(t *testing.T) {
+		_, err := (&Bundle{Envelope: f.Envelope}).Verify(cfg)
+		assert.Equal(t, cosign.ErrNoCertificate, err, "wrong error")
+	})
+}
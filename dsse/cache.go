@@ -0,0 +1,155 @@
+package dsse
+
+import (
+	"crypto"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+VerifierResolver returns a verifier for a key that must be fetched first, for
+example from a KMS. It typically fetches the public key and returns a local
+verifier for it, such as one created by NewECDSAVerifier.
+*/
+type VerifierResolver func() (Verifier, error)
+
+/*
+CachingVerifier is a Verifier that resolves the verifier of a remote key with
+a VerifierResolver and caches it, so that verifying many envelopes signed by
+the same key does not fetch the key every time. A resolved verifier is
+reused for the TTL plus a random jitter, which spreads the refreshes of many
+caching verifiers created at the same time. Concurrent calls while the key is
+being fetched wait for that fetch instead of starting their own. Errors are
+not cached: the next call fetches again.
+If the key cannot be fetched, Verify returns an error wrapping
+ErrVerifierUnavailable, KeyID returns the error and Public returns nil.
+*/
+type CachingVerifier struct {
+	resolve VerifierResolver
+	ttl     time.Duration
+	jitter  time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	cached   *resolvedVerifier
+	expires  time.Time
+	inflight *resolveCall
+}
+
+// resolvedVerifier is a verifier with its key ID and public key.
+type resolvedVerifier struct {
+	v      Verifier
+	keyID  string
+	public crypto.PublicKey
+}
+
+// resolveCall is a fetch in progress; done is closed when it completes.
+type resolveCall struct {
+	done chan struct{}
+	rv   *resolvedVerifier
+	err  error
+}
+
+/*
+NewCachingVerifier creates a CachingVerifier that caches the verifier
+returned by resolve for ttl plus a random duration of up to jitter.
+*/
+func NewCachingVerifier(resolve VerifierResolver, ttl, jitter time.Duration) *CachingVerifier {
+	return &CachingVerifier{
+		resolve: resolve,
+		ttl:     ttl,
+		jitter:  jitter,
+		now:     time.Now,
+	}
+}
+
+// Verify verifies sig over data with the resolved verifier.
+func (c *CachingVerifier) Verify(data, sig []byte) error {
+	rv, err := c.resolved()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerifierUnavailable, err)
+	}
+
+	return rv.v.Verify(data, sig)
+}
+
+// KeyID returns the key ID of the resolved verifier.
+func (c *CachingVerifier) KeyID() (string, error) {
+	rv, err := c.resolved()
+	if err != nil {
+		return "", err
+	}
+
+	return rv.keyID, nil
+}
+
+// Public returns the public key of the resolved verifier, or nil if it cannot
+// be resolved.
+func (c *CachingVerifier) Public() crypto.PublicKey {
+	rv, err := c.resolved()
+	if err != nil {
+		return nil
+	}
+
+	return rv.public
+}
+
+// Algorithm returns the algorithm of the resolved verifier, if it names one.
+func (c *CachingVerifier) Algorithm() string {
+	rv, err := c.resolved()
+	if err != nil {
+		return ""
+	}
+
+	return verifierAlgorithm(rv.v)
+}
+
+// resolved returns the cached verifier, fetching it if it expired.
+func (c *CachingVerifier) resolved() (*resolvedVerifier, error) {
+	c.mu.Lock()
+	if c.cached != nil && c.now().Before(c.expires) {
+		rv := c.cached
+		c.mu.Unlock()
+		return rv, nil
+	}
+	if call := c.inflight; call != nil {
+		c.mu.Unlock()
+		<-call.done
+		return call.rv, call.err
+	}
+	call := &resolveCall{done: make(chan struct{})}
+	c.inflight = call
+	c.mu.Unlock()
+
+	call.rv, call.err = c.fetch()
+
+	c.mu.Lock()
+	if call.err == nil {
+		c.cached = call.rv
+		c.expires = c.now().Add(c.ttl)
+		if c.jitter > 0 {
+			c.expires = c.expires.Add(time.Duration(rand.Int63n(int64(c.jitter))))
+		}
+	}
+	c.inflight = nil
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.rv, call.err
+}
+
+func (c *CachingVerifier) fetch() (*resolvedVerifier, error) {
+	v, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := v.KeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &resolvedVerifier{v: v, keyID: keyID, public: v.Public()}, nil
+}
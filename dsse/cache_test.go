@@ -0,0 +1,86 @@
+package dsse
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("kms", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	es, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	var fetches int32
+	var fail atomic.Value
+	fail.Store(false)
+	release := make(chan struct{})
+	resolve := func() (Verifier, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		if fail.Load().(bool) {
+			return nil, errors.New("rate limited")
+		}
+		return NewEd25519Verifier("kms", sv.Public().(ed25519.PublicKey))
+	}
+
+	now := time.Unix(0, 0)
+	cv := NewCachingVerifier(resolve, time.Minute, time.Second)
+	cv.now = func() time.Time { return now }
+
+	t.Run("Coalesced", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				keyID, err := cv.KeyID()
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, "kms", keyID, "wrong keyid")
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "wrong number of fetches")
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifier(cv)
+		assert.Nil(t, err, "unexpected error")
+		for i := 0; i < 3; i++ {
+			_, err = ev.Verify(env)
+			assert.Nil(t, err, "unexpected error")
+		}
+		assert.Equal(t, sv.Public(), cv.Public(), "wrong public key")
+		assert.Equal(t, "ed25519", cv.Algorithm(), "wrong algorithm")
+		assert.Equal(t, int32(1), atomic.LoadInt32(&fetches), "wrong number of fetches")
+	})
+
+	t.Run("Errors not cached", func(t *testing.T) {
+		now = now.Add(time.Minute + time.Second)
+		fail.Store(true)
+
+		err := cv.Verify(PAE(payloadType, payload), nil)
+		assert.True(t, errors.Is(err, ErrVerifierUnavailable), "wrong error")
+		assert.Nil(t, cv.Public(), "unexpected public key")
+		assert.Equal(t, int32(3), atomic.LoadInt32(&fetches), "wrong number of fetches")
+
+		fail.Store(false)
+		keyID, err := cv.KeyID()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, "kms", keyID, "wrong keyid")
+		assert.Equal(t, int32(4), atomic.LoadInt32(&fetches), "wrong number of fetches")
+	})
+}
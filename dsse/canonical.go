@@ -0,0 +1,232 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+Canonicalize returns the canonical serialization of the envelope, so that
+parties storing the same envelope produce byte-identical files. It is the
+OLPC canonical JSON of the envelope, which is compact and orders fields
+stably, with the payload, the additional authenticated data and the
+signatures re-encoded in standard base64, the payload encoding marker dropped
+and the signatures sorted by key ID. Numbers in signature extensions are kept
+as written, including those that are not integers, which OLPC canonical JSON
+does not allow. The decoded payload, and therefore the signed message, is
+unchanged. CanonicalHash hashes this serialization. ErrNoEnvelopes is
+returned for a nil envelope.
+*/
+func (e *Envelope) Canonicalize() ([]byte, error) {
+	if e == nil {
+		return nil, ErrNoEnvelopes
+	}
+
+	return e.canonicalJSON()
+}
+
+/*
+CanonicalHash returns the SHA-256 digest of the canonical serialization of
+the envelope, for use as a content address. The serialization is the one of
+Canonicalize. Envelopes that differ only in these respects, and are thus Equal,
+have the same hash. ErrNoEnvelopes is returned for a nil envelope.
+*/
+func (e *Envelope) CanonicalHash() ([32]byte, error) {
+	data, err := e.Canonicalize()
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	return sha256.Sum256(data), nil
+}
+
+/*
+Fingerprint returns the first 16 hex characters of CanonicalHash, a short
+identifier to correlate the same envelope across logs without logging its
+payload. Equal envelopes have the same fingerprint. With 64 bits, accidental
+collisions are unlikely enough for correlation, but a fingerprint can be
+forged with moderate effort, so it must not be used for security decisions;
+use CanonicalHash or verification for those. The fingerprint is empty for a
+nil envelope or one that cannot be canonicalized.
+*/
+func (e *Envelope) Fingerprint() string {
+	if e == nil {
+		return ""
+	}
+	digest, err := e.CanonicalHash()
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(digest[:8])
+}
+
+/*
+Equal reports whether e and other carry the same payload type, the same
+decoded payload and additional authenticated data and the same signatures in
+any order. Envelopes whose payload or signatures cannot be decoded are not
+equal to any envelope.
+*/
+func (e *Envelope) Equal(other *Envelope) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+
+	a, err := e.canonicalJSON()
+	if err != nil {
+		return false
+	}
+	b, err := other.canonicalJSON()
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(a, b)
+}
+
+// canonicalJSON returns the canonical serialization of the envelope.
+func (e *Envelope) canonicalJSON() ([]byte, error) {
+	body, err := e.DecodedPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := e.decodedAAD()
+	if err != nil {
+		return nil, err
+	}
+
+	type canonicalSignature struct {
+		keyID  string
+		fields map[string]interface{}
+		enc    []byte
+	}
+	sigs := make([]canonicalSignature, 0, len(e.Signatures))
+	for _, s := range e.Signatures {
+		sig, err := b64Decode(s.Sig)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := map[string]interface{}{
+			"keyid": s.KeyID,
+			"sig":   base64.StdEncoding.EncodeToString(sig),
+		}
+		if len(s.Extensions) > 0 {
+			fields["extensions"] = s.Extensions
+		}
+		enc, err := encodeCanonical(fields)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, canonicalSignature{keyID: s.KeyID, fields: fields, enc: enc})
+	}
+	sort.Slice(sigs, func(i, j int) bool {
+		if sigs[i].keyID != sigs[j].keyID {
+			return sigs[i].keyID < sigs[j].keyID
+		}
+		return bytes.Compare(sigs[i].enc, sigs[j].enc) < 0
+	})
+
+	signatures := make([]interface{}, 0, len(sigs))
+	for _, s := range sigs {
+		signatures = append(signatures, s.fields)
+	}
+
+	fields := map[string]interface{}{
+		"payloadType": e.PayloadType,
+		"payload":     base64.StdEncoding.EncodeToString(body),
+		"signatures":  signatures,
+	}
+	if len(aad) > 0 {
+		fields["aad"] = base64.StdEncoding.EncodeToString(aad)
+	}
+
+	return encodeCanonical(fields)
+}
+
+/*
+encodeCanonical returns the OLPC canonical JSON of obj like
+cjson.EncodeCanonical, but keeps numbers that are not integers as written
+instead of rejecting them, as signature extensions may contain them.
+*/
+func encodeCanonical(obj interface{}) ([]byte, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// canonicalEscaper escapes strings as OLPC canonical JSON does.
+var canonicalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func writeCanonical(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		fmt.Fprint(buf, v)
+	case json.Number:
+		buf.WriteString(v.String())
+	case string:
+		buf.WriteByte('"')
+		canonicalEscaper.WriteString(buf, v)
+		buf.WriteByte('"')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("cannot canonicalize %T", v)
+	}
+
+	return nil
+}
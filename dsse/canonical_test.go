@@ -0,0 +1,142 @@
+package dsse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalHash(t *testing.T) {
+	e := &Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ/",
+		Signatures: []Signature{
+			{KeyID: "a", Sig: "c2ln+w=="},
+			{KeyID: "b", Sig: "c2ln", Extensions: map[string]json.RawMessage{"alg": json.RawMessage(`"ed25519"`)}},
+		},
+	}
+	hash, err := e.CanonicalHash()
+	assert.Nil(t, err, "unexpected error")
+
+	reordered := &Envelope{
+		PayloadType:     e.PayloadType,
+		Payload:         "aGVsbG8gd29ybGQ_",
+		PayloadEncoding: PayloadEncodingBase64URL,
+		Signatures: []Signature{
+			{KeyID: "b", Sig: "c2ln", Extensions: map[string]json.RawMessage{"alg": json.RawMessage(` "ed25519" `)}},
+			{KeyID: "a", Sig: "c2ln-w=="},
+		},
+	}
+	assert.True(t, e.Equal(reordered), "envelopes not equal")
+	other, err := reordered.CanonicalHash()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, hash, other, "hashes differ")
+
+	tests := map[string]func(e *Envelope){
+		"payload type": func(e *Envelope) { e.PayloadType = "other" },
+		"payload":      func(e *Envelope) { e.Payload = "aGVsbG8gd29ybGQ=" },
+		"key ID":       func(e *Envelope) { e.Signatures[0].KeyID = "c" },
+		"signature":    func(e *Envelope) { e.Signatures[0].Sig = "b3RoZXI=" },
+		"extensions":   func(e *Envelope) { e.Signatures[1].Extensions = nil },
+		"dropped":      func(e *Envelope) { e.Signatures = e.Signatures[:1] },
+		"aad":          func(e *Envelope) { e.AAD = "YWFk" },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			changed := *e
+			changed.Signatures = []Signature{e.Signatures[0], e.Signatures[1]}
+			modify(&changed)
+
+			assert.False(t, e.Equal(&changed), "envelopes equal")
+			h, err := changed.CanonicalHash()
+			assert.Nil(t, err, "unexpected error")
+			assert.NotEqual(t, hash, h, "hashes equal")
+		})
+	}
+
+	invalid := &Envelope{PayloadType: e.PayloadType, Payload: "!"}
+	_, err = invalid.CanonicalHash()
+	assert.NotNil(t, err, "expected error")
+	assert.False(t, invalid.Equal(invalid), "invalid envelope equal")
+
+	var nilEnvelope *Envelope
+	assert.True(t, nilEnvelope.Equal(nil), "nil envelopes not equal")
+	assert.False(t, e.Equal(nil), "envelope equal to nil")
+	_, err = nilEnvelope.CanonicalHash()
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+
+	t.Run("Float extension", func(t *testing.T) {
+		f := e.Clone()
+		f.Signatures[1].Extensions["weight"] = json.RawMessage(`{"v": 0.5, "n": [1, -2.5e3]}`)
+		_, err := f.CanonicalHash()
+		assert.Nil(t, err, "unexpected error")
+		assert.True(t, f.Equal(f.Clone()), "envelope not equal to itself")
+		assert.False(t, f.Equal(e), "envelopes equal")
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	e := &Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ/",
+		Signatures:  []Signature{{KeyID: "a", Sig: "c2ln+w=="}, {KeyID: "b", Sig: "c2ln"}},
+	}
+	hash, err := e.CanonicalHash()
+	assert.Nil(t, err, "unexpected error")
+
+	fingerprint := e.Fingerprint()
+	assert.Len(t, fingerprint, 16, "wrong fingerprint length")
+	assert.Equal(t, hex.EncodeToString(hash[:])[:16], fingerprint, "wrong fingerprint")
+	assert.Equal(t, fingerprint, e.Clone().Fingerprint(), "fingerprint not stable")
+
+	reordered := e.Clone()
+	reordered.Signatures[0], reordered.Signatures[1] = reordered.Signatures[1], reordered.Signatures[0]
+	assert.Equal(t, fingerprint, reordered.Fingerprint(), "fingerprints differ")
+
+	changed := e.Clone()
+	changed.PayloadType = "other"
+	assert.NotEqual(t, fingerprint, changed.Fingerprint(), "fingerprints equal")
+
+	assert.Empty(t, (&Envelope{Payload: "!"}).Fingerprint(), "invalid envelope fingerprinted")
+	var nilEnvelope *Envelope
+	assert.Empty(t, nilEnvelope.Fingerprint(), "nil envelope fingerprinted")
+}
+
+func TestCanonicalize(t *testing.T) {
+	e := &Envelope{
+		PayloadType:     "http://example.com/HelloWorld",
+		Payload:         "aGVsbG8gd29ybGQ_",
+		PayloadEncoding: PayloadEncodingBase64URL,
+		Signatures: []Signature{
+			{KeyID: "b", Sig: "c2ln", Extensions: map[string]json.RawMessage{"alg": json.RawMessage(` "ed25519" `)}},
+			{KeyID: "a", Sig: "c2ln-w=="},
+		},
+	}
+
+	data, err := e.Canonicalize()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, `{"payload":"aGVsbG8gd29ybGQ/","payloadType":"http://example.com/HelloWorld","signatures":[{"keyid":"a","sig":"c2ln+w=="},{"extensions":{"alg":"ed25519"},"keyid":"b","sig":"c2ln"}]}`, string(data), "wrong serialization")
+
+	var decoded Envelope
+	assert.Nil(t, json.Unmarshal(data, &decoded), "unexpected error")
+	assert.True(t, e.Equal(&decoded), "envelopes not equal")
+	again, err := decoded.Canonicalize()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, data, again, "serialization not stable")
+
+	hash, err := e.CanonicalHash()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, sha256.Sum256(data), hash, "wrong hash")
+
+	escaped := e.Clone()
+	escaped.Signatures[0].Extensions["note"] = json.RawMessage(`"a \"quoted\" \\ <tag>"`)
+	data, err = escaped.Canonicalize()
+	assert.Nil(t, err, "unexpected error")
+	assert.Contains(t, string(data), `"note":"a \"quoted\" \\ <tag>"`, "wrong escaping")
+
+	_, err = (*Envelope)(nil).Canonicalize()
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+}
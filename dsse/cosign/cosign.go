@@ -0,0 +1,422 @@
+/*
+Package cosign verifies DSSE attestations produced by cosign with keyless
+signing, where the envelope is signed with the key of a short-lived Fulcio
+certificate and the signature is recorded in the Rekor transparency log.
+
+Verification checks that the certificate chains to a trusted Fulcio root,
+that it was issued to an expected identity, and that it signed the envelope.
+If a Rekor public key is configured, the Rekor bundle is required as well: its
+signed entry timestamp must verify, the log entry must reference the
+certificate and the payload, and the certificate is checked at the time the
+entry was integrated into the log rather than at the current time.
+Revocation of the certificate is optionally checked with OCSP and CRLs, see
+WithRevocationCheck.
+*/
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrNoCertificate indicates that an attestation carries no certificate.
+var ErrNoCertificate = errors.New("no certificate")
+
+// ErrIdentityMismatch indicates that a certificate was not issued to any of
+// the expected identities.
+var ErrIdentityMismatch = errors.New("certificate identity mismatch")
+
+// ErrNoBundle indicates that a Rekor bundle is required but missing.
+var ErrNoBundle = errors.New("no rekor bundle")
+
+// ErrInvalidBundle indicates that a Rekor bundle does not verify or does not
+// belong to the attestation.
+var ErrInvalidBundle = errors.New("invalid rekor bundle")
+
+var (
+	// oidIssuer is the Fulcio extension holding the OIDC issuer as a raw
+	// string; it is deprecated in favor of oidIssuerV2.
+	oidIssuer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	// oidIssuerV2 is the Fulcio extension holding the OIDC issuer as a DER
+	// encoded UTF8String.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+/*
+Identity describes an expected signer. A certificate matches if Subject
+matches one of its email or URI subject alternative names and Issuer matches
+the OIDC issuer recorded by Fulcio. A nil pattern matches anything.
+*/
+type Identity struct {
+	Subject *regexp.Regexp
+	Issuer  *regexp.Regexp
+}
+
+// Config describes the trust roots and identities to verify against.
+type Config struct {
+	// Roots holds the trusted Fulcio root certificates.
+	Roots *x509.CertPool
+	// Intermediates optionally holds Fulcio intermediate certificates.
+	Intermediates *x509.CertPool
+	// Identities lists the accepted signers; at least one must match.
+	Identities []Identity
+	// RekorPublicKey is the public key of the Rekor log. If set, a Rekor
+	// bundle is required.
+	RekorPublicKey crypto.PublicKey
+}
+
+/*
+Attestation is a cosign attestation to verify. Envelope is the DSSE envelope
+as written by cosign attest-blob. Certificate is the PEM encoded signing
+certificate. Bundle is the bundle written with --bundle, which carries the
+envelope and certificate as well, so either may be omitted if Bundle is set.
+*/
+type Attestation struct {
+	Envelope    []byte
+	Certificate []byte
+	Bundle      []byte
+}
+
+// Result describes a verified attestation.
+type Result struct {
+	Envelope    *dsse.Envelope
+	Certificate *x509.Certificate
+	// IntegratedTime is the time the signature was recorded in Rekor, or the
+	// zero time if no Rekor bundle was verified.
+	IntegratedTime time.Time
+}
+
+// bundle is the bundle written by cosign with --bundle.
+type bundle struct {
+	Base64Signature string       `json:"base64Signature"`
+	Cert            string       `json:"cert"`
+	RekorBundle     *rekorBundle `json:"rekorBundle"`
+}
+
+type rekorBundle struct {
+	SignedEntryTimestamp []byte       `json:"SignedEntryTimestamp"`
+	Payload              rekorPayload `json:"Payload"`
+}
+
+type rekorPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogIndex       int64  `json:"logIndex"`
+	LogID          string `json:"logID"`
+}
+
+// Verifier verifies cosign attestations.
+type Verifier struct {
+	cfg        Config
+	revocation RevocationMode
+	client     *http.Client
+	now        func() time.Time
+	cache      revocationCache
+}
+
+// NewVerifier creates a Verifier.
+func NewVerifier(cfg Config, opts ...Option) (*Verifier, error) {
+	if cfg.Roots == nil {
+		return nil, errors.New("missing fulcio roots")
+	}
+	if len(cfg.Identities) == 0 {
+		return nil, errors.New("missing identities")
+	}
+
+	v := &Verifier{
+		cfg: cfg,
+		now: time.Now,
+		cache: revocationCache{
+			ocsp: make(map[string]*ocsp.Response),
+			crls: make(map[string]*x509.RevocationList),
+		},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(v)
+		}
+	}
+
+	return v, nil
+}
+
+// Verify verifies an attestation and returns the verified envelope.
+func (v *Verifier) Verify(a Attestation) (*Result, error) {
+	var b bundle
+	if len(a.Bundle) > 0 {
+		if err := json.Unmarshal(a.Bundle, &b); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+	}
+
+	envData := a.Envelope
+	if len(envData) == 0 && b.Base64Signature != "" {
+		var err error
+		if envData, err = base64.StdEncoding.DecodeString(b.Base64Signature); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+	}
+	if err := dsse.ValidateEnvelopeJSON(envData); err != nil {
+		return nil, err
+	}
+	var env dsse.Envelope
+	if err := json.Unmarshal(envData, &env); err != nil {
+		return nil, err
+	}
+
+	certPEM := a.Certificate
+	if len(certPEM) == 0 && b.Cert != "" {
+		certPEM = decodeBundleCert(b.Cert)
+	}
+	cert, err := parseCertificate(certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Envelope: &env, Certificate: cert}
+	verifyTime := v.now()
+	if v.cfg.RekorPublicKey != nil {
+		if b.RekorBundle == nil {
+			return nil, ErrNoBundle
+		}
+		payload, err := env.DecodedPayload()
+		if err != nil {
+			return nil, err
+		}
+		if err := v.verifyRekorBundle(b.RekorBundle, cert, payload); err != nil {
+			return nil, err
+		}
+		res.IntegratedTime = time.Unix(b.RekorBundle.Payload.IntegratedTime, 0)
+		verifyTime = res.IntegratedTime
+	}
+
+	chains, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.cfg.Roots,
+		Intermediates: v.cfg.Intermediates,
+		CurrentTime:   verifyTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.checkIdentity(cert); err != nil {
+		return nil, err
+	}
+	if len(chains[0]) > 1 {
+		if err := v.checkRevocation(cert, chains[0][1], verifyTime); err != nil {
+			return nil, err
+		}
+	}
+
+	sv, err := verifierForKey(cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	ev, err := dsse.NewEnvelopeVerifier(sv)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ev.Verify(&env); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// checkIdentity checks that cert was issued to one of the expected identities.
+func (v *Verifier) checkIdentity(cert *x509.Certificate) error {
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return err
+	}
+
+	var subjects []string
+	subjects = append(subjects, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+
+	for _, id := range v.cfg.Identities {
+		if id.Issuer != nil && !id.Issuer.MatchString(issuer) {
+			continue
+		}
+		if id.Subject == nil {
+			return nil
+		}
+		for _, s := range subjects {
+			if id.Subject.MatchString(s) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: subjects %q, issuer %q", ErrIdentityMismatch, subjects, issuer)
+}
+
+/*
+verifyRekorBundle checks the signed entry timestamp of rb, and that the log
+entry references cert and the SHA-256 digest of payload. The entry is checked
+by its contents rather than its kind, so that both intoto and dsse entries are
+accepted.
+*/
+func (v *Verifier) verifyRekorBundle(rb *rekorBundle, cert *x509.Certificate, payload []byte) error {
+	// Rekor signs the RFC 8785 canonical form of the payload, which for
+	// these fields is the same as OLPC canonical JSON.
+	canonical, err := cjson.EncodeCanonical(rb.Payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	digest := sha256.Sum256(canonical)
+	pub, ok := v.cfg.RekorPublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%w: %T", dsse.ErrUnsupportedKey, v.cfg.RekorPublicKey)
+	}
+	if !ecdsa.VerifyASN1(pub, digest[:], rb.SignedEntryTimestamp) {
+		return fmt.Errorf("%w: signed entry timestamp does not verify", ErrInvalidBundle)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(rb.Payload.Body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+	var entry interface{}
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	payloadDigest := sha256.Sum256(payload)
+	wantDigest := hex.EncodeToString(payloadDigest[:])
+	var hasCert, hasDigest bool
+	walkStrings(entry, func(s string) {
+		if s == wantDigest {
+			hasDigest = true
+		}
+		if der, err := base64.StdEncoding.DecodeString(s); err == nil {
+			if block, _ := pem.Decode(der); block != nil && bytes.Equal(block.Bytes, cert.Raw) {
+				hasCert = true
+			}
+		}
+	})
+	if !hasCert {
+		return fmt.Errorf("%w: log entry does not reference the certificate", ErrInvalidBundle)
+	}
+	if !hasDigest {
+		return fmt.Errorf("%w: log entry does not reference the payload", ErrInvalidBundle)
+	}
+
+	return nil
+}
+
+// walkStrings calls f for every string in a decoded JSON value.
+func walkStrings(v interface{}, f func(string)) {
+	switch v := v.(type) {
+	case string:
+		f(v)
+	case []interface{}:
+		for _, e := range v {
+			walkStrings(e, f)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			walkStrings(e, f)
+		}
+	}
+}
+
+// certificateIssuer returns the OIDC issuer recorded in a Fulcio certificate.
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", err
+			}
+			return issuer, nil
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuer) {
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", nil
+}
+
+// decodeBundleCert returns the PEM certificate of a bundle, which cosign
+// stores base64 encoded.
+func decodeBundleCert(cert string) []byte {
+	if data, err := base64.StdEncoding.DecodeString(cert); err == nil {
+		return data
+	}
+
+	return []byte(cert)
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	if len(data) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// verifierForKey returns a dsse verifier for the key of a certificate. cosign
+// does not normalize ECDSA signatures and signs with RSA PKCS #1 v1.5, so
+// high-S signatures are accepted and RSA keys are not verified with PSS.
+func verifierForKey(pub crypto.PublicKey) (dsse.Verifier, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return dsse.NewECDSAVerifier("", k, dsse.WithAllowHighS())
+	case ed25519.PublicKey:
+		return dsse.NewEd25519Verifier("", k)
+	case *rsa.PublicKey:
+		return pkcs1v15Verifier{public: k}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", dsse.ErrUnsupportedKey, pub)
+}
+
+// pkcs1v15Verifier verifies RSA PKCS #1 v1.5 signatures over the SHA-256
+// digest of the message.
+type pkcs1v15Verifier struct {
+	public *rsa.PublicKey
+}
+
+func (v pkcs1v15Verifier) Verify(data, sig []byte) error {
+	digest := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(v.public, crypto.SHA256, digest[:], sig)
+}
+
+func (v pkcs1v15Verifier) KeyID() (string, error) {
+	return "", nil
+}
+
+func (v pkcs1v15Verifier) Public() crypto.PublicKey {
+	return v.public
+}
@@ -0,0 +1,204 @@
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse/internal/sigstoretest"
+	"github.com/stretchr/testify/assert"
+)
+
+type fixture struct {
+	*sigstoretest.Fixture
+	// envelope is the JSON encoding of Envelope.
+	envelope []byte
+}
+
+// newFixture creates a Fulcio-like root and a short-lived certificate that
+// was valid an hour ago, and an attestation signed with it. The functions in
+// leafOpts may modify the certificate template.
+func newFixture(t *testing.T, leafOpts ...func(*x509.Certificate)) *fixture {
+	f := &fixture{Fixture: sigstoretest.New(t, sigstoretest.Options{
+		Payload:     []byte(`{"_type":"https://in-toto.io/Statement/v0.1"}`),
+		LeafOptions: leafOpts,
+	})}
+	f.envelope = marshalEnvelope(t, f.Envelope)
+	return f
+}
+
+func marshalEnvelope(t *testing.T, env *dsse.Envelope) []byte {
+	data, err := json.Marshal(env)
+	assert.Nil(t, err, "unexpected error")
+	return data
+}
+
+// bundle returns a cosign bundle with a Rekor entry whose body is body.
+func (f *fixture) bundle(t *testing.T, body interface{}) []byte {
+	bodyJSON, err := json.Marshal(body)
+	assert.Nil(t, err, "unexpected error")
+
+	payload := rekorPayload{
+		Body:           base64.StdEncoding.EncodeToString(bodyJSON),
+		IntegratedTime: f.SignedAt.Unix(),
+		LogIndex:       42,
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+	}
+	canonical, err := cjson.EncodeCanonical(payload)
+	assert.Nil(t, err, "unexpected error")
+	digest := sha256.Sum256(canonical)
+	set, err := ecdsa.SignASN1(rand.Reader, f.RekorKey, digest[:])
+	assert.Nil(t, err, "unexpected error")
+
+	data, err := json.Marshal(bundle{
+		Base64Signature: base64.StdEncoding.EncodeToString(f.envelope),
+		Cert:            base64.StdEncoding.EncodeToString(f.LeafPEM),
+		RekorBundle:     &rekorBundle{SignedEntryTimestamp: set, Payload: payload},
+	})
+	assert.Nil(t, err, "unexpected error")
+	return data
+}
+
+// intotoEntry returns a Rekor intoto entry body for the attestation.
+func (f *fixture) intotoEntry() interface{} {
+	digest := sha256.Sum256(f.Payload)
+	return map[string]interface{}{
+		"apiVersion": "0.0.2",
+		"kind":       "intoto",
+		"spec": map[string]interface{}{
+			"content": map[string]interface{}{
+				"envelope": map[string]interface{}{
+					"payloadType": dsse.PayloadTypeInToto,
+					"signatures": []interface{}{
+						map[string]interface{}{
+							"publicKey": base64.StdEncoding.EncodeToString(f.LeafPEM),
+						},
+					},
+				},
+				"payloadHash": map[string]interface{}{
+					"algorithm": "sha256",
+					"value":     hex.EncodeToString(digest[:]),
+				},
+			},
+		},
+	}
+}
+
+func TestVerify(t *testing.T) {
+	f := newFixture(t)
+	identity := Identity{
+		Subject: regexp.MustCompile(`^signer@example\.com$`),
+		Issuer:  regexp.MustCompile(`^https://accounts\.example\.com$`),
+	}
+
+	v, err := NewVerifier(Config{
+		Roots:          f.Roots,
+		Identities:     []Identity{identity},
+		RekorPublicKey: &f.RekorKey.PublicKey,
+	})
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("Bundle", func(t *testing.T) {
+		res, err := v.Verify(Attestation{Bundle: f.bundle(t, f.intotoEntry())})
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, dsse.PayloadTypeInToto, res.Envelope.PayloadType, "wrong payload type")
+		assert.Equal(t, []string{sigstoretest.Subject}, res.Certificate.EmailAddresses, "wrong certificate")
+		assert.True(t, f.SignedAt.Equal(res.IntegratedTime), "wrong integrated time")
+	})
+
+	t.Run("Envelope and certificate", func(t *testing.T) {
+		_, err := v.Verify(Attestation{Envelope: f.envelope, Certificate: f.LeafPEM})
+		assert.Equal(t, ErrNoBundle, err, "wrong error")
+	})
+
+	t.Run("Expired certificate without Rekor", func(t *testing.T) {
+		noRekor, err := NewVerifier(Config{Roots: f.Roots, Identities: []Identity{identity}})
+		assert.Nil(t, err, "unexpected error")
+		_, err = noRekor.Verify(Attestation{Envelope: f.envelope, Certificate: f.LeafPEM})
+		var invalid x509.CertificateInvalidError
+		assert.True(t, errors.As(err, &invalid), "wrong error")
+		assert.Equal(t, x509.Expired, invalid.Reason, "wrong reason")
+	})
+
+	t.Run("Wrong identity", func(t *testing.T) {
+		other, err := NewVerifier(Config{
+			Roots:          f.Roots,
+			Identities:     []Identity{{Subject: regexp.MustCompile(`@example\.org$`)}},
+			RekorPublicKey: &f.RekorKey.PublicKey,
+		})
+		assert.Nil(t, err, "unexpected error")
+		_, err = other.Verify(Attestation{Bundle: f.bundle(t, f.intotoEntry())})
+		assert.True(t, errors.Is(err, ErrIdentityMismatch), "wrong error")
+	})
+
+	t.Run("Entry for other payload", func(t *testing.T) {
+		entry := f.intotoEntry()
+		content := entry.(map[string]interface{})["spec"].(map[string]interface{})["content"].(map[string]interface{})
+		content["payloadHash"] = map[string]interface{}{"algorithm": "sha256", "value": "00"}
+		_, err := v.Verify(Attestation{Bundle: f.bundle(t, entry)})
+		assert.True(t, errors.Is(err, ErrInvalidBundle), "wrong error")
+	})
+
+	t.Run("Tampered timestamp", func(t *testing.T) {
+		var b bundle
+		assert.Nil(t, json.Unmarshal(f.bundle(t, f.intotoEntry()), &b), "unexpected error")
+		b.RekorBundle.Payload.IntegratedTime++
+		data, err := json.Marshal(b)
+		assert.Nil(t, err, "unexpected error")
+		_, err = v.Verify(Attestation{Bundle: data})
+		assert.True(t, errors.Is(err, ErrInvalidBundle), "wrong error")
+	})
+
+	t.Run("Tampered envelope", func(t *testing.T) {
+		var env dsse.Envelope
+		assert.Nil(t, json.Unmarshal(f.envelope, &env), "unexpected error")
+		env.Payload = base64.StdEncoding.EncodeToString([]byte("{}"))
+		data, err := json.Marshal(env)
+		assert.Nil(t, err, "unexpected error")
+		_, err = v.Verify(Attestation{Envelope: data, Bundle: f.bundle(t, f.intotoEntry())})
+		assert.True(t, errors.Is(err, ErrInvalidBundle), "wrong error")
+	})
+
+	t.Run("High-S signature", func(t *testing.T) {
+		hs := *f
+		hs.envelope = marshalEnvelope(t, f.Sign(t, func(digest []byte) ([]byte, error) {
+			sig, err := ecdsa.SignASN1(rand.Reader, f.LeafKey, digest)
+			return sigstoretest.HighS(t, elliptic.P256(), sig), err
+		}))
+		_, err := v.Verify(Attestation{Bundle: hs.bundle(t, hs.intotoEntry())})
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("RSA certificate", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err, "unexpected error")
+		_, certPEM := f.Issue(t, &rsaKey.PublicKey)
+		envelope := marshalEnvelope(t, f.Sign(t, func(digest []byte) ([]byte, error) {
+			return rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+		}))
+
+		noRekor, err := NewVerifier(Config{Roots: f.Roots, Identities: []Identity{identity}})
+		assert.Nil(t, err, "unexpected error")
+		noRekor.now = func() time.Time { return f.SignedAt }
+		_, err = noRekor.Verify(Attestation{Envelope: envelope, Certificate: certPEM})
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("No certificate", func(t *testing.T) {
+		_, err := v.Verify(Attestation{Envelope: f.envelope})
+		assert.Equal(t, ErrNoCertificate, err, "wrong error")
+	})
+}
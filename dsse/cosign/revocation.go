@@ -0,0 +1,267 @@
+package cosign
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ErrRevoked indicates that the signing certificate was revoked before the
+// attestation was signed.
+var ErrRevoked = errors.New("certificate revoked")
+
+// ErrRevocationUnknown indicates that the revocation status of the signing
+// certificate could not be determined with RevocationHardFail.
+var ErrRevocationUnknown = errors.New("certificate revocation status unknown")
+
+// maxRevocationResponseSize limits the size of OCSP responses and CRLs.
+const maxRevocationResponseSize = 10 << 20
+
+// RevocationMode selects whether and how strictly the revocation of the
+// signing certificate is checked.
+type RevocationMode int
+
+const (
+	// RevocationOff does not check revocation. This is the default.
+	RevocationOff RevocationMode = iota
+	// RevocationSoftFail rejects revoked certificates, but accepts
+	// certificates whose status cannot be determined.
+	RevocationSoftFail
+	// RevocationHardFail rejects certificates whose status cannot be
+	// determined with ErrRevocationUnknown.
+	RevocationHardFail
+)
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+/*
+WithRevocationCheck checks that the signing certificate is not revoked. The
+status is asked from the OCSP responders named in the certificate, falling
+back to its CRL distribution points if no responder gives an answer. A
+certificate revoked before the time it is verified at, the time of the Rekor
+entry if a Rekor key is configured and the current time otherwise, is
+rejected with ErrRevoked. Responses are cached until their next update, so
+that verifying many attestations does not query the responders every time.
+Only the signing certificate is checked, not its issuers.
+*/
+func WithRevocationCheck(mode RevocationMode) Option {
+	return func(v *Verifier) {
+		v.revocation = mode
+	}
+}
+
+// WithHTTPClient sets the client used to query OCSP responders and download
+// CRLs. The default is http.DefaultClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(v *Verifier) {
+		v.client = client
+	}
+}
+
+// revocationCache holds the OCSP responses, by issuer and serial number, and
+// the CRLs, by URL, that have not reached their next update.
+type revocationCache struct {
+	mu   sync.Mutex
+	ocsp map[string]*ocsp.Response
+	crls map[string]*x509.RevocationList
+}
+
+// checkRevocation checks the revocation of cert, issued by issuer, at t.
+func (v *Verifier) checkRevocation(cert, issuer *x509.Certificate, t time.Time) error {
+	if v.revocation == RevocationOff {
+		return nil
+	}
+
+	revoked, revokedAt, err := v.revocationStatus(cert, issuer)
+	if err != nil {
+		if v.revocation == RevocationSoftFail {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", ErrRevocationUnknown, err)
+	}
+	if revoked && !revokedAt.After(t) {
+		return fmt.Errorf("%w: serial %s revoked at %s", ErrRevoked, cert.SerialNumber, revokedAt.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// revocationStatus asks OCSP, then the CRLs, whether cert is revoked.
+func (v *Verifier) revocationStatus(cert, issuer *x509.Certificate) (bool, time.Time, error) {
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		return false, time.Time{}, errors.New("certificate names no ocsp responder or crl")
+	}
+
+	var errs []error
+	resp, err := v.ocspStatus(cert, issuer)
+	if err == nil {
+		switch resp.Status {
+		case ocsp.Good:
+			return false, time.Time{}, nil
+		case ocsp.Revoked:
+			return true, resp.RevokedAt, nil
+		}
+		err = errors.New("ocsp: status unknown")
+	}
+	errs = append(errs, err)
+
+	revoked, revokedAt, err := v.crlStatus(cert, issuer)
+	if err == nil {
+		return revoked, revokedAt, nil
+	}
+	errs = append(errs, err)
+
+	return false, time.Time{}, errors.Join(errs...)
+}
+
+// ocspStatus returns the OCSP response for cert, from the cache or from the
+// first responder that answers.
+func (v *Verifier) ocspStatus(cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	digest := sha256.Sum256(issuer.Raw)
+	key := hex.EncodeToString(digest[:]) + ":" + cert.SerialNumber.String()
+
+	v.cache.mu.Lock()
+	resp := v.cache.ocsp[key]
+	v.cache.mu.Unlock()
+	if resp != nil && v.now().Before(resp.NextUpdate) {
+		return resp, nil
+	}
+
+	if len(cert.OCSPServer) == 0 {
+		return nil, errors.New("ocsp: no responder")
+	}
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, url := range cert.OCSPServer {
+		resp, err := v.queryOCSP(url, req, cert, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !resp.NextUpdate.IsZero() {
+			v.cache.mu.Lock()
+			v.cache.ocsp[key] = resp
+			v.cache.mu.Unlock()
+		}
+		return resp, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (v *Verifier) queryOCSP(url string, req []byte, cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	httpReq, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+
+	body, err := v.fetch(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ocsp: %v", err)
+	}
+	resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("ocsp: %s: %v", url, err)
+	}
+	if !resp.NextUpdate.IsZero() && v.now().After(resp.NextUpdate) {
+		return nil, fmt.Errorf("ocsp: %s: stale response", url)
+	}
+
+	return resp, nil
+}
+
+// crlStatus looks up cert in the first CRL that can be obtained.
+func (v *Verifier) crlStatus(cert, issuer *x509.Certificate) (bool, time.Time, error) {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return false, time.Time{}, errors.New("crl: no distribution point")
+	}
+
+	var errs []error
+	for _, url := range cert.CRLDistributionPoints {
+		crl, err := v.crl(url, issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// RevokedCertificateEntries replaces this field in Go 1.21, but the
+		// module supports Go 1.20, and the parser fills in both.
+		for _, entry := range crl.RevokedCertificates {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return true, entry.RevocationTime, nil
+			}
+		}
+		return false, time.Time{}, nil
+	}
+
+	return false, time.Time{}, errors.Join(errs...)
+}
+
+// crl returns the CRL at url, from the cache or downloaded.
+func (v *Verifier) crl(url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	v.cache.mu.Lock()
+	crl := v.cache.crls[url]
+	v.cache.mu.Unlock()
+	if crl != nil && v.now().Before(crl.NextUpdate) {
+		return crl, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := v.fetch(req)
+	if err != nil {
+		return nil, fmt.Errorf("crl: %v", err)
+	}
+	crl, err = x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, fmt.Errorf("crl: %s: %v", url, err)
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("crl: %s: %v", url, err)
+	}
+	if !crl.NextUpdate.IsZero() && v.now().After(crl.NextUpdate) {
+		return nil, fmt.Errorf("crl: %s: stale crl", url)
+	}
+
+	if !crl.NextUpdate.IsZero() {
+		v.cache.mu.Lock()
+		v.cache.crls[url] = crl
+		v.cache.mu.Unlock()
+	}
+	return crl, nil
+}
+
+// fetch returns the body of a successful response to req.
+func (v *Verifier) fetch(req *http.Request) ([]byte, error) {
+	client := v.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", req.URL, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxRevocationResponseSize))
+}
@@ -0,0 +1,175 @@
+package cosign
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+// revocationServer is an OCSP responder and CRL distribution point for the
+// certificates of a fixture.
+type revocationServer struct {
+	f          *fixture
+	revokedAt  time.Time
+	ocspDown   bool
+	crlDown    bool
+	ocspCalls  int
+	crlCalls   int
+	ocspServer *httptest.Server
+	crlServer  *httptest.Server
+}
+
+func newRevocationServer(t *testing.T) *revocationServer {
+	s := &revocationServer{}
+	s.ocspServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.ocspCalls++
+		if s.ocspDown {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err, "unexpected error")
+		req, err := ocsp.ParseRequest(body)
+		assert.Nil(t, err, "unexpected error")
+
+		now := time.Now()
+		tmpl := ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   now.Add(-time.Minute),
+			NextUpdate:   now.Add(time.Hour),
+		}
+		if !s.revokedAt.IsZero() {
+			tmpl.Status, tmpl.RevokedAt = ocsp.Revoked, s.revokedAt
+		}
+		resp, err := ocsp.CreateResponse(s.f.Root, s.f.Root, tmpl, s.f.RootKey)
+		assert.Nil(t, err, "unexpected error")
+		_, _ = w.Write(resp)
+	}))
+	s.crlServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.crlCalls++
+		if s.crlDown {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+
+		now := time.Now()
+		tmpl := &x509.RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: now.Add(-time.Minute),
+			NextUpdate: now.Add(time.Hour),
+		}
+		if !s.revokedAt.IsZero() {
+			tmpl.RevokedCertificates = []pkix.RevokedCertificate{{
+				SerialNumber:   s.f.Leaf.SerialNumber,
+				RevocationTime: s.revokedAt,
+			}}
+		}
+		crl, err := x509.CreateRevocationList(rand.Reader, tmpl, s.f.Root, s.f.RootKey)
+		assert.Nil(t, err, "unexpected error")
+		_, _ = w.Write(crl)
+	}))
+	t.Cleanup(s.ocspServer.Close)
+	t.Cleanup(s.crlServer.Close)
+
+	s.f = newFixture(t, func(leaf *x509.Certificate) {
+		leaf.OCSPServer = []string{s.ocspServer.URL}
+		leaf.CRLDistributionPoints = []string{s.crlServer.URL}
+	})
+	return s
+}
+
+func TestRevocationCheck(t *testing.T) {
+	s := newRevocationServer(t)
+	cfg := Config{
+		Roots: s.f.Roots,
+		Identities: []Identity{{
+			Subject: regexp.MustCompile(`^signer@example\.com$`),
+			Issuer:  regexp.MustCompile(`^https://accounts\.example\.com$`),
+		}},
+		RekorPublicKey: &s.f.RekorKey.PublicKey,
+	}
+	a := Attestation{Bundle: s.f.bundle(t, s.f.intotoEntry())}
+
+	verify := func(mode RevocationMode) error {
+		v, err := NewVerifier(cfg, WithRevocationCheck(mode))
+		assert.Nil(t, err, "unexpected error")
+		_, err = v.Verify(a)
+		return err
+	}
+
+	t.Run("Good", func(t *testing.T) {
+		v, err := NewVerifier(cfg, WithRevocationCheck(RevocationHardFail))
+		assert.Nil(t, err, "unexpected error")
+		for i := 0; i < 3; i++ {
+			_, err = v.Verify(a)
+			assert.Nil(t, err, "unexpected error")
+		}
+		assert.Equal(t, 1, s.ocspCalls, "ocsp response not cached")
+		assert.Equal(t, 0, s.crlCalls, "unexpected crl download")
+	})
+
+	t.Run("Revoked before signing", func(t *testing.T) {
+		s.revokedAt = s.f.SignedAt.Add(-time.Minute)
+		defer func() { s.revokedAt = time.Time{} }()
+
+		err := verify(RevocationSoftFail)
+		assert.True(t, errors.Is(err, ErrRevoked), "wrong error")
+		assert.Nil(t, verify(RevocationOff), "unexpected error")
+	})
+
+	t.Run("Revoked after signing", func(t *testing.T) {
+		s.revokedAt = s.f.SignedAt.Add(time.Minute)
+		defer func() { s.revokedAt = time.Time{} }()
+
+		assert.Nil(t, verify(RevocationHardFail), "unexpected error")
+	})
+
+	t.Run("CRL fallback", func(t *testing.T) {
+		s.revokedAt = s.f.SignedAt.Add(-time.Minute)
+		s.ocspDown = true
+		defer func() { s.revokedAt, s.ocspDown = time.Time{}, false }()
+
+		calls := s.crlCalls
+		err := verify(RevocationHardFail)
+		assert.True(t, errors.Is(err, ErrRevoked), "wrong error")
+		assert.Equal(t, calls+1, s.crlCalls, "crl not downloaded")
+	})
+
+	t.Run("Status unknown", func(t *testing.T) {
+		s.ocspDown, s.crlDown = true, true
+		defer func() { s.ocspDown, s.crlDown = false, false }()
+
+		assert.Nil(t, verify(RevocationSoftFail), "unexpected error")
+		err := verify(RevocationHardFail)
+		assert.True(t, errors.Is(err, ErrRevocationUnknown), "wrong error")
+	})
+
+	t.Run("No revocation information", func(t *testing.T) {
+		f := newFixture(t)
+		cfg := cfg
+		cfg.Roots, cfg.RekorPublicKey = f.Roots, &f.RekorKey.PublicKey
+		a := Attestation{Bundle: f.bundle(t, f.intotoEntry())}
+
+		v, err := NewVerifier(cfg, WithRevocationCheck(RevocationSoftFail))
+		assert.Nil(t, err, "unexpected error")
+		_, err = v.Verify(a)
+		assert.Nil(t, err, "unexpected error")
+
+		v, err = NewVerifier(cfg, WithRevocationCheck(RevocationHardFail))
+		assert.Nil(t, err, "unexpected error")
+		_, err = v.Verify(a)
+		assert.True(t, errors.Is(err, ErrRevocationUnknown), "wrong error")
+	})
+}
@@ -0,0 +1,71 @@
+package dsse
+
+import (
+	"encoding/json"
+	"io"
+)
+
+/*
+detachedSignature is the sidecar format written by WriteDetachedSignature. It
+is a JSON envelope without the payload:
+
+	{"payloadType": "...", "signatures": [{"keyid": "...", "sig": "..."}]}
+
+The additional authenticated data of the envelope, if any, is kept in the aad
+field, since the signatures cover it. The payload is distributed separately,
+typically as the artifact next to the sidecar file, and the signatures are
+computed over the PAE of the payload type and the raw artifact bytes as for
+any envelope.
+*/
+type detachedSignature struct {
+	PayloadType string      `json:"payloadType"`
+	AAD         string      `json:"aad,omitempty"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+/*
+WriteDetachedSignature writes the payload type, additional authenticated data
+and signatures of env to w in the sidecar format, followed by a newline. The
+payload is not written.
+*/
+func WriteDetachedSignature(w io.Writer, env *Envelope) error {
+	if env == nil {
+		return ErrNoEnvelopes
+	}
+	if len(env.Signatures) == 0 {
+		return ErrNoSignature
+	}
+
+	return json.NewEncoder(w).Encode(detachedSignature{
+		PayloadType: env.PayloadType,
+		AAD:         env.AAD,
+		Signatures:  env.Signatures,
+	})
+}
+
+/*
+ReadDetachedSignature reads a sidecar written by WriteDetachedSignature. The
+returned envelope has an empty payload; verify it against the artifact with
+VerifyStream, or set Payload to the base64 encoding of the artifact and call
+Verify, or VerifyWithAAD if the sidecar carries additional authenticated
+data. A structurally invalid sidecar yields a *ValidationError.
+*/
+func ReadDetachedSignature(r io.Reader) (*Envelope, error) {
+	var ds detachedSignature
+	if err := json.NewDecoder(r).Decode(&ds); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	if ds.PayloadType == "" {
+		return nil, &ValidationError{Field: "payloadType", Err: errMissing}
+	}
+	if len(ds.Signatures) == 0 {
+		return nil, &ValidationError{Field: "signatures", Err: ErrNoSignature}
+	}
+
+	return &Envelope{
+		PayloadType: ds.PayloadType,
+		AAD:         ds.AAD,
+		Signatures:  ds.Signatures,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package dsse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetachedSignature(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	var buf bytes.Buffer
+	assert.Nil(t, WriteDetachedSignature(&buf, env), "unexpected error")
+	assert.NotContains(t, buf.String(), env.Payload, "payload written to sidecar")
+
+	detached, err := ReadDetachedSignature(&buf)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, payloadType, detached.PayloadType, "wrong payload type")
+	assert.Equal(t, env.Signatures, detached.Signatures, "wrong signatures")
+	assert.Equal(t, "", detached.Payload, "unexpected payload")
+
+	acceptedKeys, err := signer.VerifyStream(detached, bytes.NewReader(payload))
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+	detached.Payload = base64.StdEncoding.EncodeToString(payload)
+	_, err = signer.Verify(detached)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = signer.VerifyStream(detached, strings.NewReader("goodbye world"))
+	assert.NotNil(t, err, "expected error")
+
+	t.Run("AAD", func(t *testing.T) {
+		env, err := signer.SignPayloadWithAAD(payloadType, payload, []byte("context"))
+		assert.Nil(t, err, "sign failed")
+
+		var buf bytes.Buffer
+		assert.Nil(t, WriteDetachedSignature(&buf, env), "unexpected error")
+		detached, err := ReadDetachedSignature(&buf)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, env.AAD, detached.AAD, "wrong aad")
+
+		detached.Payload = env.Payload
+		_, err = signer.VerifyWithAAD(detached, []byte("context"))
+		assert.Nil(t, err, "unexpected error")
+	})
+}
+
+func TestDetachedSignatureErrors(t *testing.T) {
+	assert.Equal(t, ErrNoEnvelopes, WriteDetachedSignature(&bytes.Buffer{}, nil), "wrong error")
+	assert.Equal(t, ErrNoSignature, WriteDetachedSignature(&bytes.Buffer{}, &Envelope{PayloadType: "t"}), "wrong error")
+
+	tests := map[string]string{
+		"not json":         `sig`,
+		"no payload type":  `{"signatures":[{"keyid":"k","sig":"c2ln"}]}`,
+		"no signatures":    `{"payloadType":"t"}`,
+		"empty signatures": `{"payloadType":"t","signatures":[]}`,
+		"wrong signatures": `{"payloadType":"t","signatures":"c2ln"}`,
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ReadDetachedSignature(strings.NewReader(input))
+			assert.True(t, errors.Is(err, ErrInvalidEnvelope), "wrong error")
+		})
+	}
+}
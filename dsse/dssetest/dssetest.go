@@ -0,0 +1,77 @@
+/*
+Package dssetest provides a fixed signing key and a known-good envelope for
+tests of packages that build on dsse.
+
+The key is public and must only be used in tests. Anything signed with it can
+be forged by anyone.
+*/
+package dssetest
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// TestKeyID is the key ID of the signer returned by NewTestSigner.
+const TestKeyID = "dssetest"
+
+// The payload type and payload of the envelope returned by GoldenEnvelope.
+const (
+	GoldenPayloadType = "http://example.com/HelloWorld"
+	GoldenPayload     = "hello world"
+)
+
+// testSeed is the Ed25519 seed of the test key, taken from RFC 8032 test 1.
+// TEST ONLY, DO NOT USE.
+const testSeed = "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60"
+
+// goldenEnvelope is GoldenPayload signed by the test key.
+const goldenEnvelope = `{
+  "payloadType": "http://example.com/HelloWorld",
+  "payload": "aGVsbG8gd29ybGQ=",
+  "signatures": [
+    {
+      "keyid": "dssetest",
+      "sig": "4DHX3Zn4qpBKvEj7maE8O9u9bjXEnPLLnyXVUJ2PXJR8DSLcL3QDpFvfJOj3pB/SPHsl6Jg4boxsMb6KvuYABw==",
+      "extensions": {
+        "alg": "ed25519"
+      }
+    }
+  ]
+}`
+
+/*
+NewTestSigner returns an Ed25519 signer with a fixed key and the key ID
+TestKeyID. Ed25519 signatures are deterministic, so signing the same payload
+always yields the same envelope.
+*/
+func NewTestSigner() *dsse.Ed25519SignerVerifier {
+	seed, err := hex.DecodeString(testSeed)
+	if err != nil {
+		panic(err)
+	}
+
+	sv, err := dsse.NewEd25519SignerVerifier(TestKeyID, ed25519.NewKeyFromSeed(seed))
+	if err != nil {
+		panic(err)
+	}
+
+	return sv
+}
+
+/*
+GoldenEnvelope returns a new copy of an envelope with payload GoldenPayload
+and payload type GoldenPayloadType, signed by the signer returned by
+NewTestSigner.
+*/
+func GoldenEnvelope() *dsse.Envelope {
+	var env dsse.Envelope
+	if err := json.Unmarshal([]byte(goldenEnvelope), &env); err != nil {
+		panic(err)
+	}
+
+	return &env
+}
@@ -0,0 +1,29 @@
+package dssetest
+
+import (
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoldenEnvelope(t *testing.T) {
+	signer, err := dsse.NewEnvelopeSigner(NewTestSigner())
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := signer.Verify(GoldenEnvelope())
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+	assert.Equal(t, TestKeyID, acceptedKeys[0].KeyID, "wrong keyid")
+
+	env, err := signer.SignPayload(GoldenPayloadType, []byte(GoldenPayload))
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, GoldenEnvelope(), env, "signing is not deterministic")
+}
+
+func TestGoldenEnvelopeCopy(t *testing.T) {
+	env := GoldenEnvelope()
+	env.Signatures[0].Sig = ""
+
+	assert.NotEqual(t, env, GoldenEnvelope(), "envelope shared between calls")
+}
@@ -0,0 +1,87 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateKeyID indicates that an envelope has several signatures with
+// the same key ID and the DuplicateKeyIDPolicy does not accept them.
+var ErrDuplicateKeyID = errors.New("duplicate key ID")
+
+/*
+DuplicateKeyIDPolicy selects how a verifier treats an envelope with several
+signatures claiming the same key ID, which may be a signer's mistake or an
+attempt to count one key several times. Signatures without a key ID are never
+duplicates.
+*/
+type DuplicateKeyIDPolicy int
+
+const (
+	// DuplicateKeyIDCountOnce accepts the envelope and counts a key at
+	// most once toward the threshold, however many of its signatures
+	// verify. The other signatures of the key are ignored, valid or not.
+	// This is the default.
+	DuplicateKeyIDCountOnce DuplicateKeyIDPolicy = iota
+	// DuplicateKeyIDReject rejects the envelope before any signature is
+	// verified.
+	DuplicateKeyIDReject
+	// DuplicateKeyIDRequireAllMatch requires every signature of a
+	// duplicated key ID to verify with a verifier of that key ID, and
+	// rejects the envelope otherwise. The key still counts once toward the
+	// threshold.
+	DuplicateKeyIDRequireAllMatch
+)
+
+// WithDuplicateKeyIDPolicy sets the policy for signatures with the same key
+// ID. The default is DuplicateKeyIDCountOnce.
+func WithDuplicateKeyIDPolicy(policy DuplicateKeyIDPolicy) Option {
+	return func(o *options) {
+		o.duplicateKeyIDs = policy
+	}
+}
+
+// checkDuplicateKeyIDs applies the duplicate key ID policy to the signatures
+// over msg. Errors wrap ErrDuplicateKeyID.
+func (ev *envelopeVerifier) checkDuplicateKeyIDs(msg *message, signatures []Signature) error {
+	if ev.opts.duplicateKeyIDs == DuplicateKeyIDCountOnce {
+		return nil
+	}
+
+	count := make(map[string]int)
+	for _, s := range signatures {
+		if s.KeyID != "" {
+			count[ev.opts.keyID(s.KeyID)]++
+		}
+	}
+
+	for i, s := range signatures {
+		if s.KeyID == "" || count[ev.opts.keyID(s.KeyID)] < 2 {
+			continue
+		}
+		if ev.opts.duplicateKeyIDs == DuplicateKeyIDReject {
+			return fmt.Errorf("%w: %s", ErrDuplicateKeyID, s.KeyID)
+		}
+		if !ev.matchesKeyID(msg, s) {
+			return fmt.Errorf("%w: signature %d of %s does not verify", ErrDuplicateKeyID, i, s.KeyID)
+		}
+	}
+
+	return nil
+}
+
+// matchesKeyID reports whether s verifies with a verifier of its key ID.
+func (ev *envelopeVerifier) matchesKeyID(msg *message, s Signature) bool {
+	sig, err := ev.opts.decode(s.Sig)
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+
+	for _, i := range ev.index[ev.opts.keyID(s.KeyID)] {
+		if offered, err := ev.offer(ev.providers[i], ev.keyIDs[i], msg, s, sig); offered && err == nil {
+			return true
+		}
+	}
+
+	return false
+}
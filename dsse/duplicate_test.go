@@ -0,0 +1,84 @@
+package dsse
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateKeyIDPolicy(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	newSigner := func(keyID string) *Ed25519SignerVerifier {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+		sv, err := NewEd25519SignerVerifier(keyID, key)
+		assert.Nil(t, err, "unexpected error")
+		return sv
+	}
+	a, b := newSigner("a"), newSigner("b")
+	// impostor claims the key ID of a.
+	impostor := newSigner("a")
+
+	signer, err := NewMultiEnvelopeSigner(3, a, b, impostor)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+	sigA, sigB, sigImpostor := env.Signatures[0], env.Signatures[1], env.Signatures[2]
+
+	envelope := func(signatures ...Signature) *Envelope {
+		e := env.Clone()
+		e.Signatures = signatures
+		return e
+	}
+	valid := envelope(sigA, sigA, sigB)
+	invalid := envelope(sigA, sigImpostor, sigB)
+	onlyA := envelope(sigA, sigA)
+
+	verify := func(policy DuplicateKeyIDPolicy, e *Envelope) ([]AcceptedKey, int, error) {
+		var calls int
+		ev, err := NewEnvelopeVerifierWithOptions(2, []Verifier{
+			countingVerifier{Verifier: a, calls: &calls},
+			countingVerifier{Verifier: b, calls: &calls},
+		}, WithDuplicateKeyIDPolicy(policy))
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(e)
+		return acceptedKeys, calls, err
+	}
+
+	t.Run("Count once", func(t *testing.T) {
+		for _, e := range []*Envelope{valid, invalid} {
+			acceptedKeys, _, err := verify(DuplicateKeyIDCountOnce, e)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, acceptedKeys, 2, "wrong number of accepted keys")
+		}
+		_, _, err := verify(DuplicateKeyIDCountOnce, onlyA)
+		assert.NotNil(t, err, "key counted twice")
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		for _, e := range []*Envelope{valid, invalid, onlyA} {
+			_, calls, err := verify(DuplicateKeyIDReject, e)
+			assert.True(t, errors.Is(err, ErrDuplicateKeyID), "wrong error")
+			assert.Equal(t, 0, calls, "signatures verified")
+		}
+		_, _, err := verify(DuplicateKeyIDReject, envelope(sigA, sigB))
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Require all match", func(t *testing.T) {
+		acceptedKeys, _, err := verify(DuplicateKeyIDRequireAllMatch, valid)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2, "wrong number of accepted keys")
+
+		_, _, err = verify(DuplicateKeyIDRequireAllMatch, invalid)
+		assert.True(t, errors.Is(err, ErrDuplicateKeyID), "wrong error")
+
+		_, _, err = verify(DuplicateKeyIDRequireAllMatch, onlyA)
+		assert.NotNil(t, err, "key counted twice")
+		assert.False(t, errors.Is(err, ErrDuplicateKeyID), "wrong error")
+	})
+}
@@ -0,0 +1,335 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/codahale/rfc6979"
+)
+
+// ErrHighS indicates that an ECDSA signature is not in canonical low-S form.
+var ErrHighS = errors.New("non-canonical ecdsa signature: high S value")
+
+/*
+ECDSASignerVerifier is a SignVerifier using ECDSA. By default the message is
+hashed with SHA-256, SHA-384 or SHA-512 for the P-256, P-384 and P-521 curves
+respectively; WithECDSAHash selects another of these hashes. With the
+secp256k1 build tag, keys on the Secp256k1 curve are supported as well. The
+signer and the verifier must use the same hash. A verifier-only instance,
+created with NewECDSAVerifier, returns ErrNoPrivateKey from Sign.
+
+For every valid signature (r, s), (r, n-s) is valid as well, and the same
+values can be encoded in more than one way, so a third party can turn a
+signature into a different but valid one. To make signatures unique, Sign
+always produces the low-S form, s <= n/2, and Verify rejects the high-S form
+with ErrHighS. Signatures are ASN.1 DER encoded unless another encoding is set
+with WithSignatureEncoding, and Verify accepts that encoding only.
+WithAllowHighS relaxes both checks for interoperability with signers that do
+not normalize S: Verify then accepts high-S signatures in any supported
+encoding.
+*/
+type ECDSASignerVerifier struct {
+	keyID      string
+	private    *ecdsa.PrivateKey
+	public     *ecdsa.PublicKey
+	hash       crypto.Hash
+	allowHighS bool
+	encoding   SignatureEncoding
+	// deterministic selects RFC 6979 nonces.
+	deterministic bool
+}
+
+// ECDSAOption configures an ECDSASignerVerifier.
+type ECDSAOption func(*ECDSASignerVerifier)
+
+// WithAllowHighS makes Verify accept signatures that are not in low-S form,
+// in any supported encoding.
+func WithAllowHighS() ECDSAOption {
+	return func(sv *ECDSASignerVerifier) {
+		sv.allowHighS = true
+	}
+}
+
+// WithSignatureEncoding sets the encoding of the signatures created by Sign
+// and accepted by Verify.
+func WithSignatureEncoding(encoding SignatureEncoding) ECDSAOption {
+	return func(sv *ECDSASignerVerifier) {
+		sv.encoding = encoding
+	}
+}
+
+// WithECDSAHash sets the hash applied to the message. It must be SHA-256,
+// SHA-384 or SHA-512.
+func WithECDSAHash(hash crypto.Hash) ECDSAOption {
+	return func(sv *ECDSASignerVerifier) {
+		sv.hash = hash
+	}
+}
+
+/*
+WithDeterministicSignatures makes Sign derive the nonce from the key and the
+digest as specified by RFC 6979, instead of from random bytes, so that
+signing the same message with the same key always gives the same signature.
+This makes envelopes reproducible and does not depend on the quality of the
+system's random number generator. Verification is not affected.
+*/
+func WithDeterministicSignatures() ECDSAOption {
+	return func(sv *ECDSASignerVerifier) {
+		sv.deterministic = true
+	}
+}
+
+/*
+NewECDSASignerVerifier creates an ECDSASignerVerifier from a private key.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewECDSASignerVerifier(keyID string, private *ecdsa.PrivateKey, opts ...ECDSAOption) (*ECDSASignerVerifier, error) {
+	if private == nil {
+		return nil, errors.New("missing ecdsa private key")
+	}
+
+	sv, err := NewECDSAVerifier(keyID, &private.PublicKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sv.private = private
+
+	return sv, nil
+}
+
+/*
+NewECDSAVerifier creates an ECDSASignerVerifier that can only verify.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewECDSAVerifier(keyID string, public *ecdsa.PublicKey, opts ...ECDSAOption) (*ECDSASignerVerifier, error) {
+	if public == nil {
+		return nil, errors.New("missing ecdsa public key")
+	}
+
+	hash, err := ecdsaHash(public.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID == "" {
+		keyID, err = SHA256KeyID(public)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sv := &ECDSASignerVerifier{
+		keyID:  keyID,
+		public: public,
+		hash:   hash,
+	}
+	for _, opt := range opts {
+		opt(sv)
+	}
+	if err := checkHash(sv.hash); err != nil {
+		return nil, err
+	}
+
+	return sv, nil
+}
+
+// Sign hashes data and signs the digest with the private key.
+func (sv *ECDSASignerVerifier) Sign(data []byte) ([]byte, error) {
+	h := sv.hash.New()
+	h.Write(data)
+
+	return sv.SignDigest(h.Sum(nil), sv.hash)
+}
+
+// SignDigest signs a digest computed with hash.
+func (sv *ECDSASignerVerifier) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	if sv.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+	if hash != sv.hash {
+		return nil, fmt.Errorf("%w: digest uses %v, signer uses %v", ErrAlgorithmMismatch, hash, sv.hash)
+	}
+
+	var r, s *big.Int
+	var err error
+	if impl, ok := ecdsaImpls[sv.public.Curve]; ok {
+		r, s, err = impl.sign(sv.private, digest)
+	} else if sv.deterministic {
+		r, s, err = rfc6979.SignECDSA(sv.private, digest, sv.hash.New)
+	} else {
+		r, s, err = ecdsa.Sign(rand.Reader, sv.private, digest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ecdsaMarshal(sv.public.Curve, r, ecdsaLowS(sv.public.Curve, s), sv.encoding)
+}
+
+// Verify hashes data and verifies sig over the digest with the public key.
+func (sv *ECDSASignerVerifier) Verify(data, sig []byte) error {
+	h := sv.hash.New()
+	h.Write(data)
+
+	return sv.VerifyDigest(h.Sum(nil), sv.hash, sig)
+}
+
+// HashFunc returns the hash applied to the message.
+func (sv *ECDSASignerVerifier) HashFunc() crypto.Hash {
+	return sv.hash
+}
+
+// VerifyDigest verifies sig over a digest computed with hash.
+func (sv *ECDSASignerVerifier) VerifyDigest(digest []byte, hash crypto.Hash, sig []byte) error {
+	if hash != sv.hash {
+		return fmt.Errorf("%w: digest uses %v, verifier uses %v", ErrAlgorithmMismatch, hash, sv.hash)
+	}
+
+	// With high-S allowed signatures are not unique anyway, so any encoding
+	// is accepted.
+	parse := func(sig []byte) (*big.Int, *big.Int, error) {
+		return ecdsaParseAs(sv.public.Curve, sig, sv.encoding)
+	}
+	if sv.allowHighS {
+		parse = func(sig []byte) (*big.Int, *big.Int, error) {
+			return ecdsaParse(sv.public.Curve, sig)
+		}
+	}
+	r, s, err := parse(sig)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	if !sv.allowHighS && ecdsaIsHighS(sv.public.Curve, s) {
+		return ErrHighS
+	}
+
+	verify := ecdsa.Verify
+	if impl, ok := ecdsaImpls[sv.public.Curve]; ok {
+		verify = impl.verify
+	}
+	if !verify(sv.public, digest, r, s) {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+/*
+lowS returns sig in low-S form, in the encoding it was given in. Signatures
+that do not parse are returned unchanged, for Verify to reject.
+*/
+func (sv *ECDSASignerVerifier) lowS(sig []byte) []byte {
+	curve := sv.public.Curve
+	encoding := SignatureEncodingDER
+	r, s, err := ecdsaParseDER(sig)
+	if err != nil {
+		encoding = SignatureEncodingRaw
+		if r, s, err = ecdsaParseRaw(curve, sig); err != nil {
+			return sig
+		}
+	}
+	if !ecdsaIsHighS(curve, s) {
+		return sig
+	}
+
+	low, err := ecdsaMarshal(curve, r, ecdsaLowS(curve, s), encoding)
+	if err != nil {
+		return sig
+	}
+	return low
+}
+
+// KeyID returns the key ID of the key.
+func (sv *ECDSASignerVerifier) KeyID() (string, error) {
+	return sv.keyID, nil
+}
+
+// Public returns the public key.
+func (sv *ECDSASignerVerifier) Public() crypto.PublicKey {
+	return sv.public
+}
+
+// Algorithm returns the name of the algorithm, such as "ecdsa-p256-sha256".
+func (sv *ECDSASignerVerifier) Algorithm() string {
+	curve := strings.ToLower(strings.ReplaceAll(sv.public.Curve.Params().Name, "-", ""))
+	return fmt.Sprintf("ecdsa-%s-%s", curve, hashName(sv.hash))
+}
+
+/*
+ecdsaCurves maps the supported curves to their default hash. Curves that are
+only available with a build tag, such as secp256k1, are added by the files
+implementing them.
+*/
+var ecdsaCurves = map[elliptic.Curve]crypto.Hash{
+	elliptic.P256(): crypto.SHA256,
+	elliptic.P384(): crypto.SHA384,
+	elliptic.P521(): crypto.SHA512,
+}
+
+/*
+ecdsaImpl signs and verifies on a curve that crypto/ecdsa has no constant time
+implementation of. The files adding such curves to ecdsaCurves register one in
+ecdsaImpls, which Sign and Verify then use instead of crypto/ecdsa.
+*/
+type ecdsaImpl struct {
+	sign   func(private *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error)
+	verify func(public *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool
+}
+
+var ecdsaImpls = map[elliptic.Curve]ecdsaImpl{}
+
+func ecdsaHash(curve elliptic.Curve) (crypto.Hash, error) {
+	if hash, ok := ecdsaCurves[curve]; ok {
+		return hash, nil
+	}
+
+	return 0, errors.New("unsupported ecdsa curve")
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+func ecdsaParseDER(sig []byte) (*big.Int, *big.Int, error) {
+	var es ecdsaSignature
+	rest, err := asn1.Unmarshal(sig, &es)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("trailing data after ecdsa signature")
+	}
+	if es.R.Sign() <= 0 || es.S.Sign() <= 0 {
+		return nil, nil, errors.New("invalid ecdsa signature values")
+	}
+
+	return es.R, es.S, nil
+}
+
+func ecdsaMarshalDER(r, s *big.Int) ([]byte, error) {
+	return asn1.Marshal(ecdsaSignature{R: r, S: s})
+}
+
+// ecdsaIsHighS reports whether s is greater than half the curve order.
+func ecdsaIsHighS(curve elliptic.Curve, s *big.Int) bool {
+	halfOrder := new(big.Int).Rsh(curve.Params().N, 1)
+	return s.Cmp(halfOrder) > 0
+}
+
+// ecdsaLowS returns the low-S form of s.
+func ecdsaLowS(curve elliptic.Curve, s *big.Int) *big.Int {
+	if ecdsaIsHighS(curve, s) {
+		return new(big.Int).Sub(curve.Params().N, s)
+	}
+	return s
+}
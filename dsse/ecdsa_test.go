@@ -0,0 +1,191 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+highSSignature signs the hash of data with key like an implementation that
+does not normalize S, such as OpenSSL or an HSM, and returns the DER
+signature in high-S form.
+*/
+func highSSignature(t *testing.T, key *ecdsa.PrivateKey, hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, h.Sum(nil))
+	assert.Nil(t, err, "sign failed")
+
+	r, s, err := ecdsaParseDER(sig)
+	assert.Nil(t, err, "unexpected error")
+	if !ecdsaIsHighS(key.Curve, s) {
+		s = new(big.Int).Sub(key.Curve.Params().N, s)
+	}
+	sig, err = ecdsaMarshalDER(r, s)
+	assert.Nil(t, err, "unexpected error")
+	return sig
+}
+
+func TestECDSASignerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			assert.Nil(t, err, "unexpected error")
+
+			sv, err := NewECDSASignerVerifier("", key)
+			assert.Nil(t, err, "unexpected error")
+
+			signer, err := NewEnvelopeSigner(sv)
+			assert.Nil(t, err, "unexpected error")
+
+			env, err := signer.SignPayload(payloadType, []byte(payload))
+			assert.Nil(t, err, "sign failed")
+
+			v, err := NewECDSAVerifier("", &key.PublicKey)
+			assert.Nil(t, err, "unexpected error")
+			ev, err := NewEnvelopeVerifier(v)
+			assert.Nil(t, err, "unexpected error")
+
+			acceptedKeys, err := ev.Verify(env)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+			_, err = v.Sign([]byte(payload))
+			assert.Equal(t, ErrNoPrivateKey, err, "wrong error")
+
+			err = v.Verify([]byte("tampered"), []byte("not a signature"))
+			assert.Equal(t, ErrSignatureInvalid, err, "wrong error")
+		})
+	}
+
+	t.Run("Unsupported curve", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = NewECDSASignerVerifier("", key)
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+// Low-S and high-S forms of the signature in the DSSE protocol specification
+// example, see TestEcdsaSign.
+const (
+	ecdsaLowSVector  = "3044022003726ab101ad56c2763b6c6aac8e487275e2a79193a09dc5f859d9f8ef3c4a3b022051eab0c06a369b64a7222537d4887a893dc200ae21060074d57825235b624864"
+	ecdsaHighSVector = "3045022003726ab101ad56c2763b6c6aac8e487275e2a79193a09dc5f859d9f8ef3c4a3b022100ae154f3e95c9649c58dddac82b7785767f24f9ff86119e101e41a59fa100dced"
+)
+
+func TestECDSALowS(t *testing.T) {
+	pae := PAE("http://example.com/HelloWorld", []byte("hello world"))
+	lowS, err := hex.DecodeString(ecdsaLowSVector)
+	assert.Nil(t, err, "unexpected error")
+	highS, err := hex.DecodeString(ecdsaHighSVector)
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("Strict", func(t *testing.T) {
+		v, err := NewECDSAVerifier("", &newEcdsaKey().PublicKey)
+		assert.Nil(t, err, "unexpected error")
+
+		assert.Nil(t, v.Verify(pae, lowS), "unexpected error")
+		assert.Equal(t, ErrHighS, v.Verify(pae, highS), "wrong error")
+	})
+
+	t.Run("Relaxed", func(t *testing.T) {
+		v, err := NewECDSAVerifier("", &newEcdsaKey().PublicKey, WithAllowHighS())
+		assert.Nil(t, err, "unexpected error")
+
+		assert.Nil(t, v.Verify(pae, lowS), "unexpected error")
+		assert.Nil(t, v.Verify(pae, highS), "unexpected error")
+	})
+
+	t.Run("Sign produces low-S", func(t *testing.T) {
+		sv, err := NewECDSASignerVerifier("", newEcdsaKey())
+		assert.Nil(t, err, "unexpected error")
+
+		for i := 0; i < 32; i++ {
+			sig, err := sv.Sign(pae)
+			assert.Nil(t, err, "sign failed")
+
+			_, s, err := ecdsaParseDER(sig)
+			assert.Nil(t, err, "unexpected error")
+			assert.False(t, ecdsaIsHighS(elliptic.P256(), s), "high-S signature produced")
+			assert.Nil(t, sv.Verify(pae, sig), "unexpected error")
+		}
+	})
+}
+
+func TestECDSADeterministicSignatures(t *testing.T) {
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			assert.Nil(t, err, "unexpected error")
+			sv, err := NewECDSASignerVerifier("", key, WithDeterministicSignatures())
+			assert.Nil(t, err, "unexpected error")
+
+			first, err := sv.Sign([]byte("hello world"))
+			assert.Nil(t, err, "sign failed")
+			second, err := sv.Sign([]byte("hello world"))
+			assert.Nil(t, err, "sign failed")
+			assert.Equal(t, first, second, "signatures differ")
+			assert.Nil(t, sv.Verify([]byte("hello world"), first), "unexpected error")
+
+			other, err := sv.Sign([]byte("hello world!"))
+			assert.Nil(t, err, "sign failed")
+			assert.NotEqual(t, first, other, "signatures of different messages agree")
+
+			randomized, err := NewECDSASignerVerifier("", key)
+			assert.Nil(t, err, "unexpected error")
+			third, err := randomized.Sign([]byte("hello world"))
+			assert.Nil(t, err, "sign failed")
+			assert.NotEqual(t, first, third, "randomized signature is deterministic")
+		})
+	}
+
+	t.Run("Envelope", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+		sv, err := NewECDSASignerVerifier("", key, WithDeterministicSignatures())
+		assert.Nil(t, err, "unexpected error")
+		signer, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+
+		first, err := signer.SignPayload(PayloadTypeInToto, []byte("{}"))
+		assert.Nil(t, err, "sign failed")
+		second, err := signer.SignPayload(PayloadTypeInToto, []byte("{}"))
+		assert.Nil(t, err, "sign failed")
+		assert.Equal(t, first, second, "envelopes differ")
+	})
+
+	// RFC 6979, A.2.5: P-256 with SHA-256 over "sample". The specified S is
+	// high, so Sign returns n-S.
+	t.Run("Test vector", func(t *testing.T) {
+		hexInt := func(s string) *big.Int {
+			n, ok := new(big.Int).SetString(s, 16)
+			assert.True(t, ok, "invalid hex")
+			return n
+		}
+		curve := elliptic.P256()
+		key := &ecdsa.PrivateKey{D: hexInt("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")}
+		key.PublicKey.Curve = curve
+		key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(key.D.Bytes())
+
+		sv, err := NewECDSASignerVerifier("", key, WithDeterministicSignatures())
+		assert.Nil(t, err, "unexpected error")
+		sig, err := sv.Sign([]byte("sample"))
+		assert.Nil(t, err, "sign failed")
+		r, s, err := ecdsaParseDER(sig)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, hexInt("EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716"), r, "wrong r")
+		wantS := hexInt("F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8")
+		assert.Equal(t, new(big.Int).Sub(curve.Params().N, wantS), s, "wrong s")
+	})
+}
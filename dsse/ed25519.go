@@ -0,0 +1,168 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+)
+
+/*
+Ed25519SignerVerifier is a SignVerifier using Ed25519. A verifier-only
+instance, created with NewEd25519Verifier, returns ErrNoPrivateKey from Sign.
+
+By default the message is signed as is, which requires it to be held in
+memory. WithEd25519ph selects Ed25519ph from RFC 8032 instead, which signs the
+SHA-512 digest of the message, so that large payloads can be hashed
+incrementally, see VerifyStream. Ed25519 and Ed25519ph signatures are not
+interchangeable; signer and verifier must use the same variant. Ed25519ph
+needs Go 1.20 or later; with older versions WithEd25519ph makes the
+constructors fail.
+*/
+type Ed25519SignerVerifier struct {
+	keyID   string
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+	prehash bool
+}
+
+var errEd25519phUnsupported = errors.New("ed25519ph requires go 1.20 or later")
+
+// Ed25519Option configures an Ed25519SignerVerifier.
+type Ed25519Option func(*Ed25519SignerVerifier)
+
+// WithEd25519ph selects the pre-hashed variant Ed25519ph.
+func WithEd25519ph() Ed25519Option {
+	return func(sv *Ed25519SignerVerifier) {
+		sv.prehash = true
+	}
+}
+
+/*
+NewEd25519SignerVerifier creates an Ed25519SignerVerifier from a private key.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewEd25519SignerVerifier(keyID string, private ed25519.PrivateKey, opts ...Ed25519Option) (*Ed25519SignerVerifier, error) {
+	if len(private) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid ed25519 private key size")
+	}
+
+	sv, err := NewEd25519Verifier(keyID, private.Public().(ed25519.PublicKey), opts...)
+	if err != nil {
+		return nil, err
+	}
+	sv.private = private
+
+	return sv, nil
+}
+
+/*
+NewEd25519Verifier creates an Ed25519SignerVerifier that can only verify.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewEd25519Verifier(keyID string, public ed25519.PublicKey, opts ...Ed25519Option) (*Ed25519SignerVerifier, error) {
+	if len(public) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 public key size")
+	}
+
+	if keyID == "" {
+		var err error
+		keyID, err = SHA256KeyID(public)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sv := &Ed25519SignerVerifier{
+		keyID:  keyID,
+		public: public,
+	}
+	for _, opt := range opts {
+		opt(sv)
+	}
+	if sv.prehash && !ed25519phSupported {
+		return nil, errEd25519phUnsupported
+	}
+
+	return sv, nil
+}
+
+// Sign signs data with the private key.
+func (sv *Ed25519SignerVerifier) Sign(data []byte) ([]byte, error) {
+	if sv.prehash {
+		digest := sha512.Sum512(data)
+		return sv.SignDigest(digest[:], crypto.SHA512)
+	}
+	if sv.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	return ed25519.Sign(sv.private, data), nil
+}
+
+// Verify verifies sig over data with the public key.
+func (sv *Ed25519SignerVerifier) Verify(data, sig []byte) error {
+	if sv.prehash {
+		digest := sha512.Sum512(data)
+		return sv.VerifyDigest(digest[:], crypto.SHA512, sig)
+	}
+	if !ed25519.Verify(sv.public, data, sig) {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// HashFunc returns crypto.SHA512 for Ed25519ph, and 0 for Ed25519, which does
+// not hash the message before signing.
+func (sv *Ed25519SignerVerifier) HashFunc() crypto.Hash {
+	if sv.prehash {
+		return crypto.SHA512
+	}
+	return 0
+}
+
+// SignDigest signs a SHA-512 digest with Ed25519ph.
+func (sv *Ed25519SignerVerifier) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	if sv.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+	if hash != sv.HashFunc() {
+		return nil, fmt.Errorf("%w: digest uses %v, signer uses %v", ErrAlgorithmMismatch, hash, sv.HashFunc())
+	}
+
+	return ed25519phSign(sv.private, digest)
+}
+
+// VerifyDigest verifies sig over a SHA-512 digest with Ed25519ph.
+func (sv *Ed25519SignerVerifier) VerifyDigest(digest []byte, hash crypto.Hash, sig []byte) error {
+	if hash != sv.HashFunc() {
+		return fmt.Errorf("%w: digest uses %v, verifier uses %v", ErrAlgorithmMismatch, hash, sv.HashFunc())
+	}
+	if err := ed25519phVerify(sv.public, digest, sig); err != nil {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// KeyID returns the key ID of the key.
+func (sv *Ed25519SignerVerifier) KeyID() (string, error) {
+	return sv.keyID, nil
+}
+
+// Public returns the public key.
+func (sv *Ed25519SignerVerifier) Public() crypto.PublicKey {
+	return sv.public
+}
+
+// Algorithm returns the name of the algorithm, "ed25519" or "ed25519ph".
+func (sv *Ed25519SignerVerifier) Algorithm() string {
+	if sv.prehash {
+		return "ed25519ph"
+	}
+	return "ed25519"
+}
@@ -0,0 +1,111 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEd25519Key() ed25519.PrivateKey {
+	seed := make([]byte, ed25519.SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	return ed25519.NewKeyFromSeed(seed)
+}
+
+func TestEd25519SignerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	keyID, err := sv.KeyID()
+	assert.Nil(t, err, "unexpected error")
+	wantKeyID, err := SHA256KeyID(sv.Public())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, wantKeyID, keyID, "wrong keyid")
+
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	acceptedKeys, err := signer.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+	t.Run("Tampered", func(t *testing.T) {
+		err := sv.Verify([]byte("tampered"), []byte("not a signature"))
+		assert.Equal(t, ErrSignatureInvalid, err, "wrong error")
+	})
+
+	t.Run("Verifier only", func(t *testing.T) {
+		v, err := NewEd25519Verifier("custom", sv.Public().(ed25519.PublicKey))
+		assert.Nil(t, err, "unexpected error")
+
+		keyID, err := v.KeyID()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, "custom", keyID, "wrong keyid")
+
+		_, err = v.Sign([]byte(payload))
+		assert.Equal(t, ErrNoPrivateKey, err, "wrong error")
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		_, err := NewEd25519SignerVerifier("", ed25519.PrivateKey{1, 2, 3})
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+func TestEd25519ph(t *testing.T) {
+	// Test vector from RFC 8032, section 7.3.
+	key, _ := hex.DecodeString("833fe62409237b9d62ec77587520911e9a759cec1d19755b7da901b96dca3d42ec172b93ad5e563bf4932c70e1245034c35467ef2efd4d64ebf819683467e2bf")
+	message, _ := hex.DecodeString("616263")
+	want := "98a70222f0b8121aa9d30f813d683f809e462b469c7ff87639499bb94e6dae4131f85042463c2a355a2003d062adf5aaa10b8c61e636062aaad11c2a26083406"
+
+	sv, err := NewEd25519SignerVerifier("", ed25519.PrivateKey(key), WithEd25519ph())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ed25519ph", sv.Algorithm(), "wrong algorithm")
+	assert.Equal(t, crypto.SHA512, sv.HashFunc(), "wrong hash")
+
+	sig, err := sv.Sign(message)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, want, hex.EncodeToString(sig), "wrong signature")
+	assert.Nil(t, sv.Verify(message, sig), "unexpected error")
+
+	pure, err := NewEd25519Verifier("", sv.Public().(ed25519.PublicKey))
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, crypto.Hash(0), pure.HashFunc(), "wrong hash")
+	assert.Equal(t, ErrSignatureInvalid, pure.Verify(message, sig), "wrong error")
+
+	t.Run("Envelope", func(t *testing.T) {
+		var payloadType = "http://example.com/HelloWorld"
+		var payload = []byte("hello world")
+
+		signer, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		_, err = signer.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+
+		detached := *env
+		detached.Payload = ""
+		_, err = signer.VerifyStream(&detached, bytes.NewReader(payload))
+		assert.Nil(t, err, "unexpected error")
+
+		ev, err := NewEnvelopeVerifier(pure)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrAlgorithmMismatch), "wrong error")
+	})
+}
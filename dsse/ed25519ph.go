@@ -0,0 +1,20 @@
+//go:build go1.20
+
+package dsse
+
+import (
+	"crypto"
+	"crypto/ed25519"
+)
+
+// ed25519phSupported reports whether crypto/ed25519 implements Ed25519ph,
+// which it does since Go 1.20.
+const ed25519phSupported = true
+
+func ed25519phSign(private ed25519.PrivateKey, digest []byte) ([]byte, error) {
+	return private.Sign(nil, digest, &ed25519.Options{Hash: crypto.SHA512})
+}
+
+func ed25519phVerify(public ed25519.PublicKey, digest, sig []byte) error {
+	return ed25519.VerifyWithOptions(public, digest, sig, &ed25519.Options{Hash: crypto.SHA512})
+}
@@ -0,0 +1,17 @@
+//go:build !go1.20
+
+package dsse
+
+import "crypto/ed25519"
+
+// ed25519phSupported reports whether crypto/ed25519 implements Ed25519ph,
+// which it does since Go 1.20.
+const ed25519phSupported = false
+
+func ed25519phSign(ed25519.PrivateKey, []byte) ([]byte, error) {
+	return nil, errEd25519phUnsupported
+}
+
+func ed25519phVerify(ed25519.PublicKey, []byte, []byte) error {
+	return errEd25519phUnsupported
+}
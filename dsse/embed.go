@@ -0,0 +1,231 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+)
+
+// Signature extensions carrying the public key of the signer, see
+// WithEmbeddedPublicKeys.
+const (
+	// ExtensionPublicKey holds the PEM encoded PKIX public key as a string.
+	ExtensionPublicKey = "publicKey"
+	// ExtensionJWK holds the public key as a JSON Web Key object.
+	ExtensionJWK = "jwk"
+)
+
+// EmbeddedKeyFormat selects how WithEmbeddedPublicKeys embeds a public key.
+type EmbeddedKeyFormat int
+
+const (
+	// EmbeddedKeyNone embeds no key. This is the default.
+	EmbeddedKeyNone EmbeddedKeyFormat = iota
+	// EmbeddedKeyPEM embeds the key in the ExtensionPublicKey extension.
+	EmbeddedKeyPEM
+	// EmbeddedKeyJWK embeds the key in the ExtensionJWK extension.
+	EmbeddedKeyJWK
+)
+
+/*
+WithEmbeddedPublicKeys makes an EnvelopeSigner embed the public key of each
+signer in an extension of its signature, so that the envelope can be verified
+without obtaining the key out of band, see WithTrustEmbeddedKeys. Ed25519,
+ECDSA and RSA keys are supported; signing fails with an error wrapping
+ErrUnsupportedKey for other signers.
+*/
+func WithEmbeddedPublicKeys(format EmbeddedKeyFormat) Option {
+	return func(o *options) {
+		o.embedKeys = format
+	}
+}
+
+/*
+WithTrustEmbeddedKeys makes the verifier also verify signatures with the
+public keys embedded in them by WithEmbeddedPublicKeys. Anyone can embed a
+key, so keys accepted this way are returned in the AcceptedKeys with Embedded
+set, but never count toward the threshold, which only the configured
+verifiers can meet. To verify envelopes with embedded keys alone, for example
+for trust on first use, use NewEmbeddedKeyVerifier. The key IDs of such keys are
+chosen by the signer and prove nothing. An embedded key is ignored if its
+signature has the key ID of a configured verifier, so that it cannot stand in
+for a trusted key. Malformed embedded keys are ignored. The algorithm
+recorded in the signature selects the hash, and the default hash of the key
+is used otherwise.
+*/
+func WithTrustEmbeddedKeys() Option {
+	return func(o *options) {
+		o.trustEmbeddedKeys = true
+	}
+}
+
+/*
+NewEmbeddedKeyVerifier returns a verifier that verifies envelopes with the
+public keys embedded in their signatures by WithEmbeddedPublicKeys only,
+without any configured key. Verify succeeds if at least one signature
+verifies with its embedded key, and returns the keys of the valid signatures
+with Embedded set. Anyone can embed a key, so this proves only that the
+envelope was not modified since it was signed by the holder of the returned
+keys: the caller must check their Public field against its own trust policy,
+for example by pinning the key seen first. WithTrustEmbeddedKeys is implied.
+*/
+func NewEmbeddedKeyVerifier(opts ...Option) *envelopeVerifier {
+	return &envelopeVerifier{
+		index:         make(map[string][]int),
+		threshold:     1,
+		countEmbedded: true,
+		opts:          newOptions(append(opts, WithTrustEmbeddedKeys())...),
+	}
+}
+
+// signatureExtensions returns the extensions of a signature made by signer.
+func (o *options) signatureExtensions(signer SignVerifier) (map[string]json.RawMessage, error) {
+	extensions := algorithmExtensions(signer)
+	if o.embedKeys == EmbeddedKeyNone {
+		return extensions, nil
+	}
+
+	name, value, err := embedPublicKey(signer, o.embedKeys)
+	if err != nil {
+		return nil, err
+	}
+	if extensions == nil {
+		extensions = make(map[string]json.RawMessage)
+	}
+	extensions[name] = value
+
+	return extensions, nil
+}
+
+// embedPublicKey returns the extension embedding the public key of signer in
+// the given format.
+func embedPublicKey(signer SignVerifier, format EmbeddedKeyFormat) (string, json.RawMessage, error) {
+	pub := signer.Public()
+	switch pub.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+	default:
+		return "", nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+	}
+
+	switch format {
+	case EmbeddedKeyPEM:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := json.Marshal(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+		return ExtensionPublicKey, value, err
+	case EmbeddedKeyJWK:
+		var hash crypto.Hash
+		if ps, ok := prehashSigner(signer); ok {
+			hash = ps.HashFunc()
+		}
+		key, err := publicJWK(pub, hash)
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := json.Marshal(key)
+		return ExtensionJWK, value, err
+	}
+
+	return "", nil, fmt.Errorf("unknown embedded key format %d", format)
+}
+
+// embeddedPublicKey returns the public key embedded in s, if any.
+func embeddedPublicKey(s Signature) (crypto.PublicKey, bool, error) {
+	if raw, ok := s.Extensions[ExtensionJWK]; ok {
+		var key jwk
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, true, err
+		}
+		v, err := key.verifier()
+		if err != nil {
+			return nil, true, err
+		}
+		return v.Public(), true, nil
+	}
+
+	if raw, ok := s.Extensions[ExtensionPublicKey]; ok {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return nil, true, err
+		}
+		pub, err := ParsePublicKeyPEM([]byte(encoded))
+		return pub, true, err
+	}
+
+	return nil, false, nil
+}
+
+// embeddedVerifier returns a verifier for the public key embedded in s.
+func embeddedVerifier(s Signature) (Verifier, bool, error) {
+	pub, ok, err := embeddedPublicKey(s)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	alg, _, err := signatureAlgorithm(s)
+	if err != nil {
+		return nil, true, err
+	}
+
+	hash, hashed := algorithmHash(alg)
+	var v Verifier
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if alg == "ed25519ph" {
+			v, err = NewEd25519Verifier(s.KeyID, k, WithEd25519ph())
+		} else {
+			v, err = NewEd25519Verifier(s.KeyID, k)
+		}
+	case *ecdsa.PublicKey:
+		if hashed {
+			v, err = NewECDSAVerifier(s.KeyID, k, WithECDSAHash(hash))
+		} else {
+			v, err = NewECDSAVerifier(s.KeyID, k)
+		}
+	case *rsa.PublicKey:
+		if hashed {
+			v, err = NewRSAPSSVerifier(s.KeyID, k, WithRSAPSSHash(hash))
+		} else {
+			v, err = NewRSAPSSVerifier(s.KeyID, k)
+		}
+	default:
+		err = fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+	}
+
+	return v, true, err
+}
+
+/*
+addEmbeddedKeys adds verifiers for the keys embedded in the signatures to ev,
+skipping those whose key ID is already taken by a verifier, and marks them as
+embedded.
+*/
+func (ev *envelopeVerifier) addEmbeddedKeys(signatures []Signature) {
+	for _, s := range signatures {
+		v, ok, err := embeddedVerifier(s)
+		if !ok {
+			continue
+		}
+		if err != nil {
+			ev.opts.debug("ignoring embedded key", "signature_keyid", s.KeyID, "error", err)
+			continue
+		}
+
+		keyID := verifierKeyID(v)
+		if len(ev.index[ev.opts.keyID(keyID)]) > 0 {
+			ev.opts.debug("ignoring embedded key: key ID taken", "signature_keyid", s.KeyID)
+			continue
+		}
+		if ev.embedded == nil {
+			ev.embedded = make(map[int]bool)
+		}
+		ev.embedded[len(ev.providers)] = true
+		ev.addProvider(v, keyID)
+	}
+}
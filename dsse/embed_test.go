@@ -0,0 +1,209 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedPublicKeys(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	edSV, err := NewEd25519SignerVerifier("ed", edKey)
+	assert.Nil(t, err, "unexpected error")
+	edphSV, err := NewEd25519SignerVerifier("edph", edKey, WithEd25519ph())
+	assert.Nil(t, err, "unexpected error")
+	ecSV, err := NewECDSASignerVerifier("ec", ecKey, WithECDSAHash(crypto.SHA512))
+	assert.Nil(t, err, "unexpected error")
+	rsaSV, err := NewRSAPSSSignerVerifier("rsa", rsaKey, WithRSAPSSHash(crypto.SHA384))
+	assert.Nil(t, err, "unexpected error")
+
+	trusted, err := NewEd25519SignerVerifier("trusted", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signedByTrusted := func(t *testing.T) *Envelope {
+		signer, err := NewEnvelopeSigner(trusted)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte("hello world"))
+		assert.Nil(t, err, "sign failed")
+		return env
+	}
+
+	for _, format := range []EmbeddedKeyFormat{EmbeddedKeyPEM, EmbeddedKeyJWK} {
+		for _, sv := range []SignVerifier{edSV, edphSV, ecSV, rsaSV} {
+			keyID, _ := sv.KeyID()
+			t.Run(keyID, func(t *testing.T) {
+				signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{sv}, WithEmbeddedPublicKeys(format))
+				assert.Nil(t, err, "unexpected error")
+				env, err := signer.SignPayload(payloadType, []byte("hello world"))
+				assert.Nil(t, err, "sign failed")
+
+				extension := ExtensionPublicKey
+				if format == EmbeddedKeyJWK {
+					extension = ExtensionJWK
+				}
+				assert.Contains(t, env.Signatures[0].Extensions, extension, "key not embedded")
+
+				// Embedded keys are ignored by default.
+				ev, err := NewEnvelopeVerifier(trusted)
+				assert.Nil(t, err, "unexpected error")
+				_, err = ev.Verify(env)
+				assert.NotNil(t, err, "expected error")
+
+				// Embedded keys are reported, but only the
+				// configured key counts toward the threshold.
+				ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{trusted}, WithTrustEmbeddedKeys())
+				assert.Nil(t, err, "unexpected error")
+				_, err = ev.Verify(env)
+				var verr *VerificationError
+				assert.True(t, errors.As(err, &verr), "wrong error")
+				assert.Equal(t, 0, verr.Found, "embedded key counted")
+
+				cosigned, err := MergeEnvelopes(env, signedByTrusted(t))
+				assert.Nil(t, err, "unexpected error")
+				acceptedKeys, err := ev.Verify(cosigned)
+				assert.Nil(t, err, "unexpected error")
+				assert.Len(t, acceptedKeys, 2)
+				for _, k := range acceptedKeys {
+					assert.Equal(t, k.KeyID != "trusted", k.Embedded, "wrong embedded flag")
+					if k.Embedded {
+						assert.Equal(t, sv.Public(), k.Public, "wrong key")
+					}
+				}
+			})
+		}
+	}
+
+	t.Run("Only embedded keys", func(t *testing.T) {
+		_, err := NewEnvelopeVerifierWithOptions(1, nil, WithTrustEmbeddedKeys())
+		assert.NotNil(t, err, "expected error")
+
+		signer, err := NewEnvelopeSignerWithOptions(2, []SignVerifier{edSV, ecSV}, WithEmbeddedPublicKeys(EmbeddedKeyPEM))
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte("hello world"))
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{trusted}, WithTrustEmbeddedKeys())
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(env)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, 0, verr.Found, "embedded keys counted")
+		assert.Len(t, acceptedKeys, 2, "embedded keys not reported")
+
+		resolving, err := NewEnvelopeVerifierFuncWithOptions(2, func(string) (Verifier, error) {
+			return nil, nil
+		}, WithTrustEmbeddedKeys())
+		assert.Nil(t, err, "unexpected error")
+		_, err = resolving.Verify(env)
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, 0, verr.Found, "embedded keys counted")
+	})
+
+	t.Run("Embedded key verifier", func(t *testing.T) {
+		signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{edSV}, WithEmbeddedPublicKeys(EmbeddedKeyJWK))
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte("hello world"))
+		assert.Nil(t, err, "sign failed")
+
+		ev := NewEmbeddedKeyVerifier()
+		acceptedKeys, err := ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 1, "wrong number of keys")
+		assert.True(t, acceptedKeys[0].Embedded, "key not marked embedded")
+		assert.Equal(t, edSV.Public(), acceptedKeys[0].Public, "wrong key")
+
+		// Without an embedded key nothing can be verified.
+		_, err = ev.Verify(signedByTrusted(t))
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.ErrorIs(t, err, ErrNoMatchingKey, "wrong error")
+
+		tampered := env.Clone()
+		tampered.Payload = "Z29vZGJ5ZSB3b3JsZA=="
+		acceptedKeys, err = ev.Verify(tampered)
+		assert.ErrorIs(t, err, ErrSignatureInvalid, "wrong error")
+		assert.Empty(t, acceptedKeys, "tampered envelope accepted")
+	})
+
+	t.Run("Key ID of a configured key", func(t *testing.T) {
+		impostor, err := NewEd25519SignerVerifier("trusted", edKey)
+		assert.Nil(t, err, "unexpected error")
+
+		signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{impostor}, WithEmbeddedPublicKeys(EmbeddedKeyPEM))
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte("hello world"))
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{trusted}, WithTrustEmbeddedKeys())
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.ErrorIs(t, err, ErrSignatureInvalid, "wrong error")
+	})
+
+	t.Run("Tampered key", func(t *testing.T) {
+		signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{edSV}, WithEmbeddedPublicKeys(EmbeddedKeyPEM))
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte("hello world"))
+		assert.Nil(t, err, "sign failed")
+		other, err := NewEd25519SignerVerifier("", newEd25519Key())
+		assert.Nil(t, err, "unexpected error")
+		_, value, err := embedPublicKey(other, EmbeddedKeyPEM)
+		assert.Nil(t, err, "unexpected error")
+		env.Signatures[0].Extensions[ExtensionPublicKey] = value
+
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{trusted}, WithTrustEmbeddedKeys())
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+		assert.Empty(t, acceptedKeys, "tampered key accepted")
+
+		env.Signatures[0].Extensions[ExtensionPublicKey] = json.RawMessage(`"not pem"`)
+		acceptedKeys, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+		assert.Empty(t, acceptedKeys, "malformed key accepted")
+	})
+}
+
+func TestPublicJWK(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, pub := range []crypto.PublicKey{ecKey.Public(), rsaKey.Public(), newEd25519Key().Public()} {
+		key, err := publicJWK(pub, 0)
+		assert.Nil(t, err, "unexpected error")
+		v, err := key.verifier()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, pub, v.Public(), "wrong key")
+	}
+
+	key, err := publicJWK(ecKey.Public(), crypto.SHA256)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ES256", key.Alg)
+
+	// ES384 is only defined for P-384.
+	key, err = publicJWK(ecKey.Public(), crypto.SHA384)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "", key.Alg)
+	_, err = key.verifier()
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = publicJWK("not a key", 0)
+	assert.ErrorIs(t, err, ErrUnsupportedKey, "wrong error")
+}
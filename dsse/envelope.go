@@ -0,0 +1,166 @@
+package dsse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoEnvelopes indicates that no envelope was provided.
+var ErrNoEnvelopes = errors.New("no envelopes provided")
+
+// ErrPayloadMismatch indicates that envelopes do not carry the same payload.
+var ErrPayloadMismatch = errors.New("payload mismatch")
+
+/*
+DecodedPayload returns the payload of the envelope decoded from base64. The
+payload is decoded exactly as the verifier decodes it, so the result is the
+payload covered by the envelope's signatures. If PayloadEncoding is set, only
+that alphabet is accepted.
+*/
+func (e *Envelope) DecodedPayload() ([]byte, error) {
+	return decodePayload(e.Payload, e.PayloadEncoding)
+}
+
+// SignatureCount returns the number of signatures in the envelope.
+func (e *Envelope) SignatureCount() int {
+	return len(e.Signatures)
+}
+
+/*
+SignerKeyIDs returns the key IDs claimed by the signatures of the envelope, in
+order of appearance and without duplicates. Signatures without a key ID are
+skipped. The signatures are not verified, so the key IDs only tell which
+verifiers may be needed.
+*/
+func (e *Envelope) SignerKeyIDs() []string {
+	keyIDs := []string{}
+	seen := make(map[string]bool)
+	for _, s := range e.Signatures {
+		if s.KeyID == "" || seen[s.KeyID] {
+			continue
+		}
+		seen[s.KeyID] = true
+		keyIDs = append(keyIDs, s.KeyID)
+	}
+
+	return keyIDs
+}
+
+/*
+Clone returns a deep copy of the envelope. The signatures, including their
+extensions, are copied, so that the clone can be modified, for example by
+appending signatures, or handed to another goroutine without affecting e.
+A nil envelope yields nil.
+*/
+func (e *Envelope) Clone() *Envelope {
+	if e == nil {
+		return nil
+	}
+
+	clone := *e
+	if e.Signatures == nil {
+		return &clone
+	}
+
+	clone.Signatures = make([]Signature, len(e.Signatures))
+	for i, s := range e.Signatures {
+		if s.Extensions != nil {
+			extensions := make(map[string]json.RawMessage, len(s.Extensions))
+			for name, value := range s.Extensions {
+				extensions[name] = append(json.RawMessage(nil), value...)
+			}
+			s.Extensions = extensions
+		}
+		clone.Signatures[i] = s
+	}
+
+	return &clone
+}
+
+/*
+Unsigned returns a copy of the envelope without signatures, keeping the
+payload, its type and encoding and the additional authenticated data. The
+copy has an empty, non-nil Signatures slice that does not share memory with
+e, so it can be signed anew, for example with AppendSignature, without
+affecting e. A nil envelope yields nil.
+*/
+func (e *Envelope) Unsigned() *Envelope {
+	if e == nil {
+		return nil
+	}
+
+	unsigned := *e
+	unsigned.Signatures = []Signature{}
+
+	return &unsigned
+}
+
+/*
+MarshalJSONIndent is like json.MarshalIndent applied to the envelope. It is
+meant for envelopes written for humans; json.Marshal produces the compact
+form. Either way the fields are emitted in the order payloadType, payload,
+signatures. ErrNoEnvelopes is returned for a nil envelope.
+*/
+func (e *Envelope) MarshalJSONIndent(prefix, indent string) ([]byte, error) {
+	if e == nil {
+		return nil, ErrNoEnvelopes
+	}
+
+	return json.MarshalIndent(e, prefix, indent)
+}
+
+/*
+MergeEnvelopes combines the signatures of envelopes over the same payload into
+a single envelope. All envelopes must have the same payload type, additional
+authenticated data and decoded payload, otherwise ErrPayloadMismatch is
+returned. The payload is taken as encoded in the first envelope. Signatures
+with the same key ID and signature value are included once. The signatures
+are not verified.
+*/
+func MergeEnvelopes(envs ...*Envelope) (*Envelope, error) {
+	if len(envs) == 0 || envs[0] == nil {
+		return nil, ErrNoEnvelopes
+	}
+
+	body, err := envs[0].DecodedPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Envelope{
+		PayloadType:     envs[0].PayloadType,
+		Payload:         envs[0].Payload,
+		PayloadEncoding: envs[0].PayloadEncoding,
+		AAD:             envs[0].AAD,
+	}
+
+	seen := make(map[[2]string]bool)
+	for _, e := range envs {
+		if e == nil {
+			return nil, ErrNoEnvelopes
+		}
+		if e.PayloadType != merged.PayloadType || e.AAD != merged.AAD {
+			return nil, ErrPayloadMismatch
+		}
+
+		other, err := e.DecodedPayload()
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(body, other) {
+			return nil, ErrPayloadMismatch
+		}
+
+		for _, s := range e.Signatures {
+			key := [2]string{s.KeyID, s.Sig}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Signatures = append(merged.Signatures, s)
+		}
+	}
+
+	return merged, nil
+}
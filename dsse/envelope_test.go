@@ -0,0 +1,265 @@
+package dsse
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodedPayload(t *testing.T) {
+	var payload = []byte{0xfb, 0xff, 0xfe, 'h', 'i'}
+
+	t.Run("Standard encoding", func(t *testing.T) {
+		e := Envelope{Payload: base64.StdEncoding.EncodeToString(payload)}
+		got, err := e.DecodedPayload()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, payload, got, "wrong payload")
+	})
+
+	t.Run("URL encoding", func(t *testing.T) {
+		e := Envelope{Payload: base64.URLEncoding.EncodeToString(payload)}
+		got, err := e.DecodedPayload()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, payload, got, "wrong payload")
+	})
+
+	t.Run("Encoding marker", func(t *testing.T) {
+		std := base64.StdEncoding.EncodeToString(payload)
+		url := base64.URLEncoding.EncodeToString(payload)
+
+		e := Envelope{Payload: std, PayloadEncoding: PayloadEncodingBase64}
+		got, err := e.DecodedPayload()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, payload, got, "wrong payload")
+
+		e = Envelope{Payload: url, PayloadEncoding: PayloadEncodingBase64URL}
+		got, err = e.DecodedPayload()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, payload, got, "wrong payload")
+
+		// The marker disables the fallback to the other alphabet.
+		e = Envelope{Payload: url, PayloadEncoding: PayloadEncodingBase64}
+		_, err = e.DecodedPayload()
+		assert.IsType(t, base64.CorruptInputError(0), err, "wrong error")
+
+		e = Envelope{Payload: std, PayloadEncoding: PayloadEncodingBase64URL}
+		_, err = e.DecodedPayload()
+		assert.IsType(t, base64.CorruptInputError(0), err, "wrong error")
+
+		e = Envelope{Payload: std, PayloadEncoding: "hex"}
+		_, err = e.DecodedPayload()
+		assert.Equal(t, ErrUnknownPayloadEncoding, err, "wrong error")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		e := Envelope{Payload: "Not base 64"}
+		got, err := e.DecodedPayload()
+		assert.IsType(t, base64.CorruptInputError(0), err, "wrong error")
+		assert.Nil(t, got, "wrong payload")
+	})
+}
+
+func TestSignatureExtensions(t *testing.T) {
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload("http://example.com/HelloWorld", []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	data, err := json.Marshal(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.NotContains(t, string(data), "extensions", "empty extensions serialized")
+
+	env.Signatures[0].Extensions = map[string]json.RawMessage{
+		"cert":       json.RawMessage(`"-----BEGIN CERTIFICATE-----"`),
+		"x-unknown":  json.RawMessage(`{"nested":[1,2,3]}`),
+		"signedTime": json.RawMessage(`"2021-01-01T00:00:00Z"`),
+	}
+
+	data, err = json.Marshal(env)
+	assert.Nil(t, err, "unexpected error")
+
+	var got Envelope
+	err = json.Unmarshal(data, &got)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, env, &got, "extensions did not round-trip")
+
+	acceptedKeys, err := signer.Verify(&got)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+}
+
+func TestMergeEnvelopes(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	var ns nilsigner
+	var null nullsigner
+	nilSigner, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	nullSigner, err := NewEnvelopeSigner(null)
+	assert.Nil(t, err, "unexpected error")
+
+	env1, err := nilSigner.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	env2, err := nullSigner.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	env2.Payload = base64.URLEncoding.EncodeToString(payload)
+
+	merged, err := MergeEnvelopes(env1, env2, env1)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, env1.Payload, merged.Payload, "wrong payload")
+	assert.Equal(t, []Signature{env1.Signatures[0], env2.Signatures[0]}, merged.Signatures, "wrong signatures")
+	assert.Len(t, env1.Signatures, 1, "input modified")
+
+	ev, err := NewMultiEnvelopeVerifier(2, ns, null)
+	assert.Nil(t, err, "unexpected error")
+	acceptedKeys, err := ev.Verify(merged)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 2, "unexpected keys")
+
+	t.Run("Payload encoding", func(t *testing.T) {
+		encoded := *env2
+		encoded.PayloadEncoding = PayloadEncodingBase64URL
+		merged, err := MergeEnvelopes(&encoded, env1)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, PayloadEncodingBase64URL, merged.PayloadEncoding, "wrong payload encoding")
+		body, err := merged.DecodedPayload()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, payload, body, "wrong payload")
+	})
+
+	t.Run("Different payload", func(t *testing.T) {
+		other, err := nullSigner.SignPayload(payloadType, []byte("goodbye"))
+		assert.Nil(t, err, "sign failed")
+		_, err = MergeEnvelopes(env1, other)
+		assert.Equal(t, ErrPayloadMismatch, err, "wrong error")
+	})
+
+	t.Run("Different payload type", func(t *testing.T) {
+		other, err := nullSigner.SignPayload("other", payload)
+		assert.Nil(t, err, "sign failed")
+		_, err = MergeEnvelopes(env1, other)
+		assert.Equal(t, ErrPayloadMismatch, err, "wrong error")
+	})
+
+	t.Run("No envelopes", func(t *testing.T) {
+		_, err := MergeEnvelopes()
+		assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+		_, err = MergeEnvelopes(env1, nil)
+		assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	})
+}
+
+func TestMarshalJSONIndent(t *testing.T) {
+	e := &Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ=",
+		Signatures: []Signature{
+			{KeyID: "nil", Sig: "c2ln"},
+		},
+	}
+
+	compact, err := json.Marshal(e)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, `{"payloadType":"http://example.com/HelloWorld","payload":"aGVsbG8gd29ybGQ=","signatures":[{"keyid":"nil","sig":"c2ln"}]}`, string(compact), "wrong compact encoding")
+
+	indented, err := e.MarshalJSONIndent("", "  ")
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, `{
+  "payloadType": "http://example.com/HelloWorld",
+  "payload": "aGVsbG8gd29ybGQ=",
+  "signatures": [
+    {
+      "keyid": "nil",
+      "sig": "c2ln"
+    }
+  ]
+}`, string(indented), "wrong indented encoding")
+
+	var got Envelope
+	assert.Nil(t, json.Unmarshal(indented, &got), "unexpected error")
+	assert.Equal(t, e, &got, "indented envelope did not round-trip")
+
+	_, err = (*Envelope)(nil).MarshalJSONIndent("", "  ")
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+}
+
+func TestSignerKeyIDs(t *testing.T) {
+	e := &Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ=",
+		Signatures: []Signature{
+			{KeyID: "b", Sig: "c2ln"},
+			{KeyID: "", Sig: "c2ln"},
+			{KeyID: "a", Sig: "c2ln"},
+			{KeyID: "b", Sig: "b3RoZXI="},
+		},
+	}
+
+	assert.Equal(t, 4, e.SignatureCount(), "wrong signature count")
+	assert.Equal(t, []string{"b", "a"}, e.SignerKeyIDs(), "wrong key IDs")
+
+	empty := &Envelope{}
+	assert.Equal(t, 0, empty.SignatureCount(), "wrong signature count")
+	assert.Equal(t, []string{}, empty.SignerKeyIDs(), "wrong key IDs")
+}
+
+func TestUnsigned(t *testing.T) {
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayloadWithAAD("http://example.com/HelloWorld", []byte("hello world"), []byte("context"))
+	assert.Nil(t, err, "sign failed")
+	signatures := append([]Signature(nil), env.Signatures...)
+
+	unsigned := env.Unsigned()
+	assert.Equal(t, env.PayloadType, unsigned.PayloadType, "wrong payload type")
+	assert.Equal(t, env.Payload, unsigned.Payload, "wrong payload")
+	assert.Equal(t, env.AAD, unsigned.AAD, "wrong aad")
+	assert.NotNil(t, unsigned.Signatures, "nil signatures")
+	assert.Empty(t, unsigned.Signatures, "unexpected signatures")
+
+	resigned, err := signer.AppendSignature(unsigned)
+	assert.Nil(t, err, "sign failed")
+	assert.Len(t, resigned.Signatures, 1, "wrong number of signatures")
+	_, err = signer.VerifyWithAAD(resigned, []byte("context"))
+	assert.Nil(t, err, "unexpected error")
+
+	unsigned.Signatures = append(unsigned.Signatures, Signature{KeyID: "other"})
+	assert.Equal(t, signatures, env.Signatures, "original modified")
+
+	assert.Nil(t, (*Envelope)(nil).Unsigned(), "unexpected envelope")
+}
+
+func TestClone(t *testing.T) {
+	env := &Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ=",
+		AAD:         "Y29udGV4dA==",
+		Signatures: []Signature{
+			{KeyID: "a", Sig: "c2ln", Extensions: map[string]json.RawMessage{"ext": json.RawMessage(`{"x":1}`)}},
+			{KeyID: "b", Sig: "c2ln"},
+		},
+	}
+	// Leave spare capacity, which an append to a shallow copy would use.
+	env.Signatures = append(env.Signatures[:2:2], Signature{})[:2]
+
+	clone := env.Clone()
+	assert.Equal(t, env, clone, "clone differs")
+
+	clone.Signatures[0].KeyID = "changed"
+	clone.Signatures[0].Extensions["ext"][2] = 'y'
+	clone.Signatures[0].Extensions["other"] = json.RawMessage(`1`)
+	clone.Signatures = append(clone.Signatures, Signature{KeyID: "c"})
+	assert.Equal(t, "a", env.Signatures[0].KeyID, "original modified")
+	assert.Equal(t, json.RawMessage(`{"x":1}`), env.Signatures[0].Extensions["ext"], "original extension modified")
+	assert.Len(t, env.Signatures[0].Extensions, 1, "original extensions modified")
+	assert.Equal(t, Signature{}, env.Signatures[:3][2], "original backing array modified")
+
+	assert.Nil(t, (&Envelope{}).Clone().Signatures, "nil signatures not kept")
+	assert.Nil(t, (*Envelope)(nil).Clone(), "unexpected envelope")
+}
@@ -0,0 +1,238 @@
+/*
+Package fido2 implements a DSSE SignVerifier backed by a FIDO2 security key,
+using the WebAuthn assertion flow. Signing requires user presence on the
+authenticator, which makes it possible to demand a physical touch for every
+signed attestation.
+
+The package does not talk to authenticators itself. Callers provide an
+Authenticator that performs authenticatorGetAssertion over the platform
+transport (USB HID, NFC, BLE or an operating system API).
+*/
+package fido2
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+
+	// rpIdHash (32) + flags (1) + signCount (4)
+	minAuthDataLen = 37
+
+	clientDataTypeGet = "webauthn.get"
+)
+
+// ErrNoAuthenticator indicates that a verifier-only instance was asked to sign.
+var ErrNoAuthenticator = errors.New("no authenticator")
+
+// ErrInvalidAssertion indicates that an assertion failed verification.
+var ErrInvalidAssertion = errors.New("invalid webauthn assertion")
+
+/*
+Authenticator performs the authenticatorGetAssertion operation for the
+credential registered with the relying party rpID. It returns the
+authenticator data and the signature over authenticatorData ||
+clientDataHash.
+*/
+type Authenticator interface {
+	GetAssertion(rpID string, clientDataHash []byte) (authenticatorData, signature []byte, err error)
+}
+
+// Config describes a registered WebAuthn credential.
+type Config struct {
+	// RPID is the relying party ID the credential is scoped to.
+	RPID string
+	// Origin is recorded in the client data when signing and, if set,
+	// required when verifying.
+	Origin string
+	// PublicKey is the credential public key, either a P-256
+	// *ecdsa.PublicKey or an ed25519.PublicKey.
+	PublicKey crypto.PublicKey
+	// KeyID is the DSSE key ID. If empty it is derived with
+	// dsse.SHA256KeyID.
+	KeyID string
+	// RequireUserVerification additionally requires the UV flag, e.g. a
+	// PIN or biometric check, when verifying.
+	RequireUserVerification bool
+}
+
+/*
+Assertion is the DSSE signature produced by a SignerVerifier. It is stored
+JSON encoded in the sig field of the envelope.
+*/
+type Assertion struct {
+	AuthenticatorData []byte `json:"authenticatorData"`
+	ClientDataJSON    []byte `json:"clientDataJSON"`
+	Signature         []byte `json:"signature"`
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+/*
+SignerVerifier is a dsse.SignVerifier that signs with a FIDO2 authenticator.
+The WebAuthn challenge is the SHA-256 digest of the data to be signed, which
+binds the assertion to the DSSE pre-authentication encoding.
+*/
+type SignerVerifier struct {
+	auth Authenticator
+	cfg  Config
+}
+
+var _ dsse.SignVerifier = (*SignerVerifier)(nil)
+
+// NewSignerVerifier creates a SignerVerifier that signs with auth.
+func NewSignerVerifier(auth Authenticator, cfg Config) (*SignerVerifier, error) {
+	if auth == nil {
+		return nil, ErrNoAuthenticator
+	}
+
+	sv, err := NewVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sv.auth = auth
+
+	return sv, nil
+}
+
+// NewVerifier creates a SignerVerifier that can only verify.
+func NewVerifier(cfg Config) (*SignerVerifier, error) {
+	if cfg.RPID == "" {
+		return nil, errors.New("missing relying party ID")
+	}
+
+	switch k := cfg.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize != 256 {
+			return nil, errors.New("unsupported credential curve")
+		}
+	case ed25519.PublicKey:
+	default:
+		return nil, errors.New("unsupported credential public key")
+	}
+
+	if cfg.KeyID == "" {
+		keyID, err := dsse.SHA256KeyID(cfg.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		cfg.KeyID = keyID
+	}
+
+	return &SignerVerifier{cfg: cfg}, nil
+}
+
+// Sign requests an assertion over data from the authenticator.
+func (sv *SignerVerifier) Sign(data []byte) ([]byte, error) {
+	if sv.auth == nil {
+		return nil, ErrNoAuthenticator
+	}
+
+	cdj, err := json.Marshal(clientData{
+		Type:      clientDataTypeGet,
+		Challenge: challenge(data),
+		Origin:    sv.cfg.Origin,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cdh := sha256.Sum256(cdj)
+
+	authData, sig, err := sv.auth.GetAssertion(sv.cfg.RPID, cdh[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(Assertion{
+		AuthenticatorData: authData,
+		ClientDataJSON:    cdj,
+		Signature:         sig,
+	})
+}
+
+/*
+Verify checks that sig is an assertion over data: the client data must be a
+webauthn.get request whose challenge matches data, the authenticator data
+must be scoped to the relying party and have the user present flag set, and
+the signature must verify with the credential public key.
+*/
+func (sv *SignerVerifier) Verify(data, sig []byte) error {
+	var a Assertion
+	if err := json.Unmarshal(sig, &a); err != nil {
+		return ErrInvalidAssertion
+	}
+
+	var cd clientData
+	if err := json.Unmarshal(a.ClientDataJSON, &cd); err != nil {
+		return ErrInvalidAssertion
+	}
+	if cd.Type != clientDataTypeGet || cd.Challenge != challenge(data) {
+		return ErrInvalidAssertion
+	}
+	if sv.cfg.Origin != "" && cd.Origin != sv.cfg.Origin {
+		return ErrInvalidAssertion
+	}
+
+	if len(a.AuthenticatorData) < minAuthDataLen {
+		return ErrInvalidAssertion
+	}
+	rpIDHash := sha256.Sum256([]byte(sv.cfg.RPID))
+	if !bytes.Equal(a.AuthenticatorData[:32], rpIDHash[:]) {
+		return ErrInvalidAssertion
+	}
+	flags := a.AuthenticatorData[32]
+	if flags&flagUserPresent == 0 {
+		return ErrInvalidAssertion
+	}
+	if sv.cfg.RequireUserVerification && flags&flagUserVerified == 0 {
+		return ErrInvalidAssertion
+	}
+
+	cdh := sha256.Sum256(a.ClientDataJSON)
+	signed := append(append([]byte{}, a.AuthenticatorData...), cdh[:]...)
+
+	var ok bool
+	switch k := sv.cfg.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		ok = ecdsa.VerifyASN1(k, digest[:], a.Signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, signed, a.Signature)
+	}
+	if !ok {
+		return ErrInvalidAssertion
+	}
+
+	return nil
+}
+
+// KeyID returns the key ID of the credential.
+func (sv *SignerVerifier) KeyID() (string, error) {
+	return sv.cfg.KeyID, nil
+}
+
+// Public returns the credential public key.
+func (sv *SignerVerifier) Public() crypto.PublicKey {
+	return sv.cfg.PublicKey
+}
+
+// challenge returns the WebAuthn challenge for data.
+func challenge(data []byte) string {
+	digest := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
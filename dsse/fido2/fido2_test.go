@@ -0,0 +1,194 @@
+package fido2
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/stretchr/testify/assert"
+)
+
+// softAuthenticator emulates a security key holding a single credential.
+type softAuthenticator struct {
+	key   crypto.Signer
+	flags byte
+	rpID  string
+}
+
+func (a *softAuthenticator) GetAssertion(rpID string, clientDataHash []byte) ([]byte, []byte, error) {
+	if a.rpID != "" {
+		rpID = a.rpID
+	}
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], a.flags, 0, 0, 0, 1)
+	signed := append(append([]byte{}, authData...), clientDataHash...)
+
+	var sig []byte
+	var err error
+	switch k := a.key.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signed)
+		sig, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, signed)
+	}
+
+	return authData, sig, err
+}
+
+func TestSignerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+
+	for name, key := range map[string]crypto.Signer{"ECDSA": ecdsaKey, "Ed25519": ed25519Key} {
+		t.Run(name, func(t *testing.T) {
+			cfg := Config{
+				RPID:      "example.com",
+				Origin:    "https://example.com",
+				PublicKey: key.Public(),
+			}
+			auth := &softAuthenticator{key: key, flags: flagUserPresent}
+
+			sv, err := NewSignerVerifier(auth, cfg)
+			assert.Nil(t, err, "unexpected error")
+
+			signer, err := dsse.NewEnvelopeSigner(sv)
+			assert.Nil(t, err, "unexpected error")
+
+			env, err := signer.SignPayload(payloadType, []byte(payload))
+			assert.Nil(t, err, "sign failed")
+
+			v, err := NewVerifier(cfg)
+			assert.Nil(t, err, "unexpected error")
+			ev, err := dsse.NewEnvelopeVerifier(v)
+			assert.Nil(t, err, "unexpected error")
+
+			acceptedKeys, err := ev.Verify(env)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+			_, err = v.Sign([]byte(payload))
+			assert.Equal(t, ErrNoAuthenticator, err, "wrong error")
+		})
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	var data = []byte("pae")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	cfg := Config{
+		RPID:      "example.com",
+		Origin:    "https://example.com",
+		PublicKey: key.Public(),
+	}
+	v, err := NewVerifier(cfg)
+	assert.Nil(t, err, "unexpected error")
+
+	sign := func(auth *softAuthenticator, cfg Config) []byte {
+		sv, err := NewSignerVerifier(auth, cfg)
+		assert.Nil(t, err, "unexpected error")
+		sig, err := sv.Sign(data)
+		assert.Nil(t, err, "unexpected error")
+		return sig
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent}, cfg)
+		assert.Nil(t, v.Verify(data, sig), "unexpected error")
+	})
+
+	t.Run("Other data", func(t *testing.T) {
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent}, cfg)
+		assert.Equal(t, ErrInvalidAssertion, v.Verify([]byte("other"), sig), "wrong error")
+	})
+
+	t.Run("User not present", func(t *testing.T) {
+		sig := sign(&softAuthenticator{key: key}, cfg)
+		assert.Equal(t, ErrInvalidAssertion, v.Verify(data, sig), "wrong error")
+	})
+
+	t.Run("User verification required", func(t *testing.T) {
+		uvCfg := cfg
+		uvCfg.RequireUserVerification = true
+		uv, err := NewVerifier(uvCfg)
+		assert.Nil(t, err, "unexpected error")
+
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent}, cfg)
+		assert.Equal(t, ErrInvalidAssertion, uv.Verify(data, sig), "wrong error")
+
+		sig = sign(&softAuthenticator{key: key, flags: flagUserPresent | flagUserVerified}, cfg)
+		assert.Nil(t, uv.Verify(data, sig), "unexpected error")
+	})
+
+	t.Run("Other relying party", func(t *testing.T) {
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent, rpID: "evil.example"}, cfg)
+		assert.Equal(t, ErrInvalidAssertion, v.Verify(data, sig), "wrong error")
+	})
+
+	t.Run("Other origin", func(t *testing.T) {
+		otherCfg := cfg
+		otherCfg.Origin = "https://evil.example"
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent}, otherCfg)
+		assert.Equal(t, ErrInvalidAssertion, v.Verify(data, sig), "wrong error")
+	})
+
+	t.Run("Tampered signature", func(t *testing.T) {
+		sig := sign(&softAuthenticator{key: key, flags: flagUserPresent}, cfg)
+		var a Assertion
+		assert.Nil(t, json.Unmarshal(sig, &a), "unexpected error")
+		a.AuthenticatorData[36]++
+		sig, err := json.Marshal(a)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, ErrInvalidAssertion, v.Verify(data, sig), "wrong error")
+	})
+
+	t.Run("Not an assertion", func(t *testing.T) {
+		assert.Equal(t, ErrInvalidAssertion, v.Verify(data, []byte("sig")), "wrong error")
+	})
+
+	t.Run("Authenticator error", func(t *testing.T) {
+		sv, err := NewSignerVerifier(errAuthenticator{}, cfg)
+		assert.Nil(t, err, "unexpected error")
+		_, err = sv.Sign(data)
+		assert.Equal(t, errTouch, err, "wrong error")
+	})
+}
+
+var errTouch = errors.New("no touch")
+
+type errAuthenticator struct{}
+
+func (errAuthenticator) GetAssertion(string, []byte) ([]byte, []byte, error) {
+	return nil, nil, errTouch
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = NewVerifier(Config{PublicKey: key.Public()})
+	assert.NotNil(t, err, "expected error")
+
+	_, err = NewVerifier(Config{RPID: "example.com", PublicKey: key.Public()})
+	assert.NotNil(t, err, "expected error")
+
+	_, err = NewVerifier(Config{RPID: "example.com", PublicKey: "not a key"})
+	assert.NotNil(t, err, "expected error")
+
+	_, err = NewSignerVerifier(nil, Config{})
+	assert.Equal(t, ErrNoAuthenticator, err, "wrong error")
+}
@@ -0,0 +1,71 @@
+package dsse
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// verifyErrorMessages are the messages of FormatVerifyError for sentinel
+// errors, in the order they are checked.
+var verifyErrorMessages = []struct {
+	err error
+	msg string
+}{
+	{ErrNoEnvelopes, "no envelope was provided"},
+	{ErrNoSignature, "the envelope is not signed"},
+	{ErrInvalidEnvelope, "the envelope is malformed"},
+	{ErrUnknownPayloadEncoding, "the envelope uses an unknown payload encoding"},
+	{ErrAADMismatch, "the envelope is bound to a different context than expected"},
+	{ErrPayloadTypeNotAccepted, "the payload type is not accepted"},
+	{ErrPayloadTooLarge, "the payload exceeds the maximum allowed size"},
+	{ErrUnknownPayloadSize, "the size of the payload cannot be determined"},
+	{ErrDisallowedAlgorithm, "a signature uses an algorithm that is not allowed"},
+	{ErrMissingSignature, "a required key did not sign the envelope"},
+	{ErrAlgorithmMismatch, "a signature was made with a different algorithm than the trusted key uses"},
+	{ErrKeyExpired, "the signing key has expired"},
+	{ErrKeyNotYetValid, "the signing key is not yet valid"},
+	{ErrEmptySignature, "a signature is empty"},
+	{ErrHighS, "a signature is not in canonical form"},
+	{ErrVerifierUnavailable, "a verifier could not be reached; try again later"},
+	{ErrNoMatchingKey, "none of the trusted keys signed the envelope"},
+	{ErrSignatureInvalid, "a signature from a trusted key is invalid; the envelope may have been tampered with"},
+	{ErrUnknownKey, "the envelope was signed with an unknown key"},
+}
+
+/*
+FormatVerifyError returns a message describing a verification error for end
+users, such as the users of a command line tool. Errors not known to this
+package are described by their own message. FormatVerifyError returns the
+empty string for a nil error.
+*/
+func FormatVerifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var verr *VerificationError
+	if errors.As(err, &verr) {
+		msg := fmt.Sprintf("verification failed: %d of %d required signatures are valid", verr.Found, verr.Expected)
+		if verr.Err != nil {
+			msg += ": " + FormatVerifyError(verr.Err)
+		}
+		return msg
+	}
+
+	var b64err base64.CorruptInputError
+	if errors.As(err, &b64err) {
+		return fmt.Sprintf("malformed base64 data at byte %d", int64(b64err))
+	}
+
+	for _, m := range verifyErrorMessages {
+		if errors.Is(err, m.err) {
+			if err != m.err {
+				return fmt.Sprintf("%s (%v)", m.msg, err)
+			}
+			return m.msg
+		}
+	}
+
+	return err.Error()
+}
@@ -0,0 +1,54 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatVerifyError(t *testing.T) {
+	ev, err := NewEnvelopeVerifier(&mockVerifier{})
+	assert.Nil(t, err, "unexpected error")
+
+	_, b64err := ev.Verify(&Envelope{
+		PayloadType: "http://example.com/HelloWorld",
+		Payload:     "aGVsbG8gd29ybGQ=",
+		Signatures:  []Signature{{KeyID: "mock", Sig: "!!!!"}},
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"no signature", ErrNoSignature, "the envelope is not signed"},
+		{"unknown key", ErrUnknownKey, "the envelope was signed with an unknown key"},
+		{"invalid signature", ErrSignatureInvalid, "a signature from a trusted key is invalid; the envelope may have been tampered with"},
+		{"malformed base64", b64err, "malformed base64 data at byte 0"},
+		{
+			"threshold",
+			&VerificationError{Found: 1, Expected: 2, Err: ErrNoMatchingKey},
+			"verification failed: 1 of 2 required signatures are valid: none of the trusted keys signed the envelope",
+		},
+		{
+			"wrapped",
+			fmt.Errorf("%w: KeyID=k", ErrMissingSignature),
+			"a required key did not sign the envelope (missing required signature: KeyID=k)",
+		},
+		{
+			"validation",
+			&ValidationError{Field: "payload", Err: errMissing},
+			"the envelope is malformed (invalid envelope: payload: missing)",
+		},
+		{"unknown", errors.New("test err verify"), "test err verify"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, FormatVerifyError(test.err), "wrong message")
+		})
+	}
+}
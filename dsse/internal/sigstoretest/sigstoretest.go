@@ -0,0 +1,203 @@
+/*
+Package sigstoretest provides a Fulcio-like certificate authority, a
+short-lived signing certificate and a DSSE envelope signed with it, for tests
+of the packages that verify Sigstore attestations.
+
+The envelope is signed the way cosign does: the SHA-256 digest of the
+pre-authentication encoding is signed with ecdsa.SignASN1 and the signature
+is used as is, so about half of the signatures are in high-S form.
+*/
+package sigstoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+)
+
+// The identity the signing certificate is issued to.
+const (
+	Subject = "signer@example.com"
+	Issuer  = "https://accounts.example.com"
+)
+
+// oidIssuerV2 is the Fulcio extension holding the OIDC issuer.
+var oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+
+// Options configures New.
+type Options struct {
+	// Intermediate makes the root issue the leaf through an intermediate.
+	Intermediate bool
+	// Payload is the in-toto statement to sign.
+	Payload []byte
+	// LeafOptions may modify the template of the leaf certificate.
+	LeafOptions []func(*x509.Certificate)
+}
+
+// Fixture is a certificate authority and an envelope signed by a leaf.
+type Fixture struct {
+	Roots   *x509.CertPool
+	Root    *x509.Certificate
+	RootKey *ecdsa.PrivateKey
+	// Issuer issues the leaf certificates. It is Root unless
+	// Options.Intermediate is set.
+	Issuer    *x509.Certificate
+	IssuerKey *ecdsa.PrivateKey
+	Leaf      *x509.Certificate
+	LeafKey   *ecdsa.PrivateKey
+	// LeafPEM is the PEM encoding of Leaf, and Chain the DER encoding of
+	// Leaf followed by the intermediate, if any.
+	LeafPEM  []byte
+	Chain    [][]byte
+	RekorKey *ecdsa.PrivateKey
+	Payload  []byte
+	Envelope *dsse.Envelope
+	// SignedAt is an hour ago; the leaf is only valid around that time.
+	SignedAt time.Time
+}
+
+/*
+New creates a root, an intermediate if requested, and a leaf certificate for
+Subject and Issuer that was valid an hour ago, and signs an envelope of
+opts.Payload with the leaf key.
+*/
+func New(t testing.TB, opts Options) *Fixture {
+	t.Helper()
+	f := &Fixture{
+		SignedAt: time.Now().Add(-time.Hour).Truncate(time.Second),
+		Payload:  opts.Payload,
+	}
+
+	f.RootKey = newKey(t)
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test fulcio root"},
+		NotBefore:             f.SignedAt.Add(-24 * time.Hour),
+		NotAfter:              f.SignedAt.Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	f.Root = newCert(t, rootTmpl, rootTmpl, &f.RootKey.PublicKey, f.RootKey)
+	f.Roots = x509.NewCertPool()
+	f.Roots.AddCert(f.Root)
+	f.Issuer, f.IssuerKey = f.Root, f.RootKey
+
+	if opts.Intermediate {
+		f.IssuerKey = newKey(t)
+		f.Issuer = newCert(t, &x509.Certificate{
+			SerialNumber:          big.NewInt(2),
+			Subject:               pkix.Name{CommonName: "test fulcio intermediate"},
+			NotBefore:             f.SignedAt.Add(-24 * time.Hour),
+			NotAfter:              f.SignedAt.Add(24 * time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		}, f.Root, &f.IssuerKey.PublicKey, f.RootKey)
+	}
+
+	f.LeafKey = newKey(t)
+	f.Leaf, f.LeafPEM = f.Issue(t, &f.LeafKey.PublicKey, opts.LeafOptions...)
+	f.Chain = [][]byte{f.Leaf.Raw}
+	if opts.Intermediate {
+		f.Chain = append(f.Chain, f.Issuer.Raw)
+	}
+
+	f.Envelope = f.Sign(t, func(digest []byte) ([]byte, error) {
+		return ecdsa.SignASN1(rand.Reader, f.LeafKey, digest)
+	})
+	f.RekorKey = newKey(t)
+
+	return f
+}
+
+// Issue returns a leaf certificate for pub issued by f.Issuer, and its PEM
+// encoding. The functions in leafOpts may modify the template.
+func (f *Fixture) Issue(t testing.TB, pub interface{}, leafOpts ...func(*x509.Certificate)) (*x509.Certificate, []byte) {
+	t.Helper()
+	issuer, err := asn1.MarshalWithParams(Issuer, "utf8")
+	check(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(3),
+		NotBefore:       f.SignedAt.Add(-5 * time.Minute),
+		NotAfter:        f.SignedAt.Add(5 * time.Minute),
+		EmailAddresses:  []string{Subject},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	for _, opt := range leafOpts {
+		opt(tmpl)
+	}
+
+	leaf := newCert(t, tmpl, f.Issuer, pub, f.IssuerKey)
+	return leaf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})
+}
+
+// Sign returns an envelope of f.Payload without key ID, whose signature is
+// the result of sign for the SHA-256 digest of the pre-authentication
+// encoding.
+func (f *Fixture) Sign(t testing.TB, sign func(digest []byte) ([]byte, error)) *dsse.Envelope {
+	t.Helper()
+	digest := sha256.Sum256(dsse.PAE(dsse.PayloadTypeInToto, f.Payload))
+	sig, err := sign(digest[:])
+	check(t, err)
+
+	return &dsse.Envelope{
+		PayloadType: dsse.PayloadTypeInToto,
+		Payload:     base64.StdEncoding.EncodeToString(f.Payload),
+		Signatures:  []dsse.Signature{{Sig: base64.StdEncoding.EncodeToString(sig)}},
+	}
+}
+
+// HighS returns the ASN.1 ECDSA signature sig for a key on curve with S
+// replaced by N-S if S is not already in the upper half of the curve order.
+func HighS(t testing.TB, curve elliptic.Curve, sig []byte) []byte {
+	t.Helper()
+	var rs struct{ R, S *big.Int }
+	_, err := asn1.Unmarshal(sig, &rs)
+	check(t, err)
+	n := curve.Params().N
+	if rs.S.Cmp(new(big.Int).Rsh(n, 1)) <= 0 {
+		rs.S.Sub(n, rs.S)
+	}
+
+	sig, err = asn1.Marshal(rs)
+	check(t, err)
+	return sig
+}
+
+func newKey(t testing.TB) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	check(t, err)
+	return key
+}
+
+func newCert(t testing.TB, tmpl, parent *x509.Certificate, pub, priv interface{}) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, pub, priv)
+	check(t, err)
+	cert, err := x509.ParseCertificate(der)
+	check(t, err)
+	return cert
+}
+
+func check(t testing.TB, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package sigstoretest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	for _, intermediate := range []bool{false, true} {
+		f := New(t, Options{Intermediate: intermediate, Payload: []byte("{}")})
+
+		inters := x509.NewCertPool()
+		for _, der := range f.Chain[1:] {
+			cert, err := x509.ParseCertificate(der)
+			assert.Nil(t, err, "unexpected error")
+			inters.AddCert(cert)
+		}
+		_, err := f.Leaf.Verify(x509.VerifyOptions{
+			Roots:         f.Roots,
+			Intermediates: inters,
+			CurrentTime:   f.SignedAt,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, []string{Subject}, f.Leaf.EmailAddresses, "wrong subject")
+
+		sig, err := base64.StdEncoding.DecodeString(f.Envelope.Signatures[0].Sig)
+		assert.Nil(t, err, "unexpected error")
+		digest := sha256.Sum256(dsse.PAE(f.Envelope.PayloadType, f.Payload))
+		assert.True(t, ecdsa.VerifyASN1(&f.LeafKey.PublicKey, digest[:], sig), "wrong signature")
+	}
+}
+
+func TestHighS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	digest := sha256.Sum256([]byte("data"))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.Nil(t, err, "sign failed")
+
+	sig = HighS(t, elliptic.P256(), sig)
+	assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig), "wrong signature")
+	var rs struct{ R, S *big.Int }
+	_, err = asn1.Unmarshal(sig, &rs)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, rs.S.Cmp(new(big.Int).Rsh(elliptic.P256().Params().N, 1)), "low S")
+	assert.Equal(t, sig, HighS(t, elliptic.P256(), sig), "not idempotent")
+}
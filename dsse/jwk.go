@@ -0,0 +1,281 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// jwk is a JSON Web Key of RFC 7517, with the parameters of the supported key
+// types.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+/*
+SkippedKeysError is returned by VerifiersFromJWKS together with the verifiers
+for the other keys if some keys of the set are not supported. Each warning
+names a skipped key and why it was skipped. It wraps ErrUnsupportedKey.
+*/
+type SkippedKeysError struct {
+	Warnings []string
+}
+
+func (e *SkippedKeysError) Error() string {
+	return fmt.Sprintf("skipped %d keys: %s", len(e.Warnings), strings.Join(e.Warnings, "; "))
+}
+
+func (e *SkippedKeysError) Unwrap() error {
+	return ErrUnsupportedKey
+}
+
+/*
+VerifiersFromJWKS creates verifiers for the keys of a JSON Web Key Set, such
+as the one an OIDC provider publishes at its jwks_uri. The key ID of each
+verifier is the kid of its key. EC keys on the curves supported by
+NewECDSAVerifier, RSA keys and Ed25519 OKP keys are supported; the alg of an
+RSA key, if present, selects the hash, for example "PS512", and the alg of an
+EC key must be the one defined for its curve, for example "ES384" for P-384.
+EC keys accept high-S signatures, which JOSE libraries produce. RSA keys are
+used for RSASSA-PSS, as "RS" algorithms are not supported.
+
+Keys of an unsupported type, curve or algorithm, and keys meant for
+encryption, do not abort the set: they are skipped, and the verifiers for the
+other keys are returned with a *SkippedKeysError listing them. Callers that
+accept a partial set can check for it with errors.As. A supported key with
+invalid parameters is an error.
+*/
+func VerifiersFromJWKS(jwks []byte) ([]Verifier, error) {
+	var set struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(jwks, &set); err != nil {
+		return nil, err
+	}
+
+	var verifiers []Verifier
+	var warnings []string
+	for i, raw := range set.Keys {
+		var key jwk
+		if err := json.Unmarshal(raw, &key); err != nil {
+			return nil, fmt.Errorf("jwk %d: %w", i, err)
+		}
+
+		v, err := key.verifier()
+		if errors.Is(err, ErrUnsupportedKey) {
+			warnings = append(warnings, fmt.Sprintf("jwk %d (%q): %v", i, key.Kid, err))
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwk %d (%q): %w", i, key.Kid, err)
+		}
+		verifiers = append(verifiers, v)
+	}
+
+	if len(warnings) > 0 {
+		return verifiers, &SkippedKeysError{Warnings: warnings}
+	}
+	return verifiers, nil
+}
+
+func (k *jwk) verifier() (Verifier, error) {
+	if k.Use != "" && k.Use != "sig" {
+		return nil, fmt.Errorf("%w: use %q", ErrUnsupportedKey, k.Use)
+	}
+
+	switch k.Kty {
+	case "EC":
+		return k.ecdsaVerifier()
+	case "RSA":
+		return k.rsaVerifier()
+	case "OKP":
+		return k.ed25519Verifier()
+	}
+
+	return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, k.Kty)
+}
+
+// jwkECDSACurves maps the JOSE ECDSA algorithms to the curve they use.
+var jwkECDSACurves = map[string]string{
+	"ES256":  "P-256",
+	"ES384":  "P-384",
+	"ES512":  "P-521",
+	"ES256K": "secp256k1",
+}
+
+func (k *jwk) ecdsaVerifier() (Verifier, error) {
+	var public *ecdsa.PublicKey
+	for curve := range ecdsaCurves {
+		if curve.Params().Name == k.Crv {
+			public = &ecdsa.PublicKey{Curve: curve}
+			break
+		}
+	}
+	if public == nil {
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, k.Crv)
+	}
+
+	// Each ES algorithm is defined for one curve only, RFC 7518 section 3.4
+	// and RFC 8812 section 3.2.
+	hash := ecdsaCurves[public.Curve]
+	if k.Alg != "" {
+		crv, ok := jwkECDSACurves[k.Alg]
+		if !ok {
+			return nil, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, k.Alg)
+		}
+		if crv != k.Crv {
+			return nil, fmt.Errorf("%w: alg %q on crv %q", ErrUnsupportedKey, k.Alg, k.Crv)
+		}
+	}
+
+	size := (public.Curve.Params().BitSize + 7) / 8
+	x, err := jwkCoordinate(k.X, size)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := jwkCoordinate(k.Y, size)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	if !public.Curve.IsOnCurve(x, y) {
+		return nil, errors.New("point not on curve")
+	}
+	public.X, public.Y = x, y
+
+	// JOSE libraries do not normalize S.
+	return NewECDSAVerifier(k.Kid, public, WithECDSAHash(hash), WithAllowHighS())
+}
+
+func (k *jwk) rsaVerifier() (Verifier, error) {
+	var hash crypto.Hash
+	switch k.Alg {
+	case "", "PS256":
+		hash = crypto.SHA256
+	case "PS384":
+		hash = crypto.SHA384
+	case "PS512":
+		hash = crypto.SHA512
+	default:
+		return nil, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, k.Alg)
+	}
+
+	n, err := jwkDecode(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("n: %w", err)
+	}
+	e, err := jwkDecode(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("e: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa key")
+	}
+
+	public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	return NewRSAPSSVerifier(k.Kid, public, WithRSAPSSHash(hash))
+}
+
+func (k *jwk) ed25519Verifier() (Verifier, error) {
+	if k.Crv != "Ed25519" {
+		return nil, fmt.Errorf("%w: crv %q", ErrUnsupportedKey, k.Crv)
+	}
+	if k.Alg != "" && k.Alg != "EdDSA" {
+		return nil, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, k.Alg)
+	}
+
+	x, err := jwkDecode(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	if len(x) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid ed25519 key size")
+	}
+
+	return NewEd25519Verifier(k.Kid, ed25519.PublicKey(x))
+}
+
+/*
+publicJWK returns the JWK of pub, an ed25519.PublicKey, *ecdsa.PublicKey or
+*rsa.PublicKey. hash is the hash the key signs with, or zero if it signs the
+message itself, and selects the alg of the key.
+*/
+func publicJWK(pub crypto.PublicKey, hash crypto.Hash) (*jwk, error) {
+	var bits string
+	if hash != 0 {
+		bits = strconv.Itoa(8 * hash.Size())
+	}
+
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		key := &jwk{Kty: "OKP", Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(k)}
+		if hash == 0 {
+			key.Alg = "EdDSA"
+		}
+		return key, nil
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		key := &jwk{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(k.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(k.Y.FillBytes(make([]byte, size))),
+		}
+		alg := "ES" + bits
+		if key.Crv == "secp256k1" {
+			alg = "ES256K"
+		}
+		// JOSE has no name for other hashes on a curve; the algorithm
+		// extension of the signature records them instead.
+		if hash != 0 && hash == ecdsaCurves[k.Curve] {
+			key.Alg = alg
+		}
+		return key, nil
+	case *rsa.PublicKey:
+		key := &jwk{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+		if hash != 0 {
+			key.Alg = "PS" + bits
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+// jwkDecode decodes a base64url parameter, tolerating padding.
+func jwkDecode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// jwkCoordinate decodes an EC coordinate, which must be size bytes long.
+func jwkCoordinate(s string, size int) (*big.Int, error) {
+	b, err := jwkDecode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", size, len(b))
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,115 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	assert.Nil(t, err, "unexpected error")
+	return data
+}
+
+func TestVerifiersFromJWKS(t *testing.T) {
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+
+	ecJWK := map[string]string{
+		"kty": "EC", "kid": "ec", "crv": "P-384", "alg": "ES384",
+		"x": b64(ecKey.X.FillBytes(make([]byte, 48))),
+		"y": b64(ecKey.Y.FillBytes(make([]byte, 48))),
+	}
+	rsaJWK := map[string]string{
+		"kty": "RSA", "kid": "rsa", "use": "sig", "alg": "PS512",
+		"n": b64(rsaKey.N.Bytes()),
+		"e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+	}
+	edJWK := map[string]string{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(edPublic)}
+
+	ecSigner, err := NewECDSASignerVerifier("ec", ecKey, WithECDSAHash(crypto.SHA384))
+	assert.Nil(t, err, "unexpected error")
+	rsaSigner, err := NewRSAPSSSignerVerifier("rsa", rsaKey, WithRSAPSSHash(crypto.SHA512))
+	assert.Nil(t, err, "unexpected error")
+	edSigner, err := NewEd25519SignerVerifier("ed", edPrivate)
+	assert.Nil(t, err, "unexpected error")
+
+	es, err := NewMultiEnvelopeSigner(3, ecSigner, rsaSigner, edSigner)
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload("http://example.com/HelloWorld", []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	verifiers, err := VerifiersFromJWKS(jwksJSON(t, ecJWK, rsaJWK, edJWK))
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, verifiers, 3, "wrong number of verifiers")
+
+	ev, err := NewMultiEnvelopeVerifier(3, verifiers...)
+	assert.Nil(t, err, "unexpected error")
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 3, "wrong number of accepted keys")
+	for i, keyID := range []string{"ec", "rsa", "ed"} {
+		assert.Equal(t, keyID, acceptedKeys[i].KeyID, "wrong keyid")
+	}
+
+	t.Run("High-S signature", func(t *testing.T) {
+		pae := PAE("http://example.com/HelloWorld", []byte("hello world"))
+		sig, err := ConvertECDSASignature(elliptic.P384(), highSSignature(t, ecKey, crypto.SHA384, pae), SignatureEncodingJOSE)
+		assert.Nil(t, err, "unexpected error")
+		assert.Nil(t, verifiers[0].Verify(pae, sig), "high-S signature rejected")
+	})
+
+	t.Run("Unsupported keys skipped", func(t *testing.T) {
+		verifiers, err := VerifiersFromJWKS(jwksJSON(t,
+			map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+			edJWK,
+			map[string]string{"kty": "OKP", "kid": "x", "crv": "X25519", "x": b64(edPublic)},
+			map[string]string{"kty": "RSA", "kid": "rs", "alg": "RS256", "n": rsaJWK["n"], "e": rsaJWK["e"]},
+			map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": rsaJWK["n"], "e": rsaJWK["e"]},
+			map[string]string{"kty": "EC", "kid": "es256", "crv": "P-384", "alg": "ES256", "x": ecJWK["x"], "y": ecJWK["y"]},
+		))
+		assert.Len(t, verifiers, 1, "wrong number of verifiers")
+		keyID, _ := verifiers[0].KeyID()
+		assert.Equal(t, "ed", keyID, "wrong keyid")
+
+		var skipped *SkippedKeysError
+		assert.True(t, errors.As(err, &skipped), "wrong error")
+		assert.True(t, errors.Is(err, ErrUnsupportedKey), "wrong error")
+		assert.Len(t, skipped.Warnings, 5, "wrong number of warnings")
+		assert.Contains(t, skipped.Warnings[0], `"hmac"`, "wrong warning")
+	})
+
+	t.Run("Invalid keys", func(t *testing.T) {
+		bad := map[string]string{}
+		for k, v := range ecJWK {
+			bad[k] = v
+		}
+		bad["y"] = bad["x"]
+		_, err := VerifiersFromJWKS(jwksJSON(t, edJWK, bad))
+		assert.NotNil(t, err, "expected error")
+		assert.False(t, errors.Is(err, ErrUnsupportedKey), "wrong error")
+
+		_, err = VerifiersFromJWKS(jwksJSON(t, map[string]string{"kty": "OKP", "crv": "Ed25519", "x": "AAAA"}))
+		assert.NotNil(t, err, "expected error")
+
+		_, err = VerifiersFromJWKS([]byte(`not json`))
+		assert.NotNil(t, err, "expected error")
+	})
+}
@@ -0,0 +1,111 @@
+package dsse
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidJWS indicates that a string is not a JWS in compact serialization.
+var ErrInvalidJWS = errors.New("invalid jws")
+
+// ErrNotSingleSignature indicates that an envelope does not have exactly one
+// signature.
+var ErrNotSingleSignature = errors.New("envelope does not have exactly one signature")
+
+// jwsHeader is the protected header of a JWS created by EnvelopeToJWS. The
+// payload type of the envelope is carried as the content type.
+type jwsHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Cty string `json:"cty,omitempty"`
+}
+
+/*
+EnvelopeToJWS converts a single-signature envelope to a JWS in the compact
+serialization of RFC 7515, with alg as the JWS algorithm, such as "ES256".
+The payload type is stored as the cty header parameter.
+
+A JWS signature covers base64url(header) "." base64url(payload) rather than
+the PAE, so the signature of the envelope cannot be carried over. The JWS is
+signed anew with signer instead, which must implement alg; for example, an
+ECDSASignerVerifier must use SignatureEncodingJOSE. The signature of the
+envelope is not verified, so verify the envelope before converting it.
+*/
+func EnvelopeToJWS(env *Envelope, alg string, signer Signer) (string, error) {
+	if env == nil {
+		return "", ErrNoEnvelopes
+	}
+	if len(env.Signatures) != 1 {
+		return "", ErrNotSingleSignature
+	}
+	if signer == nil {
+		return "", ErrNoSigners
+	}
+
+	body, err := env.DecodedPayload()
+	if err != nil {
+		return "", err
+	}
+
+	keyID, err := signer.KeyID()
+	if err != nil {
+		return "", err
+	}
+	header, err := json.Marshal(jwsHeader{Alg: alg, Kid: keyID, Cty: env.PayloadType})
+	if err != nil {
+		return "", err
+	}
+
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	sig, err := signer.Sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+/*
+EnvelopeFromJWS converts a JWS in compact serialization to an envelope. The
+payload type is taken from the cty header parameter, which must be present.
+
+As with EnvelopeToJWS, the JWS signature does not cover the PAE and is not
+carried over, nor is it verified, so verify the JWS before converting it. If
+es is not nil, the envelope is signed with it; otherwise it has no signatures
+and can be signed later with EnvelopeSigner.AppendSignature.
+*/
+func EnvelopeFromJWS(jws string, es *EnvelopeSigner) (*Envelope, error) {
+	parts := strings.Split(jws, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 parts, got %d", ErrInvalidJWS, len(parts))
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidJWS, err)
+	}
+	var header jwsHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidJWS, err)
+	}
+	if header.Cty == "" {
+		return nil, fmt.Errorf("%w: missing cty header parameter", ErrInvalidJWS)
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidJWS, err)
+	}
+
+	if es != nil {
+		return es.SignPayload(header.Cty, body)
+	}
+
+	return &Envelope{
+		PayloadType: header.Cty,
+		Payload:     base64.StdEncoding.EncodeToString(body),
+	}, nil
+}
@@ -0,0 +1,99 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJWS(t *testing.T) {
+	var payloadType = PayloadTypeInToto
+	var payload = `{"_type":"https://in-toto.io/Statement/v0.1"}`
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	jose, err := NewECDSASignerVerifier("k", key, WithSignatureEncoding(SignatureEncodingJOSE))
+	assert.Nil(t, err, "unexpected error")
+	der, err := NewECDSASignerVerifier("k", key)
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(der)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	jws, err := EnvelopeToJWS(env, "ES256", jose)
+	assert.Nil(t, err, "unexpected error")
+
+	// The JWS verifies as RFC 7515 prescribes.
+	parts := strings.Split(jws, ".")
+	assert.Len(t, parts, 3, "wrong number of parts")
+	var header map[string]string
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, json.Unmarshal(rawHeader, &header), "unexpected error")
+	assert.Equal(t, map[string]string{"alg": "ES256", "kid": "k", "cty": payloadType}, header, "wrong header")
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, sig, 64, "wrong signature size")
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s), "jws signature invalid")
+
+	t.Run("re-signed", func(t *testing.T) {
+		back, err := EnvelopeFromJWS(jws, signer)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, env.PayloadType, back.PayloadType, "wrong payload type")
+		assert.Equal(t, env.Payload, back.Payload, "wrong payload")
+
+		_, err = signer.Verify(back)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		back, err := EnvelopeFromJWS(jws, nil)
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, 0, back.SignatureCount(), "unexpected signatures")
+
+		signed, err := signer.AppendSignature(back)
+		assert.Nil(t, err, "unexpected error")
+		_, err = signer.Verify(signed)
+		assert.Nil(t, err, "unexpected error")
+	})
+}
+
+func TestJWSErrors(t *testing.T) {
+	var ns nilsigner
+
+	_, err := EnvelopeToJWS(nil, "ES256", ns)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	_, err = EnvelopeToJWS(&Envelope{PayloadType: "t"}, "ES256", ns)
+	assert.Equal(t, ErrNotSingleSignature, err, "wrong error")
+	_, err = EnvelopeToJWS(&Envelope{PayloadType: "t", Signatures: []Signature{{}, {}}}, "ES256", ns)
+	assert.Equal(t, ErrNotSingleSignature, err, "wrong error")
+	_, err = EnvelopeToJWS(&Envelope{PayloadType: "t", Signatures: []Signature{{}}}, "ES256", nil)
+	assert.Equal(t, ErrNoSigners, err, "wrong error")
+
+	tests := map[string]string{
+		"parts":   "a.b",
+		"header":  "!!.e30.c2ln",
+		"json":    base64.RawURLEncoding.EncodeToString([]byte("x")) + ".e30.c2ln",
+		"cty":     base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`)) + ".e30.c2ln",
+		"payload": base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","cty":"t"}`)) + ".!!.c2ln",
+	}
+	for name, jws := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := EnvelopeFromJWS(jws, nil)
+			assert.True(t, errors.Is(err, ErrInvalidJWS), "wrong error")
+		})
+	}
+}
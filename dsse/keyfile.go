@@ -0,0 +1,404 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrUnsupportedKey indicates that a key file describes a key type or scheme
+// that is not supported.
+var ErrUnsupportedKey = errors.New("unsupported key")
+
+// ErrEncryptedKey indicates that a key file is encrypted and no password was
+// provided.
+var ErrEncryptedKey = errors.New("key is encrypted")
+
+// ErrDecryptionFailed indicates that an encrypted key could not be decrypted,
+// usually because of a wrong password.
+var ErrDecryptionFailed = errors.New("key decryption failed")
+
+// ErrKeyIDMismatch indicates that a key file is named after a key ID that does
+// not identify the key it contains.
+var ErrKeyIDMismatch = errors.New("key ID does not match key")
+
+// encryptedKeySeparator separates the fields of an encrypted key.
+const encryptedKeySeparator = "@@@@"
+
+/*
+jsonKey is a key in the securesystemslib key format:
+
+	{
+	  "keytype": "ed25519",
+	  "scheme": "ed25519",
+	  "keyid_hash_algorithms": ["sha256", "sha512"],
+	  "keyval": {"public": "...", "private": "..."}
+	}
+
+Ed25519 keys are hex encoded, the public half of ECDSA and RSA keys is a PEM
+encoded PKIX public key and their private half a PEM encoded private key. An
+optional keyid field is used as the key ID of the loaded key.
+*/
+type jsonKey struct {
+	KeyID               string   `json:"keyid,omitempty"`
+	KeyType             string   `json:"keytype"`
+	Scheme              string   `json:"scheme"`
+	KeyIDHashAlgorithms []string `json:"keyid_hash_algorithms,omitempty"`
+	KeyVal              struct {
+		Public  string `json:"public"`
+		Private string `json:"private,omitempty"`
+	} `json:"keyval"`
+}
+
+/*
+LoadSignerFromJSON creates a bundled SignVerifier from a private key in the
+securesystemslib JSON key format. The supported schemes are "ed25519",
+"ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384" and "rsassa-pss-sha256",
+"rsassa-pss-sha384" and "rsassa-pss-sha512". The key ID is the keyid field
+of the key if present, and otherwise computed as securesystemslib does, as
+the hex encoded SHA-256 digest of the canonical JSON of the public key.
+Encrypted keys are rejected with ErrEncryptedKey; use
+LoadSignerFromEncryptedJSON for them.
+*/
+func LoadSignerFromJSON(data []byte) (SignVerifier, error) {
+	return loadSignerFromJSON(data, nil)
+}
+
+/*
+LoadSignerFromEncryptedJSON is like LoadSignerFromJSON, but decrypts the key
+with password if needed. Both a key file that is encrypted as a whole and a
+key whose keyval.private is encrypted are supported, in the securesystemslib
+format "salt@@@@iterations@@@@hmac@@@@iv@@@@ciphertext" with PBKDF2-SHA256
+key derivation, AES-256-CTR encryption and an HMAC-SHA256 over the
+ciphertext.
+*/
+func LoadSignerFromEncryptedJSON(data, password []byte) (SignVerifier, error) {
+	return loadSignerFromJSON(data, password)
+}
+
+/*
+LoadVerifierFromJSON creates a bundled verifier from a public key in the
+securesystemslib JSON key format. The private half of the key, if present,
+is ignored. See LoadSignerFromJSON for the supported schemes and the key ID.
+ECDSA verifiers accept high-S signatures, which securesystemslib produces.
+*/
+func LoadVerifierFromJSON(data []byte) (Verifier, error) {
+	key, err := parseJSONKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID, err := key.keyID()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.KeyType {
+	case "ed25519":
+		public, err := key.ed25519Public()
+		if err != nil {
+			return nil, err
+		}
+		return NewEd25519Verifier(keyID, public)
+	case "ecdsa", "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384":
+		public, err := ParsePublicKeyPEM([]byte(key.KeyVal.Public))
+		if err != nil {
+			return nil, err
+		}
+		pk, ok := public.(*ecdsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("expected ecdsa key, got %T", public)
+		}
+		hash, err := key.ecdsaHash(pk.Curve)
+		if err != nil {
+			return nil, err
+		}
+		// securesystemslib does not normalize S.
+		return NewECDSAVerifier(keyID, pk, WithECDSAHash(hash), WithAllowHighS())
+	case "rsa":
+		public, err := ParsePublicKeyPEM([]byte(key.KeyVal.Public))
+		if err != nil {
+			return nil, err
+		}
+		pk, ok := public.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("expected rsa key, got %T", public)
+		}
+		hash, err := key.rsaPSSHash()
+		if err != nil {
+			return nil, err
+		}
+		return NewRSAPSSVerifier(keyID, pk, WithRSAPSSHash(hash))
+	}
+
+	return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, key.KeyType)
+}
+
+func loadSignerFromJSON(data, password []byte) (SignVerifier, error) {
+	if s := strings.TrimSpace(string(data)); strings.Contains(s, encryptedKeySeparator) && !strings.HasPrefix(s, "{") {
+		if password == nil {
+			return nil, ErrEncryptedKey
+		}
+		var err error
+		if data, err = decryptKey(s, password); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := parseJSONKey(data)
+	if err != nil {
+		return nil, err
+	}
+	if key.KeyVal.Private == "" {
+		return nil, ErrNoPrivateKey
+	}
+	if strings.Contains(key.KeyVal.Private, encryptedKeySeparator) {
+		if password == nil {
+			return nil, ErrEncryptedKey
+		}
+		private, err := decryptKey(key.KeyVal.Private, password)
+		if err != nil {
+			return nil, err
+		}
+		key.KeyVal.Private = string(private)
+	}
+
+	keyID, err := key.keyID()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.KeyType {
+	case "ed25519":
+		if _, err := key.ed25519Public(); err != nil {
+			return nil, err
+		}
+		seed, err := hex.DecodeString(key.KeyVal.Private)
+		if err != nil {
+			return nil, err
+		}
+		var private ed25519.PrivateKey
+		switch len(seed) {
+		case ed25519.SeedSize:
+			private = ed25519.NewKeyFromSeed(seed)
+		case ed25519.PrivateKeySize:
+			private = ed25519.PrivateKey(seed)
+		default:
+			return nil, errors.New("invalid ed25519 private key size")
+		}
+		return NewEd25519SignerVerifier(keyID, private)
+	case "ecdsa", "ecdsa-sha2-nistp256", "ecdsa-sha2-nistp384":
+		private, err := ParsePrivateKeyPEM([]byte(key.KeyVal.Private))
+		if err != nil {
+			return nil, err
+		}
+		k, ok := private.(*ecdsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("expected ecdsa key, got %T", private)
+		}
+		hash, err := key.ecdsaHash(k.Curve)
+		if err != nil {
+			return nil, err
+		}
+		return NewECDSASignerVerifier(keyID, k, WithECDSAHash(hash))
+	case "rsa":
+		private, err := ParsePrivateKeyPEM([]byte(key.KeyVal.Private))
+		if err != nil {
+			return nil, err
+		}
+		k, ok := private.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("expected rsa key, got %T", private)
+		}
+		hash, err := key.rsaPSSHash()
+		if err != nil {
+			return nil, err
+		}
+		return NewRSAPSSSignerVerifier(keyID, k, WithRSAPSSHash(hash))
+	}
+
+	return nil, fmt.Errorf("%w: key type %q", ErrUnsupportedKey, key.KeyType)
+}
+
+func parseJSONKey(data []byte) (*jsonKey, error) {
+	var key jsonKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+	if key.KeyType == "" || key.Scheme == "" || key.KeyVal.Public == "" {
+		return nil, errors.New("key is missing keytype, scheme or keyval.public")
+	}
+
+	return &key, nil
+}
+
+// keyID returns the keyid of the key, computing it if the field is not set.
+func (k *jsonKey) keyID() (string, error) {
+	if k.KeyID != "" {
+		return k.KeyID, nil
+	}
+
+	public := *k
+	public.KeyVal.Private = ""
+	data, err := cjson.EncodeCanonical(public)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+func (k *jsonKey) ed25519Public() (ed25519.PublicKey, error) {
+	if k.Scheme != "ed25519" {
+		return nil, fmt.Errorf("%w: scheme %q", ErrUnsupportedKey, k.Scheme)
+	}
+
+	public, err := hex.DecodeString(k.KeyVal.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	return ed25519.PublicKey(public), nil
+}
+
+// ecdsaHash returns the hash of the scheme, checking that it fits the curve.
+func (k *jsonKey) ecdsaHash(curve elliptic.Curve) (crypto.Hash, error) {
+	switch {
+	case k.Scheme == "ecdsa-sha2-nistp256" && curve == elliptic.P256():
+		return crypto.SHA256, nil
+	case k.Scheme == "ecdsa-sha2-nistp384" && curve == elliptic.P384():
+		return crypto.SHA384, nil
+	}
+
+	return 0, fmt.Errorf("%w: scheme %q for curve %s", ErrUnsupportedKey, k.Scheme, curve.Params().Name)
+}
+
+func (k *jsonKey) rsaPSSHash() (crypto.Hash, error) {
+	switch k.Scheme {
+	case "rsassa-pss-sha256":
+		return crypto.SHA256, nil
+	case "rsassa-pss-sha384":
+		return crypto.SHA384, nil
+	case "rsassa-pss-sha512":
+		return crypto.SHA512, nil
+	}
+
+	return 0, fmt.Errorf("%w: scheme %q", ErrUnsupportedKey, k.Scheme)
+}
+
+/*
+VerifiersFromDir loads a trust store of PEM encoded PKIX public keys from the
+directory dir, with each key in a file named after its key ID plus the
+extension ".pem". Other files and subdirectories are ignored. The key ID in
+the file name must identify the key as SHA256KeyID does, encoded in hex or in
+any base64 alphabet and optionally prefixed with "sha256:"; otherwise an error
+wrapping ErrKeyIDMismatch is returned, so that misfiled keys are caught. The
+verifiers are returned by key ID and use the key ID of their file name, as
+that is the form signatures are expected to carry. See VerifySignature for
+the supported keys.
+*/
+func VerifiersFromDir(dir string) (map[string]Verifier, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	verifiers := make(map[string]Verifier)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		keyID := strings.TrimSuffix(entry.Name(), ".pem")
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		public, err := ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+
+		fingerprint, err := SHA256KeyID(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if !sameKeyID(keyID, strings.TrimPrefix(fingerprint, "SHA256:")) {
+			return nil, fmt.Errorf("%w: %s is %s", ErrKeyIDMismatch, entry.Name(), fingerprint)
+		}
+
+		v, err := verifierForKey(keyID, public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		verifiers[keyID] = v
+	}
+
+	return verifiers, nil
+}
+
+/*
+decryptKey decrypts a key in the securesystemslib encrypted format,
+"salt@@@@iterations@@@@hmac@@@@iv@@@@ciphertext", with all but the iteration
+count hex encoded.
+*/
+func decryptKey(encrypted string, password []byte) ([]byte, error) {
+	fields := strings.Split(strings.TrimSpace(encrypted), encryptedKeySeparator)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: malformed encrypted key", ErrDecryptionFailed)
+	}
+
+	var raw [4][]byte
+	for i, field := range []string{fields[0], fields[2], fields[3], fields[4]} {
+		b, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed encrypted key", ErrDecryptionFailed)
+		}
+		raw[i] = b
+	}
+	salt, mac, iv, ciphertext := raw[0], raw[1], raw[2], raw[3]
+
+	iterations, err := strconv.Atoi(fields[1])
+	if err != nil || iterations <= 0 {
+		return nil, fmt.Errorf("%w: malformed encrypted key", ErrDecryptionFailed)
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("%w: malformed encrypted key", ErrDecryptionFailed)
+	}
+
+	derived := pbkdf2.Key(password, salt, iterations, 32, sha256.New)
+
+	h := hmac.New(sha256.New, derived)
+	h.Write(ciphertext)
+	if !hmac.Equal(h.Sum(nil), mac) {
+		return nil, ErrDecryptionFailed
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, iv).XORKeyStream(plaintext, ciphertext)
+
+	return bytes.TrimSpace(plaintext), nil
+}
@@ -0,0 +1,280 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+func jsonKeyFile(t *testing.T, keyType, scheme, public, private string) []byte {
+	key := map[string]interface{}{
+		"keytype": keyType,
+		"scheme":  scheme,
+		"keyval":  map[string]string{"public": public, "private": private},
+	}
+	data, err := json.Marshal(key)
+	assert.Nil(t, err, "unexpected error")
+	return data
+}
+
+// encryptKey encrypts data in the securesystemslib encrypted key format.
+func encryptKey(t *testing.T, data, password []byte) string {
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	_, err := rand.Read(salt)
+	assert.Nil(t, err, "unexpected error")
+	_, err = rand.Read(iv)
+	assert.Nil(t, err, "unexpected error")
+
+	derived := pbkdf2.Key(password, salt, 1000, 32, sha256.New)
+	block, err := aes.NewCipher(derived)
+	assert.Nil(t, err, "unexpected error")
+	ciphertext := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(ciphertext, data)
+
+	h := hmac.New(sha256.New, derived)
+	h.Write(ciphertext)
+
+	return strings.Join([]string{
+		hex.EncodeToString(salt),
+		"1000",
+		hex.EncodeToString(h.Sum(nil)),
+		hex.EncodeToString(iv),
+		hex.EncodeToString(ciphertext),
+	}, encryptedKeySeparator)
+}
+
+func TestLoadFromJSON(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	edKey := newEd25519Key()
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	ecPrivate, err := x509.MarshalECPrivateKey(ecKey)
+	assert.Nil(t, err, "unexpected error")
+	ecPublic, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+
+	tests := map[string][]byte{
+		"ed25519": jsonKeyFile(t, "ed25519", "ed25519",
+			hex.EncodeToString(edKey.Public().(ed25519.PublicKey)),
+			hex.EncodeToString(edKey.Seed())),
+		"ecdsa": jsonKeyFile(t, "ecdsa", "ecdsa-sha2-nistp384",
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPublic})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecPrivate}))),
+		"rsa": jsonKeyFile(t, "rsa", "rsassa-pss-sha512",
+			string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublic})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			sv, err := LoadSignerFromJSON(data)
+			assert.Nil(t, err, "unexpected error")
+			v, err := LoadVerifierFromJSON(data)
+			assert.Nil(t, err, "unexpected error")
+
+			signerKeyID, err := sv.KeyID()
+			assert.Nil(t, err, "unexpected error")
+			verifierKeyID, err := v.KeyID()
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, signerKeyID, 64, "wrong key ID")
+			assert.Equal(t, signerKeyID, verifierKeyID, "key IDs differ")
+
+			signer, err := NewEnvelopeSigner(sv)
+			assert.Nil(t, err, "unexpected error")
+			env, err := signer.SignPayload(payloadType, []byte(payload))
+			assert.Nil(t, err, "sign failed")
+
+			ev, err := NewEnvelopeVerifier(v)
+			assert.Nil(t, err, "unexpected error")
+			acceptedKeys, err := ev.Verify(env)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, acceptedKeys, 1, "unexpected keys")
+		})
+	}
+}
+
+func TestLoadVerifierFromJSONHighS(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	public := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	v, err := LoadVerifierFromJSON(jsonKeyFile(t, "ecdsa", "ecdsa-sha2-nistp384", public, ""))
+	assert.Nil(t, err, "unexpected error")
+	sig := highSSignature(t, ecKey, crypto.SHA384, PAE(payloadType, payload))
+	assert.Nil(t, v.Verify(PAE(payloadType, payload), sig), "high-S signature rejected")
+}
+
+func TestJSONKeyID(t *testing.T) {
+	public := hex.EncodeToString(newEd25519Key().Public().(ed25519.PublicKey))
+	canonical := fmt.Sprintf(`{"keytype":"ed25519","keyval":{"public":"%s"},"scheme":"ed25519"}`, public)
+	digest := sha256.Sum256([]byte(canonical))
+
+	v, err := LoadVerifierFromJSON(jsonKeyFile(t, "ed25519", "ed25519", public, ""))
+	assert.Nil(t, err, "unexpected error")
+	keyID, err := v.KeyID()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, hex.EncodeToString(digest[:]), keyID, "wrong key ID")
+
+	v, err = LoadVerifierFromJSON([]byte(fmt.Sprintf(`{"keyid":"k","keytype":"ed25519","scheme":"ed25519","keyval":{"public":"%s"}}`, public)))
+	assert.Nil(t, err, "unexpected error")
+	keyID, err = v.KeyID()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "k", keyID, "wrong key ID")
+}
+
+func TestLoadSignerFromEncryptedJSON(t *testing.T) {
+	edKey := newEd25519Key()
+	public := hex.EncodeToString(edKey.Public().(ed25519.PublicKey))
+	plain := jsonKeyFile(t, "ed25519", "ed25519", public, hex.EncodeToString(edKey.Seed()))
+	password := []byte("secret")
+
+	tests := map[string][]byte{
+		"whole file":     []byte(encryptKey(t, plain, password)),
+		"private keyval": jsonKeyFile(t, "ed25519", "ed25519", public, encryptKey(t, []byte(hex.EncodeToString(edKey.Seed())), password)),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadSignerFromJSON(data)
+			assert.Equal(t, ErrEncryptedKey, err, "wrong error")
+
+			_, err = LoadSignerFromEncryptedJSON(data, []byte("wrong"))
+			assert.True(t, errors.Is(err, ErrDecryptionFailed), "wrong error")
+
+			sv, err := LoadSignerFromEncryptedJSON(data, password)
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, edKey.Public(), sv.Public(), "wrong key")
+		})
+	}
+
+	sv, err := LoadSignerFromEncryptedJSON(plain, password)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, edKey.Public(), sv.Public(), "wrong key")
+}
+
+func TestLoadFromJSONErrors(t *testing.T) {
+	public := hex.EncodeToString(newEd25519Key().Public().(ed25519.PublicKey))
+
+	_, err := LoadSignerFromJSON(jsonKeyFile(t, "ed25519", "ed25519", public, ""))
+	assert.Equal(t, ErrNoPrivateKey, err, "wrong error")
+
+	_, err = LoadVerifierFromJSON(jsonKeyFile(t, "dsa", "dsa", public, ""))
+	assert.True(t, errors.Is(err, ErrUnsupportedKey), "wrong error")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	ecPublic, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	_, err = LoadVerifierFromJSON(jsonKeyFile(t, "ecdsa", "ecdsa-sha2-nistp384",
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: ecPublic})), ""))
+	assert.True(t, errors.Is(err, ErrUnsupportedKey), "wrong error")
+
+	_, err = LoadVerifierFromJSON([]byte(`{"keytype":"ed25519"}`))
+	assert.NotNil(t, err, "expected error")
+}
+
+func TestVerifiersFromDir(t *testing.T) {
+	writeKey := func(t *testing.T, dir, name string, public interface{}) {
+		der, err := x509.MarshalPKIXPublicKey(public)
+		assert.Nil(t, err, "unexpected error")
+		data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), data, 0o600), "unexpected error")
+	}
+	fingerprint := func(t *testing.T, public interface{}) []byte {
+		keyID, err := SHA256KeyID(public)
+		assert.Nil(t, err, "unexpected error")
+		b, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(keyID, "SHA256:"))
+		assert.Nil(t, err, "unexpected error")
+		return b
+	}
+
+	edKey := newEd25519Key()
+	edPublic := edKey.Public()
+	ecKey := newEcdsaKey()
+	edKeyID := hex.EncodeToString(fingerprint(t, edPublic))
+	ecKeyID := "sha256:" + base64.RawURLEncoding.EncodeToString(fingerprint(t, &ecKey.PublicKey))
+
+	dir := t.TempDir()
+	writeKey(t, dir, edKeyID+".pem", edPublic)
+	writeKey(t, dir, ecKeyID+".pem", &ecKey.PublicKey)
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "README"), []byte("trusted keys"), 0o600), "unexpected error")
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "old.pem"), 0o700), "unexpected error")
+
+	verifiers, err := VerifiersFromDir(dir)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, verifiers, 2, "wrong number of verifiers")
+
+	sv, err := NewEd25519SignerVerifier(edKeyID, edKey)
+	assert.Nil(t, err, "unexpected error")
+	es, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload("http://example.com/HelloWorld", []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	ev, err := NewEnvelopeVerifier(verifiers[edKeyID], verifiers[ecKeyID])
+	assert.Nil(t, err, "unexpected error")
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, edKeyID, acceptedKeys[0].KeyID, "wrong keyid")
+
+	t.Run("High-S signature", func(t *testing.T) {
+		pae := PAE("http://example.com/HelloWorld", []byte("hello world"))
+		sig := highSSignature(t, ecKey, crypto.SHA256, pae)
+		assert.Nil(t, verifiers[ecKeyID].Verify(pae, sig), "high-S signature rejected")
+	})
+
+	t.Run("Misfiled key", func(t *testing.T) {
+		dir := t.TempDir()
+		writeKey(t, dir, edKeyID+".pem", &ecKey.PublicKey)
+
+		_, err := VerifiersFromDir(dir)
+		assert.True(t, errors.Is(err, ErrKeyIDMismatch), "wrong error")
+	})
+
+	t.Run("Not PEM", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, edKeyID+".pem"), []byte("not pem"), 0o600), "unexpected error")
+
+		_, err := VerifiersFromDir(dir)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Missing directory", func(t *testing.T) {
+		_, err := VerifiersFromDir(filepath.Join(dir, "missing"))
+		assert.True(t, errors.Is(err, os.ErrNotExist), "wrong error")
+	})
+}
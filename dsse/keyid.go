@@ -0,0 +1,100 @@
+package dsse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+/*
+KeyIDFormatError is the cause of a VerificationError if no key matched the
+signatures of an envelope, but the key ID of a signature and the key ID of a
+verifier encode the same bytes in different formats, for example hex and
+base64. This usually means that the signer and the verifier derive key IDs
+differently. It matches ErrNoMatchingKey with errors.Is.
+*/
+type KeyIDFormatError struct {
+	SignatureKeyID string
+	VerifierKeyID  string
+}
+
+func (e *KeyIDFormatError) Error() string {
+	return fmt.Sprintf("no matching key: signature key ID %q and verifier key ID %q differ only in format", e.SignatureKeyID, e.VerifierKeyID)
+}
+
+func (e *KeyIDFormatError) Is(target error) bool {
+	return target == ErrNoMatchingKey
+}
+
+/*
+keyIDFormatError returns a *KeyIDFormatError for the first key ID of the
+signatures that identifies the same key as a verifier in a different format,
+and ErrNoMatchingKey if there is none.
+*/
+func (ev *envelopeVerifier) keyIDFormatError(signatures []Signature) error {
+	for _, s := range signatures {
+		if s.KeyID == "" {
+			continue
+		}
+		for _, keyID := range ev.keyIDs {
+			if keyID != "" && keyID != s.KeyID && sameKeyID(s.KeyID, keyID) {
+				return &KeyIDFormatError{SignatureKeyID: s.KeyID, VerifierKeyID: keyID}
+			}
+		}
+	}
+
+	return ErrNoMatchingKey
+}
+
+// normalizeKeyID removes surrounding whitespace and lowercases hex key IDs.
+func normalizeKeyID(keyID string) string {
+	keyID = strings.TrimSpace(keyID)
+	if _, err := hex.DecodeString(keyID); err == nil {
+		return strings.ToLower(keyID)
+	}
+
+	return keyID
+}
+
+// sameKeyID reports whether the key IDs a and b encode the same bytes.
+func sameKeyID(a, b string) bool {
+	if normalizeKeyID(a) == normalizeKeyID(b) {
+		return true
+	}
+
+	for _, da := range keyIDBytes(a) {
+		for _, db := range keyIDBytes(b) {
+			if bytes.Equal(da, db) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+keyIDBytes returns the possible decodings of a key ID, which may be hex or
+base64 encoded with any alphabet, with or without padding, and may carry a
+"SHA256:" prefix as produced by SHA256KeyID.
+*/
+func keyIDBytes(keyID string) [][]byte {
+	keyID = strings.TrimSpace(keyID)
+	if len(keyID) > len("sha256:") && strings.EqualFold(keyID[:len("sha256:")], "sha256:") {
+		keyID = keyID[len("sha256:"):]
+	}
+
+	var decoded [][]byte
+	if b, err := hex.DecodeString(keyID); err == nil && len(b) > 0 {
+		decoded = append(decoded, b)
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(keyID); err == nil && len(b) > 0 {
+			decoded = append(decoded, b)
+		}
+	}
+
+	return decoded
+}
@@ -0,0 +1,75 @@
+package dsse
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyIDFormat(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	key := newEd25519Key()
+	pub := key.Public().(ed25519.PublicKey)
+	fingerprint, err := SHA256KeyID(pub)
+	assert.Nil(t, err, "unexpected error")
+	hexKeyID := hex.EncodeToString(mustB64Decode(t, strings.TrimPrefix(fingerprint, "SHA256:")+"="))
+
+	sign := func(keyID string) *Envelope {
+		sv, err := NewEd25519SignerVerifier(keyID, key)
+		assert.Nil(t, err, "unexpected error")
+		signer, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+		return env
+	}
+
+	verify := func(keyID string, env *Envelope, opts ...Option) error {
+		v, err := NewEd25519Verifier(keyID, pub)
+		assert.Nil(t, err, "unexpected error")
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{v}, opts...)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		return err
+	}
+
+	t.Run("Case", func(t *testing.T) {
+		env := sign(strings.ToUpper(hexKeyID))
+
+		err := verify(hexKeyID, env)
+		var ferr *KeyIDFormatError
+		assert.True(t, errors.As(err, &ferr), "wrong error")
+		assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+		assert.Equal(t, strings.ToUpper(hexKeyID), ferr.SignatureKeyID, "wrong signature key ID")
+		assert.Equal(t, hexKeyID, ferr.VerifierKeyID, "wrong verifier key ID")
+
+		assert.Nil(t, verify(hexKeyID, env, WithNormalizedKeyIDs()), "unexpected error")
+		assert.Nil(t, verify(" "+hexKeyID+"\n", env, WithNormalizedKeyIDs(), WithStrictKeyIDMatching()), "unexpected error")
+	})
+
+	t.Run("Hex and fingerprint", func(t *testing.T) {
+		env := sign(hexKeyID)
+
+		err := verify(fingerprint, env)
+		var ferr *KeyIDFormatError
+		assert.True(t, errors.As(err, &ferr), "wrong error")
+		assert.Equal(t, hexKeyID, ferr.SignatureKeyID, "wrong signature key ID")
+		assert.Equal(t, fingerprint, ferr.VerifierKeyID, "wrong verifier key ID")
+
+		err = verify(fingerprint, env, WithNormalizedKeyIDs())
+		assert.True(t, errors.As(err, &ferr), "wrong error")
+	})
+
+	t.Run("Unrelated", func(t *testing.T) {
+		err := verify("other", sign(hexKeyID))
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, ErrNoMatchingKey, verr.Err, "wrong error")
+	})
+}
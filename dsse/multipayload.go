@@ -0,0 +1,150 @@
+package dsse
+
+import (
+	"errors"
+	"strconv"
+)
+
+// ErrNoPayloads indicates that no payload was provided.
+var ErrNoPayloads = errors.New("no payloads provided")
+
+// PayloadItem is a payload and its type, as signed in a multi-payload envelope.
+type PayloadItem struct {
+	PayloadType string
+	Payload     []byte
+}
+
+/*
+EncodedPayload is a payload and its type as stored in a
+MultiPayloadEnvelope. The payload is base64 encoded, see WithBase64Encoding.
+*/
+type EncodedPayload struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+}
+
+/*
+MultiPayloadEnvelope is an extension of the DSSE envelope where every
+signature covers an ordered list of payloads at once. It is not part of the
+DSSE specification; single payloads should use Envelope.
+*/
+type MultiPayloadEnvelope struct {
+	Payloads   []EncodedPayload `json:"payloads"`
+	Signatures []Signature      `json:"signatures"`
+}
+
+/*
+MultiPAE is the pre-authentication encoding of an ordered list of payloads:
+
+	"DSSEv1-multi" SP LEN(items)
+	for each item: SP LEN(type) SP type SP LEN(payload) SP payload
+
+Every field is length prefixed as in PAE, so reordering, splitting or joining
+payloads changes the encoding. The distinct prefix keeps it from colliding
+with PAE.
+*/
+func MultiPAE(items []PayloadItem) []byte {
+	b := []byte("DSSEv1-multi ")
+	b = strconv.AppendInt(b, int64(len(items)), 10)
+	for _, item := range items {
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, int64(len(item.PayloadType)), 10)
+		b = append(b, ' ')
+		b = append(b, item.PayloadType...)
+		b = append(b, ' ')
+		b = strconv.AppendInt(b, int64(len(item.Payload)), 10)
+		b = append(b, ' ')
+		b = append(b, item.Payload...)
+	}
+
+	return b
+}
+
+/*
+SignMultiPayload signs an ordered list of payloads with a single signature
+per Signer, computed over MultiPAE(items).
+*/
+func (es *EnvelopeSigner) SignMultiPayload(items []PayloadItem) (*MultiPayloadEnvelope, error) {
+	if len(items) == 0 {
+		return nil, ErrNoPayloads
+	}
+
+	var e MultiPayloadEnvelope
+	normalized := make([]PayloadItem, 0, len(items))
+	for _, item := range items {
+		item.PayloadType = es.opts.normalizePayloadType(item.PayloadType)
+		normalized = append(normalized, item)
+		if err := ValidatePayloadType(item.PayloadType); err != nil {
+			return nil, err
+		}
+		if err := es.checkSignerPayloadType(item.PayloadType); err != nil {
+			return nil, err
+		}
+		es.opts.notePayloadType(item.PayloadType)
+		e.Payloads = append(e.Payloads, EncodedPayload{
+			PayloadType: item.PayloadType,
+			Payload:     es.opts.encode(item.Payload),
+		})
+	}
+
+	signatures, err := es.signPAE(MultiPAE(normalized))
+	if err != nil {
+		return nil, err
+	}
+	e.Signatures = signatures
+
+	return &e, nil
+}
+
+// VerifyMultiPayload decodes the payloads and verifies the signatures.
+func (es *EnvelopeSigner) VerifyMultiPayload(e *MultiPayloadEnvelope) ([]AcceptedKey, error) {
+	return es.ev.VerifyMultiPayload(e)
+}
+
+/*
+VerifyMultiPayload decodes the payloads of a multi-payload envelope and
+verifies the signatures over MultiPAE of the payloads, in order.
+*/
+func (ev *envelopeVerifier) VerifyMultiPayload(e *MultiPayloadEnvelope) ([]AcceptedKey, error) {
+	if ev.opts.auditSink != nil {
+		return ev.audit(VerifyEvent{}, func(av *envelopeVerifier) ([]AcceptedKey, error) {
+			return av.VerifyMultiPayload(e)
+		})
+	}
+
+	if e == nil {
+		return nil, ErrNoEnvelopes
+	}
+	if len(e.Signatures) == 0 {
+		return nil, ErrNoSignature
+	}
+	if err := ev.opts.checkSignatureCount(len(e.Signatures)); err != nil {
+		return nil, err
+	}
+	if len(e.Payloads) == 0 {
+		return nil, ErrNoPayloads
+	}
+
+	items := make([]PayloadItem, 0, len(e.Payloads))
+	for _, p := range e.Payloads {
+		payloadType := ev.opts.normalizePayloadType(p.PayloadType)
+		if err := ev.opts.checkPayloadType(payloadType); err != nil {
+			return nil, err
+		}
+		if err := ev.opts.checkPayloadSize(p.Payload); err != nil {
+			return nil, err
+		}
+
+		body, err := ev.opts.decode(p.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, PayloadItem{
+			PayloadType: payloadType,
+			Payload:     body,
+		})
+	}
+
+	return ev.verifySignatures(&message{pae: MultiPAE(items)}, e.Signatures)
+}
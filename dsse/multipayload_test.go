@@ -0,0 +1,94 @@
+package dsse
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiPAE(t *testing.T) {
+	t.Run("Encoding", func(t *testing.T) {
+		got := MultiPAE([]PayloadItem{
+			{"http://example.com/HelloWorld", []byte("hello world")},
+			{"t", []byte("")},
+		})
+		want := []byte("DSSEv1-multi 2 29 http://example.com/HelloWorld 11 hello world 1 t 0 ")
+		assert.Equal(t, want, got, "wrong encoding")
+	})
+
+	t.Run("Unambiguous", func(t *testing.T) {
+		encodings := [][]byte{
+			MultiPAE([]PayloadItem{{"t", []byte("ab")}}),
+			MultiPAE([]PayloadItem{{"t", []byte("a")}, {"t", []byte("b")}}),
+			MultiPAE([]PayloadItem{{"t", []byte("b")}, {"t", []byte("a")}}),
+			MultiPAE([]PayloadItem{{"t", []byte("a 1 t 1 b")}}),
+			PAE("t", []byte("ab")),
+		}
+		for i := range encodings {
+			for j := range encodings {
+				if i != j {
+					assert.NotEqual(t, encodings[i], encodings[j], "colliding encodings %d and %d", i, j)
+				}
+			}
+		}
+	})
+}
+
+func TestSignMultiPayload(t *testing.T) {
+	items := []PayloadItem{
+		{"http://example.com/HelloWorld", []byte("hello world")},
+		{"http://example.com/Goodbye", []byte("goodbye")},
+	}
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignMultiPayload(items)
+	assert.Nil(t, err, "sign failed")
+	assert.Len(t, env.Payloads, 2, "wrong payloads")
+	assert.Len(t, env.Signatures, 1, "wrong signatures")
+
+	acceptedKeys, err := signer.VerifyMultiPayload(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+	t.Run("Reordered", func(t *testing.T) {
+		reordered := *env
+		reordered.Payloads = []EncodedPayload{env.Payloads[1], env.Payloads[0]}
+		_, err := signer.VerifyMultiPayload(&reordered)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Dropped", func(t *testing.T) {
+		dropped := *env
+		dropped.Payloads = env.Payloads[:1]
+		_, err := signer.VerifyMultiPayload(&dropped)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("No payloads", func(t *testing.T) {
+		_, err := signer.SignMultiPayload(nil)
+		assert.Equal(t, ErrNoPayloads, err, "wrong error")
+
+		_, err = signer.VerifyMultiPayload(&MultiPayloadEnvelope{Signatures: env.Signatures})
+		assert.Equal(t, ErrNoPayloads, err, "wrong error")
+	})
+
+	t.Run("No signatures", func(t *testing.T) {
+		_, err := signer.VerifyMultiPayload(&MultiPayloadEnvelope{Payloads: env.Payloads})
+		assert.Equal(t, ErrNoSignature, err, "wrong error")
+	})
+
+	t.Run("Base64 encoding", func(t *testing.T) {
+		signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{ns}, WithBase64Encoding(base64.RawURLEncoding))
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignMultiPayload(items)
+		assert.Nil(t, err, "sign failed")
+		assert.Equal(t, base64.RawURLEncoding.EncodeToString(items[0].Payload), env.Payloads[0].Payload, "wrong encoding")
+
+		_, err = signer.VerifyMultiPayload(env)
+		assert.Nil(t, err, "unexpected error")
+	})
+}
@@ -0,0 +1,157 @@
+package dsse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrLineTooLong indicates that a line of an envelope stream exceeds the
+// maximum line size of the reader.
+var ErrLineTooLong = errors.New("line too long")
+
+// DefaultMaxLineSize is the default maximum size in bytes of a line read by
+// an EnvelopeStreamReader.
+const DefaultMaxLineSize = 16 << 20
+
+/*
+LineError describes a malformed line of an envelope stream. Line counts from
+one. The reader stays usable after a LineError, so that the caller can choose
+between skipping the line and stopping.
+*/
+type LineError struct {
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+/*
+EnvelopeStreamWriter writes envelopes as newline-delimited JSON, one compact
+envelope per line, for pipelines that process many envelopes without holding
+them in memory. Every envelope is written with a single Write call to the
+underlying writer, which may be wrapped in a bufio.Writer for throughput.
+*/
+type EnvelopeStreamWriter struct {
+	w io.Writer
+}
+
+// NewEnvelopeStreamWriter returns an EnvelopeStreamWriter writing to w.
+func NewEnvelopeStreamWriter(w io.Writer) *EnvelopeStreamWriter {
+	return &EnvelopeStreamWriter{w: w}
+}
+
+// Write writes e on a line of its own.
+func (sw *EnvelopeStreamWriter) Write(e *Envelope) error {
+	if e == nil {
+		return ErrNoEnvelopes
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = sw.w.Write(append(line, '\n'))
+	return err
+}
+
+/*
+EnvelopeStreamReader reads envelopes from newline-delimited JSON one at a
+time. Blank lines are skipped, and lines may end with "\r\n".
+*/
+type EnvelopeStreamReader struct {
+	r       *bufio.Reader
+	maxLine int
+	line    int
+	err     error
+}
+
+// NewEnvelopeStreamReader returns an EnvelopeStreamReader reading from r,
+// with lines limited to DefaultMaxLineSize.
+func NewEnvelopeStreamReader(r io.Reader) *EnvelopeStreamReader {
+	return &EnvelopeStreamReader{
+		r:       bufio.NewReader(r),
+		maxLine: DefaultMaxLineSize,
+	}
+}
+
+// SetMaxLineSize limits the size in bytes of a line, not counting the line
+// ending. Longer lines are reported with a LineError wrapping ErrLineTooLong.
+func (sr *EnvelopeStreamReader) SetMaxLineSize(n int) {
+	sr.maxLine = n
+}
+
+/*
+Next returns the next envelope of the stream, or io.EOF after the last one.
+A line that is not a JSON envelope, or whose envelope fails Validate, yields
+a *LineError, after which Next may be called again to continue with the
+following line. Any other error is from the underlying reader and is
+returned by all further calls.
+*/
+func (sr *EnvelopeStreamReader) Next() (*Envelope, error) {
+	for sr.err == nil {
+		line, err := sr.readLine()
+		if err != nil {
+			if _, ok := err.(*LineError); ok {
+				return nil, err
+			}
+			sr.err = err
+			break
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var e Envelope
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, &LineError{Line: sr.line, Err: err}
+		}
+		if err := e.Validate(); err != nil {
+			return nil, &LineError{Line: sr.line, Err: err}
+		}
+		return &e, nil
+	}
+
+	return nil, sr.err
+}
+
+/*
+readLine returns the next line without its line ending. A line longer than
+the maximum is consumed and reported as a LineError. At the end of the
+stream, a last line without a line ending is returned before io.EOF.
+*/
+func (sr *EnvelopeStreamReader) readLine() ([]byte, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := sr.r.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(bytes.TrimRight(line, "\r\n")) > sr.maxLine {
+				tooLong, line = true, nil
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && (err != io.EOF || (len(line) == 0 && !tooLong && len(chunk) == 0)) {
+			return nil, err
+		}
+		break
+	}
+
+	sr.line++
+	if tooLong {
+		return nil, &LineError{Line: sr.line, Err: ErrLineTooLong}
+	}
+	return bytes.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,102 @@
+package dsse
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestEnvelopeStream(t *testing.T) {
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	var envs []*Envelope
+	for _, payload := range []string{"one", "two\nlines", strings.Repeat("x", 10000)} {
+		env, err := signer.SignPayload("http://example.com/HelloWorld", []byte(payload))
+		assert.Nil(t, err, "sign failed")
+		envs = append(envs, env)
+	}
+
+	var buf bytes.Buffer
+	w := NewEnvelopeStreamWriter(&buf)
+	for _, env := range envs {
+		assert.Nil(t, w.Write(env), "unexpected error")
+	}
+	assert.Equal(t, len(envs), strings.Count(buf.String(), "\n"), "wrong line count")
+	assert.ErrorIs(t, w.Write(nil), ErrNoEnvelopes, "wrong error")
+
+	t.Run("Round trip", func(t *testing.T) {
+		r := NewEnvelopeStreamReader(bytes.NewReader(buf.Bytes()))
+		for _, want := range envs {
+			got, err := r.Next()
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, want, got)
+		}
+		_, err := r.Next()
+		assert.Equal(t, io.EOF, err, "wrong error")
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err, "wrong error")
+	})
+
+	t.Run("Malformed lines", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		input := lines[0] + "\r\n" +
+			"\n" +
+			"not json\n" +
+			`{"payloadType":"","payload":"","signatures":[]}` + "\n" +
+			lines[1] + "\n" +
+			lines[2]
+
+		r := NewEnvelopeStreamReader(strings.NewReader(input))
+		r.SetMaxLineSize(1000)
+
+		got, err := r.Next()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, envs[0], got)
+
+		var lineErr *LineError
+		_, err = r.Next()
+		assert.True(t, errors.As(err, &lineErr), "wrong error")
+		assert.Equal(t, 3, lineErr.Line, "wrong line")
+
+		_, err = r.Next()
+		assert.True(t, errors.As(err, &lineErr), "wrong error")
+		assert.Equal(t, 4, lineErr.Line, "wrong line")
+		assert.ErrorIs(t, err, ErrInvalidEnvelope, "wrong error")
+
+		got, err = r.Next()
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, envs[1], got)
+
+		// The last line has no line ending and exceeds the maximum.
+		_, err = r.Next()
+		assert.True(t, errors.As(err, &lineErr), "wrong error")
+		assert.Equal(t, 6, lineErr.Line, "wrong line")
+		assert.ErrorIs(t, err, ErrLineTooLong, "wrong error")
+
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err, "wrong error")
+	})
+
+	t.Run("Read error", func(t *testing.T) {
+		r := NewEnvelopeStreamReader(failingReader{})
+		_, err := r.Next()
+		assert.NotNil(t, err, "expected error")
+		var lineErr *LineError
+		assert.False(t, errors.As(err, &lineErr), "wrong error")
+		_, err2 := r.Next()
+		assert.Equal(t, err, err2, "wrong error")
+	})
+}
@@ -0,0 +1,485 @@
+package dsse
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+/*
+Option configures the behavior of an envelope verifier or an EnvelopeSigner.
+Options are passed to NewEnvelopeVerifierWithOptions and
+NewEnvelopeSignerWithOptions. Options that only affect verification also apply
+to the Verify method of an EnvelopeSigner.
+*/
+type Option func(*options)
+
+type options struct {
+	perSignatureCallback func(keyID string, ok bool, err error)
+	maxPayloadSize       int64
+	maxSignatures        int
+	verificationTime     time.Time
+	clock                func() time.Time
+	unknownPayloadType   func(payloadType string)
+	acceptedPayloadTypes map[string]bool
+	payloadTypeAliases   map[string]string
+	allowedAlgorithms    map[string]bool
+	strictKeyIDs         bool
+	normalizeKeyIDs      bool
+	auditSink            AuditSink
+	signDebug            func(keyID string, pae []byte)
+	verifyDebug          func(keyID string, pae []byte)
+	base64               *base64.Encoding
+	batchConcurrency     int
+	logger               Logger
+	payloadTypeNormalize func(string) string
+	duplicateKeyIDs      DuplicateKeyIDPolicy
+	verifyAll            bool
+	embedKeys            EmbeddedKeyFormat
+	trustEmbeddedKeys    bool
+	payloadTypePattern   *regexp.Regexp
+	payloadTypeAnchored  *regexp.Regexp
+}
+
+func newOptions(opts ...Option) options {
+	var o options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+/*
+WithPerSignatureCallback registers a function that is called exactly once for
+each signature of an envelope passed to Verify, in the order of the
+signatures. keyID is the key ID of the accepting verifier if the signature
+was accepted, and the key ID recorded in the signature otherwise. err
+describes why a signature was not accepted. A signature with the key ID of an
+AggregateVerifier that accepted keys, or of one of the accepted keys, is
+reported as accepted with its own key ID; accepted keys without a signature
+are not reported. A signature that cannot be
+decoded, or whose algorithm is not allowed, rejects the envelope, but the
+other signatures are still verified and reported. The callback is not called
+at all if the envelope is rejected before its signatures are verified, for
+example because it exceeds WithMaxSignatures or its payload type is not
+accepted.
+*/
+func WithPerSignatureCallback(cb func(keyID string, ok bool, err error)) Option {
+	return func(o *options) {
+		o.perSignatureCallback = cb
+	}
+}
+
+func (o *options) reportSignature(keyID string, ok bool, err error) {
+	if o.perSignatureCallback != nil {
+		o.perSignatureCallback(keyID, ok, err)
+	}
+}
+
+/*
+WithMaxPayloadSize limits the size in bytes of the decoded payload of an
+envelope. Envelopes exceeding the limit are rejected with ErrPayloadTooLarge
+before the payload is decoded. A limit of zero or less means unlimited, which
+is the default.
+*/
+func WithMaxPayloadSize(n int64) Option {
+	return func(o *options) {
+		o.maxPayloadSize = n
+	}
+}
+
+func (o *options) checkPayloadSize(payload string) error {
+	size := b64DecodedLen(payload)
+	if o.base64 != nil {
+		size = int64(o.base64.DecodedLen(len(payload)))
+	}
+	if o.maxPayloadSize > 0 && size > o.maxPayloadSize {
+		return ErrPayloadTooLarge
+	}
+	return nil
+}
+
+/*
+WithMaxSignatures limits the number of signatures of an envelope. Envelopes
+with more signatures are rejected with ErrTooManySignatures before any
+signature is verified, so that untrusted input cannot force a large number of
+possibly remote verifications. A limit of zero or less means unlimited, which
+is the default.
+*/
+func WithMaxSignatures(n int) Option {
+	return func(o *options) {
+		o.maxSignatures = n
+	}
+}
+
+func (o *options) checkSignatureCount(n int) error {
+	if o.maxSignatures > 0 && n > o.maxSignatures {
+		return fmt.Errorf("%w: %d, at most %d allowed", ErrTooManySignatures, n, o.maxSignatures)
+	}
+	return nil
+}
+
+/*
+WithVerificationTime sets the time at which time dependent verifiers, such as
+ValidityWindowVerifier, consider the signatures to be verified. By default the
+current time is used.
+*/
+func WithVerificationTime(t time.Time) Option {
+	return func(o *options) {
+		o.verificationTime = t
+	}
+}
+
+/*
+WithClock sets the function used to obtain the current time during
+verification, which defaults to time.Now. The clock gives the verification
+time, unless WithVerificationTime is set, and the time of audit events. It
+is mainly useful to make time dependent behavior reproducible in tests.
+Signing does not depend on the time, so the option has no effect on an
+EnvelopeSigner other than on its Verify method.
+*/
+func WithClock(clock func() time.Time) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// now returns the time at which signatures are considered to be verified.
+func (o *options) now() time.Time {
+	if !o.verificationTime.IsZero() {
+		return o.verificationTime
+	}
+	if o.clock != nil {
+		return o.clock()
+	}
+	return time.Now()
+}
+
+/*
+WithUnknownPayloadTypeHook registers a function that an EnvelopeSigner calls
+before signing a payload whose type is not known to IsKnownPayloadType. It can
+be used to warn about mistyped payload types. Signing is not affected.
+*/
+func WithUnknownPayloadTypeHook(hook func(payloadType string)) Option {
+	return func(o *options) {
+		o.unknownPayloadType = hook
+	}
+}
+
+func (o *options) notePayloadType(payloadType string) {
+	if o.unknownPayloadType != nil && !IsKnownPayloadType(payloadType) {
+		o.unknownPayloadType(payloadType)
+	}
+}
+
+/*
+WithAcceptedPayloadTypes restricts verification to envelopes with one of the
+given payload types. Other envelopes are rejected with an error wrapping
+ErrPayloadTypeNotAccepted before any signature is verified. Payload types are
+compared in canonical form, see CanonicalPayloadType and
+WithPayloadTypeAliases, so accepting PayloadTypeInToto also accepts its
+historical spellings.
+*/
+func WithAcceptedPayloadTypes(payloadTypes ...string) Option {
+	return func(o *options) {
+		o.acceptedPayloadTypes = make(map[string]bool, len(payloadTypes))
+		for _, pt := range payloadTypes {
+			o.acceptedPayloadTypes[pt] = true
+		}
+	}
+}
+
+/*
+WithPayloadTypeAliases adds aliases, mapping payload types to the canonical
+payload type they are equivalent to, to the aliases known to
+CanonicalPayloadType. It affects WithAcceptedPayloadTypes.
+*/
+func WithPayloadTypeAliases(aliases map[string]string) Option {
+	return func(o *options) {
+		if o.payloadTypeAliases == nil {
+			o.payloadTypeAliases = make(map[string]string, len(aliases))
+		}
+		for alias, canonical := range aliases {
+			o.payloadTypeAliases[alias] = canonical
+		}
+	}
+}
+
+// checkPayloadType checks that payloadType is accepted.
+func (o *options) checkPayloadType(payloadType string) error {
+	if err := o.checkPayloadTypePattern(payloadType); err != nil {
+		return err
+	}
+	if o.acceptedPayloadTypes == nil {
+		return nil
+	}
+
+	canonical := canonicalPayloadType(payloadType, o.payloadTypeAliases)
+	for accepted := range o.acceptedPayloadTypes {
+		if canonicalPayloadType(accepted, o.payloadTypeAliases) == canonical {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrPayloadTypeNotAccepted, payloadType)
+}
+
+/*
+WithAllowedAlgorithms restricts verification to the given signature
+algorithms, named as by AlgorithmProvider. Verify fails with
+ErrDisallowedAlgorithm if a signature records an algorithm that is not
+allowed, and verifiers that name a disallowed algorithm do not accept any
+signature. Signatures and verifiers that do not name an algorithm are not
+affected. By default all algorithms are allowed.
+*/
+func WithAllowedAlgorithms(algs ...string) Option {
+	return func(o *options) {
+		o.allowedAlgorithms = make(map[string]bool, len(algs))
+		for _, alg := range algs {
+			o.allowedAlgorithms[alg] = true
+		}
+	}
+}
+
+// algorithmAllowed reports whether a verifier naming alg may be used.
+func (o *options) algorithmAllowed(alg string) bool {
+	return o.allowedAlgorithms == nil || alg == "" || o.allowedAlgorithms[alg]
+}
+
+// checkSignatureAlgorithm returns ErrDisallowedAlgorithm if s records an
+// algorithm that is not allowed.
+func (o *options) checkSignatureAlgorithm(s Signature) error {
+	if o.allowedAlgorithms == nil {
+		return nil
+	}
+
+	alg, ok, err := signatureAlgorithm(s)
+	if !ok {
+		return nil
+	}
+	if err != nil || !o.allowedAlgorithms[alg] {
+		return fmt.Errorf("%w: %s", ErrDisallowedAlgorithm, s.Extensions[ExtensionAlgorithm])
+	}
+
+	return nil
+}
+
+/*
+WithStrictKeyIDMatching offers a signature only to verifiers whose key ID
+equals the key ID recorded in the signature. By default, a signature or
+verifier without a key ID is offered to every verifier or signature
+respectively. The key ID of a verifier that does not report one is derived
+from its public key with SHA256KeyID.
+*/
+func WithStrictKeyIDMatching() Option {
+	return func(o *options) {
+		o.strictKeyIDs = true
+	}
+}
+
+/*
+WithNormalizedKeyIDs compares key IDs after removing surrounding whitespace
+and lowercasing hexadecimal key IDs, so that a signature made by a signer
+that reports its key ID as "ABCD" is offered to a verifier with key ID
+"abcd".
+*/
+func WithNormalizedKeyIDs() Option {
+	return func(o *options) {
+		o.normalizeKeyIDs = true
+	}
+}
+
+// keyID returns keyID in the form used for comparisons.
+func (o *options) keyID(keyID string) string {
+	if o.normalizeKeyIDs {
+		return normalizeKeyID(keyID)
+	}
+	return keyID
+}
+
+// keyIDsMatch reports whether a signature with key ID sigKeyID is offered to a
+// verifier with key ID keyID.
+func (o *options) keyIDsMatch(sigKeyID, keyID string) bool {
+	sigKeyID, keyID = o.keyID(sigKeyID), o.keyID(keyID)
+	if o.strictKeyIDs {
+		return sigKeyID != "" && sigKeyID == keyID
+	}
+	return sigKeyID == "" || keyID == "" || sigKeyID == keyID
+}
+
+/*
+WithSignDebug registers a function that an EnvelopeSigner calls with the key
+ID of each signer and the exact pre-authentication encoding handed to it,
+before it signs. Together with WithVerifyDebug it helps to find out why a
+signature does not verify, for example because producer and consumer
+disagree on the payload type. pae is nil if the payload was streamed and
+only its digest was signed. The hook must not modify pae.
+*/
+func WithSignDebug(hook func(keyID string, pae []byte)) Option {
+	return func(o *options) {
+		o.signDebug = hook
+	}
+}
+
+/*
+WithVerifyDebug registers a function that an envelope verifier calls with the
+key ID of each verifier and the exact pre-authentication encoding it checks a
+signature against, before it verifies. pae is nil if the payload was streamed
+and only its digest is verified. The hook must not modify pae.
+*/
+func WithVerifyDebug(hook func(keyID string, pae []byte)) Option {
+	return func(o *options) {
+		o.verifyDebug = hook
+	}
+}
+
+func (o *options) debugSign(keyID string, pae []byte) {
+	if o.signDebug != nil {
+		o.signDebug(keyID, pae)
+	}
+}
+
+func (o *options) debugVerify(keyID string, pae []byte) {
+	if o.verifyDebug != nil {
+		o.verifyDebug(keyID, pae)
+	}
+}
+
+/*
+WithBase64Encoding replaces the base64 encoding of payloads and signatures,
+for partner systems that deviate from the DSSE specification, for example by
+using a different alphabet or no padding. The encoding is used both to encode
+when signing and to decode when verifying, in place of the default of
+encoding with the standard alphabet and decoding either standard or URL safe
+base64; the PayloadEncoding field of envelopes is then ignored. Envelopes
+using a non-standard encoding cannot be verified by other DSSE
+implementations, so this option breaks interoperability and should only be
+used when the other side requires it.
+*/
+func WithBase64Encoding(enc *base64.Encoding) Option {
+	return func(o *options) {
+		o.base64 = enc
+	}
+}
+
+// encode base64 encodes b for an envelope.
+func (o *options) encode(b []byte) string {
+	if o.base64 != nil {
+		return o.base64.EncodeToString(b)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// decode decodes the base64 encoded signature or payload s.
+func (o *options) decode(s string) ([]byte, error) {
+	if o.base64 != nil {
+		return o.base64.DecodeString(s)
+	}
+
+	// As b64Decode, logging the fallback.
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err == nil {
+		return b, nil
+	}
+	o.debug("standard base64 decoding failed, trying URL safe alphabet", "error", err)
+	return base64.URLEncoding.DecodeString(s)
+}
+
+// decodePayload decodes the payload of e.
+func (o *options) decodePayload(e *Envelope) ([]byte, error) {
+	if o.base64 != nil {
+		return o.base64.DecodeString(e.Payload)
+	}
+	if e.PayloadEncoding == "" {
+		return o.decode(e.Payload)
+	}
+	return e.DecodedPayload()
+}
+
+/*
+WithBatchConcurrency makes VerifyBatch verify up to n envelopes at the same
+time. The verifiers, and any callbacks, hooks and audit sink passed as
+options, must then be safe for concurrent use. With n of one or less, the
+default, envelopes are verified one after the other.
+*/
+func WithBatchConcurrency(n int) Option {
+	return func(o *options) {
+		o.batchConcurrency = n
+	}
+}
+
+/*
+Logger receives the debug messages of WithLogger. The arguments are
+alternating keys and values. A *slog.Logger implements it.
+*/
+type Logger interface {
+	Debug(msg string, args ...interface{})
+}
+
+/*
+WithLogger logs the decisions taken while signing and verifying at debug
+level to l, such as falling back to URL safe base64 or skipping a verifier
+whose key ID does not match a signature, to help diagnose why an envelope
+does not verify. Nothing is logged by default.
+*/
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) debug(msg string, args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Debug(msg, args...)
+	}
+}
+
+/*
+WithPayloadTypeNormalization applies normalize to payload types before they
+are encoded in the pre-authentication encoding, when signing as well as when
+verifying. By default payload types are used exactly as given, as the DSSE
+specification requires: the payload type is part of the signed message, so
+"application/vnd.in-toto+json; version=0.1" and "application/vnd.in-toto+json"
+are different messages and a signature over one does not verify for the
+other. Normalization lets producers and consumers that disagree on such
+cosmetic differences agree on the signed bytes, at the cost of accepting
+envelopes that a verifier without the same normalization rejects.
+
+An EnvelopeSigner stores the normalized payload type in the envelopes it
+creates, so that they verify without normalization, except in
+AppendSignature, which keeps the payload type of the envelope. A verifier
+checks the normalized payload type against WithAcceptedPayloadTypes. normalize
+must be deterministic and is applied before ValidatePayloadType, so it may
+remove spaces.
+*/
+func WithPayloadTypeNormalization(normalize func(string) string) Option {
+	return func(o *options) {
+		o.payloadTypeNormalize = normalize
+	}
+}
+
+func (o *options) normalizePayloadType(payloadType string) string {
+	if o.payloadTypeNormalize != nil {
+		return o.payloadTypeNormalize(payloadType)
+	}
+	return payloadType
+}
+
+/*
+WithVerifyAll makes the verifier check every signature cryptographically, to
+detect envelopes that carry invalid signatures alongside valid ones. By
+default, once a key has accepted a signature, further signatures with its key
+ID are not checked and are reported as made by an unknown key. With
+WithVerifyAll they are verified as well: a valid one is counted once towards
+the threshold like the first, and an invalid one is reported with its
+verification error, through WithPerSignatureCallback and in the Rejected
+signatures of VerifyDetailed, even if the envelope is accepted.
+*/
+func WithVerifyAll() Option {
+	return func(o *options) {
+		o.verifyAll = true
+	}
+}
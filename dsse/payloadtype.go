@@ -0,0 +1,193 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidPayloadType indicates that a payload type contains spaces or
+// non-printable characters, or is not valid UTF-8.
+var ErrInvalidPayloadType = errors.New("invalid payload type")
+
+// ErrPayloadTypeNotAccepted indicates that the payload type of an envelope is
+// not among the accepted payload types.
+var ErrPayloadTypeNotAccepted = errors.New("payload type not accepted")
+
+// ErrPayloadTypeNotAllowed indicates that a signer refuses to sign a payload
+// type that differs from the one it requires.
+var ErrPayloadTypeNotAllowed = errors.New("payload type not allowed by signer")
+
+// Well-known payload types.
+const (
+	// PayloadTypeInToto is the payload type of in-toto statements.
+	PayloadTypeInToto = "application/vnd.in-toto+json"
+	// PayloadTypeSimpleSigning is the payload type of cosign's simple
+	// signing format.
+	PayloadTypeSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// PayloadTypeDSSE is the payload type of a serialized DSSE envelope.
+	PayloadTypeDSSE = "application/vnd.dsse.envelope.v1+json"
+)
+
+var knownPayloadTypes = map[string]bool{
+	PayloadTypeInToto:        true,
+	PayloadTypeSimpleSigning: true,
+	PayloadTypeDSSE:          true,
+}
+
+/*
+payloadTypeAliases maps historical spellings of well-known payload types to
+the canonical payload type. Early in-toto implementations used the statement
+type URI as payload type.
+*/
+var payloadTypeAliases = map[string]string{
+	"https://in-toto.io/Statement/v0.1": PayloadTypeInToto,
+	"https://in-toto.io/Statement/v1":   PayloadTypeInToto,
+}
+
+/*
+CanonicalPayloadType returns the canonical spelling of payloadType. Media
+types are compared case-insensitively, and the historical spellings of the
+well-known payload types, such as the in-toto statement type URIs, map to the
+well-known payload type. Other payload types are returned unchanged.
+*/
+func CanonicalPayloadType(payloadType string) string {
+	return canonicalPayloadType(payloadType, nil)
+}
+
+// canonicalPayloadType is CanonicalPayloadType with additional aliases.
+func canonicalPayloadType(payloadType string, aliases map[string]string) string {
+	if canonical, ok := aliases[payloadType]; ok {
+		return canonical
+	}
+	if canonical, ok := payloadTypeAliases[payloadType]; ok {
+		return canonical
+	}
+
+	// Media types, unlike URIs, are case-insensitive.
+	if !strings.Contains(payloadType, ":") {
+		lower := strings.ToLower(payloadType)
+		if knownPayloadTypes[lower] {
+			return lower
+		}
+	}
+
+	return payloadType
+}
+
+// IsKnownPayloadType reports whether payloadType is one of the well-known
+// payload types defined by this package.
+func IsKnownPayloadType(payloadType string) bool {
+	return knownPayloadTypes[payloadType]
+}
+
+/*
+ValidatePayloadType returns an error wrapping ErrInvalidPayloadType if
+payloadType is not valid UTF-8 or contains a space or a non-printable
+character. Such characters have no place in a media type or URI, and a
+payload type that contains the separators of the pre-authentication encoding
+is easily mistaken for a different framing by a careless parser.
+EnvelopeSigner validates payload types before signing.
+*/
+func ValidatePayloadType(payloadType string) error {
+	// Invalid bytes would otherwise be seen as U+FFFD, which is printable.
+	if !utf8.ValidString(payloadType) {
+		return fmt.Errorf("%w: invalid UTF-8", ErrInvalidPayloadType)
+	}
+	for i, r := range payloadType {
+		if r == ' ' || !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: character %q at byte %d", ErrInvalidPayloadType, r, i)
+		}
+	}
+
+	return nil
+}
+
+/*
+PayloadTyper is implemented by signers whose key may only sign one payload
+type, for example a KMS key provisioned to sign in-toto statements only.
+RequiredPayloadType returns that payload type, or false if the signer signs
+any payload type. EnvelopeSigner checks the payload type against every signer
+before signing and returns an error wrapping ErrPayloadTypeNotAllowed if it
+differs, so that the request is refused before it reaches the backend.
+Payload types are compared as by CanonicalPayloadType.
+*/
+type PayloadTyper interface {
+	RequiredPayloadType() (string, bool)
+}
+
+/*
+PayloadTypePatternError is returned when a payload type does not match the
+pattern given with WithPayloadTypePattern. It wraps ErrPayloadTypeNotAccepted.
+*/
+type PayloadTypePatternError struct {
+	PayloadType string
+	Pattern     string
+}
+
+func (e *PayloadTypePatternError) Error() string {
+	return fmt.Sprintf("%v: %q does not match %q", ErrPayloadTypeNotAccepted, e.PayloadType, e.Pattern)
+}
+
+func (e *PayloadTypePatternError) Unwrap() error {
+	return ErrPayloadTypeNotAccepted
+}
+
+/*
+WithPayloadTypePattern requires payload types to match re, for pipelines that
+only handle their own, possibly parameterized, media types. The whole payload
+type must match, as if re were anchored at both ends, and an empty payload
+type is rejected whatever re matches. An EnvelopeSigner refuses to sign other
+payload types before building the pre-authentication encoding, and a verifier
+rejects envelopes with other payload types, in both cases with a
+*PayloadTypePatternError. The payload type is checked after
+WithPayloadTypeNormalization is applied.
+*/
+func WithPayloadTypePattern(re *regexp.Regexp) Option {
+	return func(o *options) {
+		o.payloadTypePattern, o.payloadTypeAnchored = re, nil
+		if re != nil {
+			o.payloadTypeAnchored = regexp.MustCompile(`^(?:` + re.String() + `)$`)
+		}
+	}
+}
+
+// checkPayloadTypePattern checks payloadType against WithPayloadTypePattern.
+func (o *options) checkPayloadTypePattern(payloadType string) error {
+	if o.payloadTypeAnchored == nil {
+		return nil
+	}
+	if payloadType == "" || !o.payloadTypeAnchored.MatchString(payloadType) {
+		return &PayloadTypePatternError{PayloadType: payloadType, Pattern: o.payloadTypePattern.String()}
+	}
+
+	return nil
+}
+
+/*
+checkSignerPayloadType returns an error unless payloadType matches
+WithPayloadTypePattern and every signer that requires a payload type allows
+it.
+*/
+func (es *EnvelopeSigner) checkSignerPayloadType(payloadType string) error {
+	if err := es.opts.checkPayloadTypePattern(payloadType); err != nil {
+		return err
+	}
+
+	for _, signer := range es.providers {
+		pt, ok := signer.(PayloadTyper)
+		if !ok {
+			continue
+		}
+		required, ok := pt.RequiredPayloadType()
+		if !ok || CanonicalPayloadType(required) == CanonicalPayloadType(payloadType) {
+			continue
+		}
+		return fmt.Errorf("%w: %q, signer %q requires %q", ErrPayloadTypeNotAllowed, payloadType, verifierKeyID(signer), required)
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+package dsse
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKnownPayloadType(t *testing.T) {
+	assert.True(t, IsKnownPayloadType(PayloadTypeInToto), "in-toto not known")
+	assert.True(t, IsKnownPayloadType("application/vnd.in-toto+json"), "in-toto not known")
+	assert.True(t, IsKnownPayloadType(PayloadTypeSimpleSigning), "simple signing not known")
+	assert.False(t, IsKnownPayloadType("application/vnd.in-toto+jsn"), "typo known")
+	assert.False(t, IsKnownPayloadType(""), "empty type known")
+}
+
+func TestUnknownPayloadTypeHook(t *testing.T) {
+	var ns nilsigner
+	var unknown []string
+	signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{ns},
+		WithUnknownPayloadTypeHook(func(payloadType string) {
+			unknown = append(unknown, payloadType)
+		}))
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = signer.SignPayload(PayloadTypeInToto, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	assert.Empty(t, unknown, "hook called for known type")
+
+	env, err := signer.SignPayload("application/vnd.in-toto+jsn", []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	assert.NotNil(t, env, "signing was prevented")
+	assert.Equal(t, []string{"application/vnd.in-toto+jsn"}, unknown, "hook not called")
+}
+
+func TestValidatePayloadType(t *testing.T) {
+	valid := []string{"", PayloadTypeInToto, "http://example.com/HelloWorld", "application/ünicode"}
+	for _, payloadType := range valid {
+		assert.Nil(t, ValidatePayloadType(payloadType), "unexpected error")
+	}
+
+	invalid := []string{"text/plain 11 injected", "a\tb", "a\nb", "a\x00b", "a\u200bb", "a\xffb"}
+	for _, payloadType := range invalid {
+		assert.True(t, errors.Is(ValidatePayloadType(payloadType), ErrInvalidPayloadType), "wrong error")
+	}
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = signer.SignPayload("text/plain 11 injected", []byte("{}"))
+	assert.True(t, errors.Is(err, ErrInvalidPayloadType), "wrong error")
+	_, err = signer.SignEncodedPayload("a\nb", "e30=")
+	assert.True(t, errors.Is(err, ErrInvalidPayloadType), "wrong error")
+	_, err = signer.SignMultiPayload([]PayloadItem{{PayloadType: "a b", Payload: []byte("{}")}})
+	assert.True(t, errors.Is(err, ErrInvalidPayloadType), "wrong error")
+}
+
+func TestCanonicalPayloadType(t *testing.T) {
+	assert.Equal(t, PayloadTypeInToto, CanonicalPayloadType(PayloadTypeInToto), "wrong canonical type")
+	assert.Equal(t, PayloadTypeInToto, CanonicalPayloadType("https://in-toto.io/Statement/v0.1"), "wrong canonical type")
+	assert.Equal(t, PayloadTypeInToto, CanonicalPayloadType("Application/VND.in-toto+JSON"), "wrong canonical type")
+	assert.Equal(t, "http://example.com/HelloWorld", CanonicalPayloadType("http://example.com/HelloWorld"), "wrong canonical type")
+}
+
+func TestAcceptedPayloadTypes(t *testing.T) {
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	sign := func(payloadType string) *Envelope {
+		env, err := signer.SignPayload(payloadType, []byte("{}"))
+		assert.Nil(t, err, "sign failed")
+		return env
+	}
+
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns},
+		WithAcceptedPayloadTypes(PayloadTypeInToto),
+		WithPayloadTypeAliases(map[string]string{"application/x-legacy": PayloadTypeInToto}))
+	assert.Nil(t, err, "unexpected error")
+
+	for _, payloadType := range []string{PayloadTypeInToto, "https://in-toto.io/Statement/v0.1", "application/x-legacy"} {
+		_, err = ev.Verify(sign(payloadType))
+		assert.Nil(t, err, "unexpected error")
+	}
+
+	_, err = ev.Verify(sign(PayloadTypeSimpleSigning))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAccepted), "wrong error")
+
+	env := sign("http://example.com/HelloWorld")
+	_, err = ev.VerifyStream(env, strings.NewReader("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAccepted), "wrong error")
+}
+
+// typedSigner may only sign its required payload type.
+type typedSigner struct {
+	nilsigner
+	required string
+	calls    *int
+}
+
+func (s typedSigner) Sign(data []byte) ([]byte, error) {
+	*s.calls++
+	return s.nilsigner.Sign(data)
+}
+
+func (s typedSigner) RequiredPayloadType() (string, bool) {
+	return s.required, s.required != ""
+}
+
+func TestPayloadTyper(t *testing.T) {
+	var calls int
+	signer, err := NewEnvelopeSigner(typedSigner{required: PayloadTypeInToto, calls: &calls})
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = signer.SignPayload(PayloadTypeInToto, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	_, err = signer.SignPayload("https://in-toto.io/Statement/v1", []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, 2, calls, "wrong number of signing calls")
+
+	_, err = signer.SignPayload(PayloadTypeSimpleSigning, []byte("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAllowed), "wrong error")
+	_, err = signer.SignPayloadReader(PayloadTypeSimpleSigning, strings.NewReader("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAllowed), "wrong error")
+	_, err = signer.PrepareSigning(PayloadTypeSimpleSigning, []byte("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAllowed), "wrong error")
+	_, err = signer.SignMultiPayload([]PayloadItem{
+		{PayloadType: PayloadTypeInToto, Payload: []byte("{}")},
+		{PayloadType: PayloadTypeSimpleSigning, Payload: []byte("{}")},
+	})
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAllowed), "wrong error")
+	assert.Equal(t, 2, calls, "signer called for disallowed payload type")
+
+	var ns nilsigner
+	other, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	env, err := other.SignPayload(PayloadTypeSimpleSigning, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	_, err = signer.AppendSignature(env)
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAllowed), "wrong error")
+
+	t.Run("No requirement", func(t *testing.T) {
+		signer, err := NewEnvelopeSigner(typedSigner{calls: &calls})
+		assert.Nil(t, err, "unexpected error")
+		_, err = signer.SignPayload(PayloadTypeSimpleSigning, []byte("{}"))
+		assert.Nil(t, err, "sign failed")
+	})
+}
+
+func TestPayloadTypeNormalization(t *testing.T) {
+	normalize := func(payloadType string) string {
+		if i := strings.IndexByte(payloadType, ';'); i >= 0 {
+			payloadType = payloadType[:i]
+		}
+		return strings.ToLower(strings.TrimSpace(payloadType))
+	}
+	variant := "Application/vnd.in-toto+json; version=0.1"
+
+	var ns nilsigner
+	plain, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	normalizing, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{ns}, WithPayloadTypeNormalization(normalize))
+	assert.Nil(t, err, "unexpected error")
+
+	// By default the payload type is used as is.
+	_, err = plain.SignPayload(variant, []byte("{}"))
+	assert.True(t, errors.Is(err, ErrInvalidPayloadType), "wrong error")
+
+	env, err := normalizing.SignPayload(variant, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, PayloadTypeInToto, env.PayloadType, "payload type not normalized")
+	_, err = plain.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	// A cosmetic change of the payload type breaks the signature unless the
+	// verifier normalizes it as well.
+	env.PayloadType = variant
+	_, err = plain.Verify(env)
+	assert.NotNil(t, err, "expected error")
+	_, err = normalizing.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns},
+		WithPayloadTypeNormalization(normalize), WithAcceptedPayloadTypes(PayloadTypeInToto))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.VerifyStream(env, strings.NewReader("{}"))
+	assert.Nil(t, err, "unexpected error")
+
+	multi, err := normalizing.SignMultiPayload([]PayloadItem{{PayloadType: variant, Payload: []byte("{}")}})
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, PayloadTypeInToto, multi.Payloads[0].PayloadType, "payload type not normalized")
+	multi.Payloads[0].PayloadType = variant
+	_, err = ev.VerifyMultiPayload(multi)
+	assert.Nil(t, err, "unexpected error")
+}
+
+func TestPayloadTypePattern(t *testing.T) {
+	pattern := regexp.MustCompile(`application/vnd\.example\.[a-z]+\+json(;version=[0-9.]+)?`)
+
+	var calls int
+	signer, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{typedSigner{calls: &calls}}, WithPayloadTypePattern(pattern))
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = signer.SignPayload("application/vnd.example.build+json", []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	_, err = signer.SignPayload("application/vnd.example.build+json;version=1.2", []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, 2, calls, "wrong number of signing calls")
+
+	// The whole payload type must match.
+	for _, payloadType := range []string{PayloadTypeInToto, "x-application/vnd.example.build+json", "application/vnd.example.build+json-x"} {
+		_, err = signer.SignPayload(payloadType, []byte("{}"))
+		var perr *PayloadTypePatternError
+		assert.True(t, errors.As(err, &perr), "wrong error")
+		assert.Equal(t, payloadType, perr.PayloadType, "wrong payload type")
+		assert.True(t, errors.Is(err, ErrPayloadTypeNotAccepted), "wrong error")
+	}
+	_, err = signer.SignPayloadReader(PayloadTypeInToto, strings.NewReader("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAccepted), "wrong error")
+	_, err = signer.PrepareSigning(PayloadTypeInToto, []byte("{}"))
+	assert.True(t, errors.Is(err, ErrPayloadTypeNotAccepted), "wrong error")
+	assert.Equal(t, 2, calls, "signer called for rejected payload type")
+
+	var ns nilsigner
+	plain, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	env, err := plain.SignPayload(PayloadTypeInToto, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns}, WithPayloadTypePattern(pattern))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	var perr *PayloadTypePatternError
+	assert.True(t, errors.As(err, &perr), "wrong error")
+	_, err = ev.VerifyStream(&Envelope{PayloadType: "", Signatures: env.Signatures}, strings.NewReader("{}"))
+	assert.True(t, errors.As(err, &perr), "wrong error")
+
+	env, err = plain.SignPayload("application/vnd.example.build+json", []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	// An empty payload type is rejected even if the pattern matches it.
+	ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{ns}, WithPayloadTypePattern(regexp.MustCompile(`.*`)))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(&Envelope{PayloadType: "", Payload: env.Payload, Signatures: env.Signatures})
+	assert.True(t, errors.As(err, &perr), "wrong error")
+}
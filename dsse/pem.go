@@ -0,0 +1,74 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidPEMKey indicates that a PEM block does not hold a key in any of
+// the supported formats.
+var ErrInvalidPEMKey = errors.New("invalid pem key")
+
+/*
+ParsePrivateKeyPEM parses the first PEM block of data as a private key in
+PKCS #8, SEC 1 (EC) or PKCS #1 (RSA) form and returns it as a crypto.Signer:
+an ed25519.PrivateKey, *ecdsa.PrivateKey or *rsa.PrivateKey. The formats are
+tried in turn, whatever the type of the PEM block, so a key with a
+mislabeled block is accepted as well. If none applies, the error wraps
+ErrInvalidPEMKey and lists the error of each format. Encrypted PEM blocks are
+not supported.
+*/
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	pkcs8, err8 := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err8 == nil {
+		signer, ok := pkcs8.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pkcs8)
+		}
+		return signer, nil
+	}
+	ec, errEC := x509.ParseECPrivateKey(block.Bytes)
+	if errEC == nil {
+		return ec, nil
+	}
+	rsa, errRSA := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if errRSA == nil {
+		return rsa, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q block: tried PKCS #8 (%v), SEC 1 (%v), PKCS #1 (%v)",
+		ErrInvalidPEMKey, block.Type, err8, errEC, errRSA)
+}
+
+/*
+ParsePublicKeyPEM parses the first PEM block of data as a public key in PKIX
+(SubjectPublicKeyInfo) or PKCS #1 (RSA) form. The formats are tried in turn,
+and if neither applies, the error wraps ErrInvalidPEMKey and lists the error
+of each format.
+*/
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	pkix, errPKIX := x509.ParsePKIXPublicKey(block.Bytes)
+	if errPKIX == nil {
+		return pkix, nil
+	}
+	rsa, errRSA := x509.ParsePKCS1PublicKey(block.Bytes)
+	if errRSA == nil {
+		return rsa, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q block: tried PKIX (%v), PKCS #1 (%v)",
+		ErrInvalidPEMKey, block.Type, errPKIX, errRSA)
+}
@@ -0,0 +1,120 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	pkcs8 := func(key interface{}) []byte {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		assert.Nil(t, err, "unexpected error")
+		return der
+	}
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	assert.Nil(t, err, "unexpected error")
+
+	tests := []struct {
+		name  string
+		block string
+		der   []byte
+		key   interface{ Equal(crypto.PrivateKey) bool }
+	}{
+		{"ed25519 pkcs8", "PRIVATE KEY", pkcs8(edKey), edKey},
+		{"ecdsa pkcs8", "PRIVATE KEY", pkcs8(ecKey), ecKey},
+		{"ecdsa sec1", "EC PRIVATE KEY", sec1, ecKey},
+		{"rsa pkcs8", "PRIVATE KEY", pkcs8(rsaKey), rsaKey},
+		{"rsa pkcs1", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), rsaKey},
+		{"mislabeled sec1", "PRIVATE KEY", sec1, ecKey},
+		{"mislabeled pkcs1", "EC PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey), rsaKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := pem.EncodeToMemory(&pem.Block{Type: test.block, Bytes: test.der})
+			signer, err := ParsePrivateKeyPEM(data)
+			assert.Nil(t, err, "unexpected error")
+			// Compared with Equal, as parsing may precompute values
+			// differently.
+			assert.True(t, test.key.Equal(signer), "wrong key")
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")})
+		_, err := ParsePrivateKeyPEM(data)
+		assert.ErrorIs(t, err, ErrInvalidPEMKey, "wrong error")
+		assert.Contains(t, err.Error(), "PKCS #8")
+		assert.Contains(t, err.Error(), "SEC 1")
+		assert.Contains(t, err.Error(), "PKCS #1")
+	})
+
+	t.Run("no block", func(t *testing.T) {
+		_, err := ParsePrivateKeyPEM([]byte("not pem"))
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+func TestParsePublicKeyPEM(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	pkix := func(key interface{}) []byte {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		assert.Nil(t, err, "unexpected error")
+		return der
+	}
+
+	tests := []struct {
+		name  string
+		block string
+		der   []byte
+		key   interface{}
+	}{
+		{"ecdsa pkix", "PUBLIC KEY", pkix(&ecKey.PublicKey), &ecKey.PublicKey},
+		{"rsa pkix", "PUBLIC KEY", pkix(&rsaKey.PublicKey), &rsaKey.PublicKey},
+		{"rsa pkcs1", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), &rsaKey.PublicKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := pem.EncodeToMemory(&pem.Block{Type: test.block, Bytes: test.der})
+			public, err := ParsePublicKeyPEM(data)
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, test.key, public)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("garbage")})
+		_, err := ParsePublicKeyPEM(data)
+		assert.ErrorIs(t, err, ErrInvalidPEMKey, "wrong error")
+		assert.Contains(t, err.Error(), "PKIX")
+		assert.Contains(t, err.Error(), "PKCS #1")
+	})
+}
+
+func TestNewSignerByNameMislabeledKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	_, err = NewSignerByName(SignerRSAPSS, data)
+	assert.Nil(t, err, "unexpected error")
+}
@@ -0,0 +1,77 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+VerificationPolicy describes an approval policy for
+NewEnvelopeVerifierWithPolicy: the keys in Required must all have signed, and
+Threshold more signatures are needed from the other, optional, verifiers.
+*/
+type VerificationPolicy struct {
+	// Required lists the key IDs of the verifiers whose signatures are
+	// mandatory.
+	Required []string
+	// Threshold is the number of optional verifiers that must have signed.
+	Threshold int
+}
+
+/*
+NewEnvelopeVerifierWithPolicy creates an envelope verifier enforcing policy
+over the verifiers p, for example a release manager who must sign and
+automated scanners of which some must. Verify fails with an error wrapping
+ErrMissingSignature if a required key did not sign or its signature is
+invalid, whatever the number of optional signatures. Every required key ID
+must be the key ID of one of p, and the threshold may be zero if there are
+required keys.
+*/
+func NewEnvelopeVerifierWithPolicy(policy VerificationPolicy, p []Verifier, opts ...Option) (*envelopeVerifier, error) {
+	if policy.Threshold < 0 {
+		return nil, errors.New("Invalid threshold")
+	}
+
+	keyIDs := make([]string, len(p))
+	for i, v := range p {
+		keyIDs[i] = verifierKeyID(v)
+	}
+
+	var required []string
+	isRequired := make(map[string]bool)
+	for _, keyID := range policy.Required {
+		if isRequired[keyID] {
+			continue
+		}
+		found := false
+		for _, k := range keyIDs {
+			if k == keyID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+		}
+		isRequired[keyID] = true
+		required = append(required, keyID)
+	}
+
+	var optional []Verifier
+	for i, v := range p {
+		if !isRequired[keyIDs[i]] {
+			optional = append(optional, v)
+		}
+	}
+	if policy.Threshold > 0 && !validThreshold(policy.Threshold, optional) {
+		return nil, errors.New("Invalid threshold")
+	}
+
+	ev, err := NewEnvelopeVerifierWithOptions(len(required)+policy.Threshold, p, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ev.required = required
+
+	return ev, nil
+}
@@ -0,0 +1,86 @@
+package dsse
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPolicy(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	newSigner := func(keyID string) *Ed25519SignerVerifier {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+		sv, err := NewEd25519SignerVerifier(keyID, key)
+		assert.Nil(t, err, "unexpected error")
+		return sv
+	}
+	release, scanner1, scanner2 := newSigner("release"), newSigner("scanner1"), newSigner("scanner2")
+
+	ev, err := NewEnvelopeVerifierWithPolicy(VerificationPolicy{
+		Required:  []string{"release"},
+		Threshold: 1,
+	}, []Verifier{release, scanner1, scanner2})
+	assert.Nil(t, err, "unexpected error")
+
+	sign := func(signers ...SignVerifier) *Envelope {
+		es, err := NewEnvelopeSigner(signers...)
+		assert.Nil(t, err, "unexpected error")
+		env, err := es.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+		return env
+	}
+
+	t.Run("Policy met", func(t *testing.T) {
+		acceptedKeys, err := ev.Verify(sign(release, scanner2))
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2, "wrong number of keys")
+	})
+
+	t.Run("Required missing", func(t *testing.T) {
+		_, err := ev.Verify(sign(scanner1, scanner2))
+		assert.True(t, errors.Is(err, ErrMissingSignature), "wrong error")
+		assert.Contains(t, err.Error(), "KeyID=release", "missing key not reported")
+	})
+
+	t.Run("Required invalid", func(t *testing.T) {
+		env := sign(release, scanner1, scanner2)
+		for i := range env.Signatures {
+			if env.Signatures[i].KeyID == "release" {
+				env.Signatures[i].Sig = sign(scanner1).Signatures[0].Sig
+			}
+		}
+		_, err := ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrMissingSignature), "wrong error")
+	})
+
+	t.Run("Optional missing", func(t *testing.T) {
+		_, err := ev.Verify(sign(release))
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, 2, verr.Expected, "wrong threshold")
+	})
+
+	t.Run("Required only", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierWithPolicy(VerificationPolicy{Required: []string{"release"}}, []Verifier{release, scanner1})
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(sign(release))
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Invalid policy", func(t *testing.T) {
+		_, err := NewEnvelopeVerifierWithPolicy(VerificationPolicy{Required: []string{"unknown"}}, []Verifier{release})
+		assert.True(t, errors.Is(err, ErrUnknownKey), "wrong error")
+
+		_, err = NewEnvelopeVerifierWithPolicy(VerificationPolicy{Required: []string{"release"}, Threshold: 2}, []Verifier{release, scanner1})
+		assert.NotNil(t, err, "expected error")
+
+		_, err = NewEnvelopeVerifierWithPolicy(VerificationPolicy{}, []Verifier{release})
+		assert.NotNil(t, err, "expected error")
+	})
+}
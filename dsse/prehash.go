@@ -0,0 +1,83 @@
+package dsse
+
+import "crypto"
+
+/*
+PrehashSigner is implemented by signers that hash the message before signing
+it and can sign a digest computed by the caller, such as signers backed by a
+KMS or an HSM that only accept digests. An EnvelopeSigner computes the digest
+of the pre-authentication encoding once per hash and passes it to SignDigest
+instead of calling Sign.
+*/
+type PrehashSigner interface {
+	Signer
+	// HashFunc returns the hash applied to the message, or 0 if the
+	// message is signed as is, in which case Sign is used.
+	HashFunc() crypto.Hash
+	// SignDigest signs a digest computed with hash.
+	SignDigest(digest []byte, hash crypto.Hash) ([]byte, error)
+}
+
+/*
+PrehashVerifier is implemented by verifiers that hash the message before
+verifying the signature. Such verifiers can verify a message they are given
+as a digest, which lets the envelope verifier hash large payloads
+incrementally instead of holding them in memory.
+*/
+type PrehashVerifier interface {
+	Verifier
+	// HashFunc returns the hash applied to the message, or 0 if the
+	// message is verified as is, in which case Verify is used.
+	HashFunc() crypto.Hash
+	// VerifyDigest verifies sig over a digest computed with hash.
+	VerifyDigest(digest []byte, hash crypto.Hash, sig []byte) error
+}
+
+/*
+PAEDigest returns the digest of the pre-authentication encoding of payload
+computed with h, which is the digest a PrehashSigner using h signs. Among the
+bundled signers, ECDSASignerVerifier uses SHA-256 for P-256, SHA-384 for P-384
+and SHA-512 for P-521 unless WithECDSAHash selects another hash,
+RSAPSSSignerVerifier uses SHA-256 unless WithRSAPSSHash selects another hash,
+and Ed25519SignerVerifier uses SHA-512 with WithEd25519ph. Plain Ed25519 signs
+the encoding itself; see HashFunc of each signer. PAEDigest panics if h is not
+available.
+*/
+func PAEDigest(payloadType string, payload []byte, h crypto.Hash) []byte {
+	hasher := h.New()
+	hasher.Write(PAE(payloadType, payload))
+
+	return hasher.Sum(nil)
+}
+
+// digest returns the digest of the message computed with h, computing it
+// once from the full encoding if needed.
+func (m *message) digest(h crypto.Hash) ([]byte, bool) {
+	if d, ok := m.digests[h]; ok {
+		return d, true
+	}
+	if m.pae == nil || !h.Available() {
+		return nil, false
+	}
+
+	hasher := h.New()
+	hasher.Write(m.pae)
+	if m.digests == nil {
+		m.digests = make(map[crypto.Hash][]byte)
+	}
+	m.digests[h] = hasher.Sum(nil)
+
+	return m.digests[h], true
+}
+
+// prehashSigner returns signer as a PrehashSigner if it hashes the message.
+func prehashSigner(signer Signer) (PrehashSigner, bool) {
+	ps, ok := signer.(PrehashSigner)
+	return ps, ok && ps.HashFunc() != 0
+}
+
+// prehashVerifier returns v as a PrehashVerifier if it hashes the message.
+func prehashVerifier(v Verifier) (PrehashVerifier, bool) {
+	pv, ok := v.(PrehashVerifier)
+	return pv, ok && pv.HashFunc() != 0
+}
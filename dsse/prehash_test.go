@@ -0,0 +1,123 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// digestOnlySigner models a KMS signer that only accepts digests.
+type digestOnlySigner struct {
+	key     *ecdsa.PrivateKey
+	digests [][]byte
+}
+
+func (s *digestOnlySigner) Sign(data []byte) ([]byte, error) {
+	return nil, errors.New("digest required")
+}
+
+func (s *digestOnlySigner) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	s.digests = append(s.digests, digest)
+	return ecdsa.SignASN1(rand.Reader, s.key, digest)
+}
+
+func (s *digestOnlySigner) HashFunc() crypto.Hash {
+	return crypto.SHA256
+}
+
+func (s *digestOnlySigner) KeyID() (string, error) {
+	return "kms", nil
+}
+
+func (s *digestOnlySigner) Verify(data, sig []byte) error {
+	return ErrUnknownKey
+}
+
+func (s *digestOnlySigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func TestPrehashSigner(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+
+	kms := &digestOnlySigner{key: key}
+	signer, err := NewEnvelopeSigner(kms)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	digest := sha256.Sum256(PAE(payloadType, []byte(payload)))
+	assert.Equal(t, [][]byte{digest[:]}, kms.digests, "wrong digest")
+
+	v, err := NewECDSAVerifier("kms", &key.PublicKey, WithAllowHighS())
+	assert.Nil(t, err, "unexpected error")
+	ev, err := NewEnvelopeVerifier(v)
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+	sv, err := NewECDSASignerVerifier("", key)
+	assert.Nil(t, err, "unexpected error")
+	_, err = sv.SignDigest(digest[:], crypto.SHA384)
+	assert.True(t, errors.Is(err, ErrAlgorithmMismatch), "wrong error")
+	sig, err := sv.SignDigest(digest[:], crypto.SHA256)
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, sv.VerifyDigest(digest[:], crypto.SHA256, sig), "unexpected error")
+}
+
+func TestMessageDigest(t *testing.T) {
+	msg := &message{pae: []byte("hello world")}
+
+	d1, ok := msg.digest(crypto.SHA256)
+	assert.True(t, ok, "digest not computed")
+	want := sha256.Sum256([]byte("hello world"))
+	assert.Equal(t, want[:], d1, "wrong digest")
+
+	// The digest is computed once and then reused.
+	msg.pae[0] = 'H'
+	d2, ok := msg.digest(crypto.SHA256)
+	assert.True(t, ok, "digest not computed")
+	assert.Equal(t, d1, d2, "digest recomputed")
+
+	streamed := &message{}
+	_, ok = streamed.digest(crypto.SHA256)
+	assert.False(t, ok, "digest computed without message")
+}
+
+func TestPAEDigest(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	want := sha256.Sum256(PAE(payloadType, payload))
+	assert.Equal(t, want[:], PAEDigest(payloadType, payload, crypto.SHA256), "wrong digest")
+
+	key := newEcdsaKey()
+	sv, err := NewECDSASignerVerifier("", key)
+	assert.Nil(t, err, "unexpected error")
+	sig, err := sv.Sign(PAE(payloadType, payload))
+	assert.Nil(t, err, "sign failed")
+	digest := PAEDigest(payloadType, payload, sv.HashFunc())
+	assert.True(t, ecdsa.VerifyASN1(&key.PublicKey, digest, sig), "signature not over digest")
+
+	ph, err := NewEd25519SignerVerifier("", newEd25519Key(), WithEd25519ph())
+	assert.Nil(t, err, "unexpected error")
+	sig, err = ph.Sign(PAE(payloadType, payload))
+	assert.Nil(t, err, "sign failed")
+	digest = PAEDigest(payloadType, payload, ph.HashFunc())
+	err = ed25519.VerifyWithOptions(ph.Public().(ed25519.PublicKey), digest, sig, &ed25519.Options{Hash: crypto.SHA512})
+	assert.Nil(t, err, "signature not over digest")
+}
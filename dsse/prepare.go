@@ -0,0 +1,88 @@
+package dsse
+
+import "fmt"
+
+/*
+PreparedEnvelope is an envelope awaiting a signature produced outside of the
+EnvelopeSigner, for example by an offline machine in a signing ceremony or by
+an HSM. It is created by EnvelopeSigner.PrepareSigning.
+*/
+type PreparedEnvelope struct {
+	payloadType string
+	payload     []byte
+	pae         []byte
+	es          *EnvelopeSigner
+}
+
+/*
+PrepareSigning validates the payload type and computes the pre-authentication
+encoding of payload, so that it can be signed elsewhere. The signature is
+added with FinalizeSigning. The signers of es need not have private keys;
+verifier-only instances such as those created by NewECDSAVerifier are used to
+check the signatures passed to FinalizeSigning.
+*/
+func (es *EnvelopeSigner) PrepareSigning(payloadType string, payload []byte) (*PreparedEnvelope, error) {
+	payloadType = es.opts.normalizePayloadType(payloadType)
+	if err := ValidatePayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	if err := es.checkSignerPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	es.opts.notePayloadType(payloadType)
+
+	return &PreparedEnvelope{
+		payloadType: payloadType,
+		payload:     payload,
+		pae:         PAE(payloadType, payload),
+		es:          es,
+	}, nil
+}
+
+/*
+PAE returns the pre-authentication encoding to be signed. Signers that hash
+the message before signing may sign its digest instead, see PAEDigest.
+*/
+func (p *PreparedEnvelope) PAE() []byte {
+	return p.pae
+}
+
+/*
+FinalizeSigning returns the envelope signed with sig, a signature over PAE
+made with the key of the signer with key ID keyID. The signature is verified
+with that signer before it is accepted; if no signer has the key ID, an error
+wrapping ErrUnknownKey is returned. HSMs and KMSs generally do not normalize
+ECDSA signatures, so a high-S signature for an ECDSASignerVerifier is
+converted to low-S form before it is verified and added. Envelopes finalized
+with signatures of different keys can be combined with MergeEnvelopes.
+*/
+func (p *PreparedEnvelope) FinalizeSigning(keyID string, sig []byte) (*Envelope, error) {
+	for _, sv := range p.es.providers {
+		if verifierKeyID(sv) != keyID {
+			continue
+		}
+
+		if ecv, ok := sv.(*ECDSASignerVerifier); ok {
+			sig = ecv.lowS(sig)
+		}
+		if err := p.es.ev.verify(sv, &message{pae: p.pae}, sig); err != nil {
+			return nil, err
+		}
+		extensions, err := p.es.opts.signatureExtensions(sv)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Envelope{
+			PayloadType: p.payloadType,
+			Payload:     p.es.opts.encode(p.payload),
+			Signatures: []Signature{{
+				KeyID:      keyID,
+				Sig:        p.es.opts.encode(sig),
+				Extensions: extensions,
+			}},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+}
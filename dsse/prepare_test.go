@@ -0,0 +1,80 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareSigning(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	// The offline key; the online machine only holds the public key.
+	key := newEcdsaKey()
+	v, err := NewECDSAVerifier("offline", &key.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	es, err := NewEnvelopeSigner(v)
+	assert.Nil(t, err, "unexpected error")
+
+	prepared, err := es.PrepareSigning(payloadType, payload)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, PAE(payloadType, payload), prepared.PAE(), "wrong pae")
+
+	digest := sha256.Sum256(prepared.PAE())
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.Nil(t, err, "sign failed")
+
+	env, err := prepared.FinalizeSigning("offline", sig)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, payloadType, env.PayloadType, "wrong payload type")
+	assert.Len(t, env.Signatures, 1, "wrong number of signatures")
+
+	acceptedKeys, err := es.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "offline", acceptedKeys[0].KeyID, "wrong keyid")
+
+	t.Run("High-S signature", func(t *testing.T) {
+		r, s, err := ecdsaParseDER(sig)
+		assert.Nil(t, err, "unexpected error")
+		if !ecdsaIsHighS(elliptic.P256(), s) {
+			s = new(big.Int).Sub(elliptic.P256().Params().N, s)
+		}
+		high, err := ecdsaMarshalDER(r, s)
+		assert.Nil(t, err, "unexpected error")
+
+		env, err := prepared.FinalizeSigning("offline", high)
+		assert.Nil(t, err, "unexpected error")
+		low, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+		assert.Nil(t, err, "unexpected error")
+		_, s, err = ecdsaParseDER(low)
+		assert.Nil(t, err, "unexpected error")
+		assert.False(t, ecdsaIsHighS(elliptic.P256(), s), "signature not normalized")
+		_, err = es.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Wrong signature", func(t *testing.T) {
+		other, err := es.PrepareSigning(payloadType, []byte("other"))
+		assert.Nil(t, err, "unexpected error")
+		_, err = other.FinalizeSigning("offline", sig)
+		assert.Equal(t, ErrSignatureInvalid, err, "wrong error")
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		_, err := prepared.FinalizeSigning("unknown", sig)
+		assert.True(t, errors.Is(err, ErrUnknownKey), "wrong error")
+	})
+
+	t.Run("Invalid payload type", func(t *testing.T) {
+		_, err := es.PrepareSigning("bad type", payload)
+		assert.True(t, errors.Is(err, ErrInvalidPayloadType), "wrong error")
+	})
+}
@@ -0,0 +1,118 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownSigner indicates that no signer is registered under a name.
+var ErrUnknownSigner = errors.New("unknown signer")
+
+// ErrNoPrivateKey indicates that a signer was created without a private key.
+var ErrNoPrivateKey = errors.New("no private key")
+
+// Names of the signers registered by this package.
+const (
+	SignerEd25519 = "ed25519"
+	SignerECDSA   = "ecdsa"
+	SignerRSAPSS  = "rsa-pss"
+)
+
+/*
+SignerFactory creates a SignVerifier from key material. The format of the key
+is defined by the factory; the factories registered by this package expect a
+PEM encoded private key in any of the forms accepted by ParsePrivateKeyPEM.
+*/
+type SignerFactory func(key []byte) (SignVerifier, error)
+
+var (
+	signersMu sync.RWMutex
+	signers   = map[string]SignerFactory{}
+)
+
+func init() {
+	RegisterSigner(SignerEd25519, newEd25519FromPEM)
+	RegisterSigner(SignerECDSA, newECDSAFromPEM)
+	RegisterSigner(SignerRSAPSS, newRSAPSSFromPEM)
+}
+
+/*
+RegisterSigner makes a signer factory available under name for
+NewSignerByName. If RegisterSigner is called twice with the same name or if
+factory is nil, it panics.
+*/
+func RegisterSigner(name string, factory SignerFactory) {
+	signersMu.Lock()
+	defer signersMu.Unlock()
+
+	if factory == nil {
+		panic("dsse: RegisterSigner factory is nil")
+	}
+	if _, dup := signers[name]; dup {
+		panic("dsse: RegisterSigner called twice for signer " + name)
+	}
+	signers[name] = factory
+}
+
+/*
+NewSignerByName creates a SignVerifier from key using the factory registered
+under name. If no factory is registered, an error wrapping ErrUnknownSigner
+is returned.
+*/
+func NewSignerByName(name string, key []byte) (SignVerifier, error) {
+	signersMu.RLock()
+	factory, ok := signers[name]
+	signersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigner, name)
+	}
+
+	return factory(key)
+}
+
+func newEd25519FromPEM(key []byte) (SignVerifier, error) {
+	private, err := ParsePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := private.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected ed25519 key, got %T", private)
+	}
+
+	return NewEd25519SignerVerifier("", k)
+}
+
+func newECDSAFromPEM(key []byte) (SignVerifier, error) {
+	private, err := ParsePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := private.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected ecdsa key, got %T", private)
+	}
+
+	return NewECDSASignerVerifier("", k)
+}
+
+func newRSAPSSFromPEM(key []byte) (SignVerifier, error) {
+	private, err := ParsePrivateKeyPEM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := private.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected rsa key, got %T", private)
+	}
+
+	return NewRSAPSSSignerVerifier("", k)
+}
@@ -0,0 +1,80 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pkcs8PEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.Nil(t, err, "unexpected error")
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestNewSignerByName(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	tests := []struct {
+		name string
+		key  interface{}
+		want interface{}
+	}{
+		{SignerEd25519, newEd25519Key(), &Ed25519SignerVerifier{}},
+		{SignerECDSA, ecdsaKey, &ECDSASignerVerifier{}},
+		{SignerRSAPSS, rsaKey, &RSAPSSSignerVerifier{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sv, err := NewSignerByName(test.name, pkcs8PEM(t, test.key))
+			assert.Nil(t, err, "unexpected error")
+			assert.IsType(t, test.want, sv, "wrong signer")
+		})
+	}
+
+	t.Run("Wrong key type", func(t *testing.T) {
+		_, err := NewSignerByName(SignerEd25519, pkcs8PEM(t, ecdsaKey))
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Not PEM", func(t *testing.T) {
+		_, err := NewSignerByName(SignerEd25519, []byte("not pem"))
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := NewSignerByName("unknown", nil)
+		assert.True(t, errors.Is(err, ErrUnknownSigner), "wrong error")
+	})
+}
+
+func TestRegisterSigner(t *testing.T) {
+	var ns nilsigner
+	RegisterSigner("test-nil", func(key []byte) (SignVerifier, error) {
+		return ns, nil
+	})
+
+	sv, err := NewSignerByName("test-nil", nil)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, ns, sv, "wrong signer")
+
+	assert.Panics(t, func() {
+		RegisterSigner("test-nil", func(key []byte) (SignVerifier, error) {
+			return ns, nil
+		})
+	}, "duplicate registration")
+	assert.Panics(t, func() {
+		RegisterSigner("test-nil-factory", nil)
+	}, "nil factory")
+}
@@ -0,0 +1,98 @@
+package dsse
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+NewEnvelopeVerifierFunc returns a verifier that fetches the verifiers on
+demand instead of holding them, for trust stores too large to load up front,
+such as a database or a remote key service. For every envelope, resolve is
+called once with each distinct key ID among its signatures, including the
+empty key ID of signatures without one, and the envelope is verified with the
+verifiers it returns, with a threshold of one. resolve returns a nil Verifier
+for a key ID it does not know; an error aborts the verification and is
+returned wrapped. Caching is left to resolve, which may be called
+concurrently, for example by VerifyBatch. Since the verifiers are not known
+in advance, Keys and KeyIDs return nothing.
+*/
+func NewEnvelopeVerifierFunc(resolve func(keyID string) (Verifier, error)) (*envelopeVerifier, error) {
+	return NewEnvelopeVerifierFuncWithOptions(1, resolve)
+}
+
+/*
+NewEnvelopeVerifierFuncWithOptions is like NewEnvelopeVerifierFunc, but
+requires threshold distinct keys to accept an envelope, and takes options
+like NewEnvelopeVerifierWithOptions.
+*/
+func NewEnvelopeVerifierFuncWithOptions(threshold int, resolve func(keyID string) (Verifier, error), opts ...Option) (*envelopeVerifier, error) {
+	if resolve == nil {
+		return nil, errors.New("nil resolver")
+	}
+	if threshold <= 0 {
+		return nil, errors.New("Invalid threshold")
+	}
+
+	return &envelopeVerifier{
+		index:     make(map[string][]int),
+		threshold: threshold,
+		resolve:   resolve,
+		opts:      newOptions(opts...),
+	}, nil
+}
+
+/*
+forSignatures returns the verifier to check the signatures with. It is ev,
+unless ev fetches its verifiers with resolve or trusts embedded keys: then it
+is a copy of ev holding the verifiers that resolve returns for the key IDs of
+the signatures, or those of ev, and the verifiers for the embedded keys.
+*/
+func (ev *envelopeVerifier) forSignatures(signatures []Signature) (*envelopeVerifier, error) {
+	if ev.lazy || (ev.resolve == nil && !ev.opts.trustEmbeddedKeys) {
+		return ev, nil
+	}
+
+	rv := *ev
+	rv.resolve = nil
+	rv.lazy = true
+	rv.providers, rv.keyIDs, rv.all = nil, nil, nil
+	rv.index = make(map[string][]int)
+
+	if ev.resolve == nil {
+		for i, v := range ev.providers {
+			rv.addProvider(v, ev.keyIDs[i])
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range signatures {
+		if ev.resolve == nil || seen[s.KeyID] {
+			continue
+		}
+		seen[s.KeyID] = true
+
+		v, err := ev.resolve(s.KeyID)
+		if err != nil {
+			return nil, fmt.Errorf("resolving key ID %q: %w", s.KeyID, err)
+		}
+		if v != nil {
+			rv.addProvider(v, verifierKeyID(v))
+		}
+	}
+
+	if ev.opts.trustEmbeddedKeys {
+		rv.addEmbeddedKeys(signatures)
+	}
+
+	return &rv, nil
+}
+
+// addProvider adds the verifier v with key ID keyID to ev.
+func (ev *envelopeVerifier) addProvider(v Verifier, keyID string) {
+	i := len(ev.providers)
+	ev.providers = append(ev.providers, v)
+	ev.keyIDs = append(ev.keyIDs, keyID)
+	ev.index[ev.opts.keyID(keyID)] = append(ev.index[ev.opts.keyID(keyID)], i)
+	ev.all = append(ev.all, i)
+}
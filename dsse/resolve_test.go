@@ -0,0 +1,107 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEnvelopeVerifierFunc(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	ecdsaSV, err := NewECDSASignerVerifier("ecdsa", newEcdsaKey())
+	assert.Nil(t, err, "unexpected error")
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	otherSV, err := NewEd25519SignerVerifier("other", other)
+	assert.Nil(t, err, "unexpected error")
+	unknownSV, err := NewEd25519SignerVerifier("unknown", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	store := map[string]Verifier{"ecdsa": ecdsaSV, "other": otherSV}
+	var calls []string
+	resolve := func(keyID string) (Verifier, error) {
+		calls = append(calls, keyID)
+		if keyID == "broken" {
+			return nil, ErrVerifierUnavailable
+		}
+		return store[keyID], nil
+	}
+
+	signer, err := NewMultiEnvelopeSigner(2, ecdsaSV, otherSV, unknownSV)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	env.Signatures = append(env.Signatures, env.Signatures[0])
+
+	t.Run("Resolved", func(t *testing.T) {
+		calls = nil
+		ev, err := NewEnvelopeVerifierFunc(resolve)
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2)
+		assert.Equal(t, []string{"ecdsa", "other", "unknown"}, calls, "wrong resolved key IDs")
+		assert.Empty(t, ev.KeyIDs())
+	})
+
+	t.Run("Threshold", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierFuncWithOptions(2, resolve)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+
+		ev, err = NewEnvelopeVerifierFuncWithOptions(3, resolve)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, 2, verr.Found)
+	})
+
+	t.Run("Unknown key", func(t *testing.T) {
+		unsigned, err := NewEnvelopeSigner(unknownSV)
+		assert.Nil(t, err, "unexpected error")
+		env, err := unsigned.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierFunc(resolve)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.ErrorIs(t, err, ErrNoMatchingKey, "wrong error")
+	})
+
+	t.Run("Resolver error", func(t *testing.T) {
+		broken := env.Clone()
+		broken.Signatures[0].KeyID = "broken"
+		ev, err := NewEnvelopeVerifierFunc(resolve)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(broken)
+		assert.ErrorIs(t, err, ErrVerifierUnavailable, "wrong error")
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		streamer, err := NewEnvelopeSigner(ecdsaSV)
+		assert.Nil(t, err, "unexpected error")
+		detached, err := streamer.SignPayloadReader(payloadType, bytes.NewReader(payload))
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierFunc(resolve)
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.VerifyStream(detached, bytes.NewReader(payload))
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 1)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := NewEnvelopeVerifierFunc(nil)
+		assert.NotNil(t, err, "expected error")
+		_, err = NewEnvelopeVerifierFuncWithOptions(0, resolve)
+		assert.NotNil(t, err, "expected error")
+	})
+}
@@ -0,0 +1,104 @@
+package dsse
+
+import (
+	"crypto/x509"
+	"time"
+)
+
+/*
+VerificationResult describes the outcome of a verification in detail, for
+policy engines that need more than whether the envelope was accepted.
+*/
+type VerificationResult struct {
+	// Signatures is the number of signatures of the envelope.
+	Signatures int
+	// Threshold is the number of keys that must accept the envelope.
+	Threshold int
+	// ThresholdMet reports whether enough keys accepted the envelope.
+	ThresholdMet bool
+	// Accepted lists the keys that accepted a signature, each counted once.
+	Accepted []AcceptedKey
+	// Rejected lists the signatures that no key accepted, with the reason.
+	Rejected []SignatureDecision
+}
+
+// AcceptedKeyIDs returns the key IDs of the accepted keys.
+func (r *VerificationResult) AcceptedKeyIDs() []string {
+	keyIDs := make([]string, 0, len(r.Accepted))
+	for _, k := range r.Accepted {
+		keyIDs = append(keyIDs, k.KeyID)
+	}
+
+	return keyIDs
+}
+
+/*
+SigningTimeRange returns the earliest and the latest signing time of the
+accepted signatures, as returned by Signature.VerifySigningTime with roots,
+for example to reject attestations older than a freshness threshold. Only
+RFC 3161 timestamps of authorities chaining to roots are used; the unsigned
+ExtensionSignedAt extension, which anyone relaying the envelope can rewrite,
+is ignored, and so are signatures of embedded keys, see
+WithTrustEmbeddedKeys. Accepted signatures without a valid timestamp are
+ignored; ok is false if no accepted signature has one.
+*/
+func (r *VerificationResult) SigningTimeRange(roots *x509.CertPool) (earliest, latest time.Time, ok bool) {
+	for _, k := range r.Accepted {
+		if k.Embedded {
+			continue
+		}
+		t, err := k.Sig.VerifySigningTime(roots)
+		if err != nil {
+			continue
+		}
+		if !ok || t.Before(earliest) {
+			earliest = t
+		}
+		if !ok || t.After(latest) {
+			latest = t
+		}
+		ok = true
+	}
+
+	return earliest, latest, ok
+}
+
+/*
+VerifyDetailed verifies e like Verify, and describes the outcome in a
+VerificationResult. The result is returned whenever the signatures were
+examined, along with the error Verify returns, if any, so that it also
+describes envelopes that too few keys accepted. It is nil if the envelope was
+rejected before its signatures were examined, for example because it has no
+signatures or its payload cannot be decoded.
+*/
+func (ev *envelopeVerifier) VerifyDetailed(e *Envelope) (*VerificationResult, error) {
+	var decisions []SignatureDecision
+	rv := ev.recording(func(d SignatureDecision) {
+		decisions = append(decisions, d)
+	})
+
+	acceptedKeys, err := rv.Verify(e)
+	if len(decisions) == 0 {
+		return nil, err
+	}
+
+	result := &VerificationResult{
+		Signatures:   len(e.Signatures),
+		Threshold:    ev.threshold,
+		ThresholdMet: err == nil,
+		Accepted:     acceptedKeys,
+	}
+	for _, d := range decisions {
+		if !d.Accepted {
+			result.Rejected = append(result.Rejected, d)
+		}
+	}
+
+	return result, err
+}
+
+// VerifyDetailed verifies e and describes the outcome in a
+// VerificationResult. See the VerifyDetailed method of the envelope verifier.
+func (es *EnvelopeSigner) VerifyDetailed(e *Envelope) (*VerificationResult, error) {
+	return es.ev.VerifyDetailed(e)
+}
@@ -0,0 +1,111 @@
+package dsse
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyDetailed(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	ed, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	ec, err := NewECDSASignerVerifier("ec", newEcdsaKey())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewMultiEnvelopeSigner(2, ed, ec)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	result, err := signer.VerifyDetailed(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 2, result.Signatures, "wrong signature count")
+	assert.Equal(t, 2, result.Threshold, "wrong threshold")
+	assert.True(t, result.ThresholdMet, "threshold not met")
+	assert.Equal(t, []string{"ed", "ec"}, result.AcceptedKeyIDs(), "wrong accepted keys")
+	assert.Empty(t, result.Rejected, "unexpected rejections")
+
+	t.Run("Threshold not met", func(t *testing.T) {
+		tampered := *env
+		tampered.Signatures = []Signature{env.Signatures[0], env.Signatures[1]}
+		tampered.Signatures[1].Sig = env.Signatures[0].Sig
+
+		result, err := signer.VerifyDetailed(&tampered)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.False(t, result.ThresholdMet, "threshold met")
+		assert.Equal(t, []string{"ed"}, result.AcceptedKeyIDs(), "wrong accepted keys")
+		assert.Len(t, result.Rejected, 1, "wrong rejections")
+		assert.Equal(t, "ec", result.Rejected[0].KeyID, "wrong rejected key")
+		assert.True(t, errors.Is(result.Rejected[0].Err, ErrSignatureInvalid), "wrong reason")
+	})
+
+	t.Run("No signatures", func(t *testing.T) {
+		result, err := signer.VerifyDetailed(&Envelope{PayloadType: payloadType})
+		assert.Equal(t, ErrNoSignature, err, "wrong error")
+		assert.Nil(t, result, "unexpected result")
+	})
+}
+
+func TestSigningTimeRange(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	tsa := newTestTSA(t)
+
+	ed, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	ec, err := NewECDSASignerVerifier("ec", newEcdsaKey())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewMultiEnvelopeSigner(2, ed, ec)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	result, err := signer.VerifyDetailed(env)
+	assert.Nil(t, err, "unexpected error")
+	_, _, ok := result.SigningTimeRange(tsa.roots)
+	assert.False(t, ok, "unexpected signing times")
+
+	timestamped := func(s Signature, tsa *testTSA, genTime time.Time) Signature {
+		sig, err := b64Decode(s.Sig)
+		assert.Nil(t, err, "unexpected error")
+		s.Extensions = map[string]json.RawMessage{ExtensionTimestamp: newTimeStampToken(t, tsa, sig, genTime)}
+		return s
+	}
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	env.Signatures = []Signature{
+		timestamped(env.Signatures[0], tsa, last),
+		timestamped(env.Signatures[1], tsa, first),
+		// Rejected signatures do not count.
+		timestamped(Signature{KeyID: "ed", Sig: env.Signatures[1].Sig}, tsa, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	result, err = signer.VerifyDetailed(env)
+	assert.Nil(t, err, "unexpected error")
+	earliest, latest, ok := result.SigningTimeRange(tsa.roots)
+	assert.True(t, ok, "no signing times")
+	assert.Equal(t, first, earliest.UTC(), "wrong earliest time")
+	assert.Equal(t, last, latest.UTC(), "wrong latest time")
+
+	// Unverified times are ignored: the claimed signing time, and tokens
+	// without a valid signature of a trusted authority.
+	env.Signatures[1].Extensions = map[string]json.RawMessage{ExtensionSignedAt: json.RawMessage(`"2000-01-01T00:00:00Z"`)}
+	result, err = signer.VerifyDetailed(env)
+	assert.Nil(t, err, "unexpected error")
+	earliest, latest, ok = result.SigningTimeRange(tsa.roots)
+	assert.True(t, ok, "no signing times")
+	assert.Equal(t, last, earliest.UTC(), "unverified signing time used")
+	assert.Equal(t, earliest, latest, "unverified signing time used")
+
+	env.Signatures[1] = timestamped(env.Signatures[1], nil, first)
+	result, err = signer.VerifyDetailed(env)
+	assert.Nil(t, err, "unexpected error")
+	earliest, latest, ok = result.SigningTimeRange(tsa.roots)
+	assert.True(t, ok, "no signing times")
+	assert.Equal(t, earliest, latest, "unsigned timestamp used")
+}
@@ -0,0 +1,152 @@
+package dsse
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+)
+
+/*
+RSAPSSSignerVerifier is a SignVerifier using RSASSA-PSS with a salt length
+equal to the hash length. The message is hashed with SHA-256 unless another
+hash is selected with WithRSAPSSHash; the signer and the verifier must use
+the same hash. A verifier-only instance, created with
+NewRSAPSSVerifier, returns ErrNoPrivateKey from Sign.
+*/
+type RSAPSSSignerVerifier struct {
+	keyID   string
+	private *rsa.PrivateKey
+	public  *rsa.PublicKey
+	hash    crypto.Hash
+}
+
+// RSAPSSOption configures an RSAPSSSignerVerifier.
+type RSAPSSOption func(*RSAPSSSignerVerifier)
+
+// WithRSAPSSHash sets the hash applied to the message. It must be SHA-256,
+// SHA-384 or SHA-512.
+func WithRSAPSSHash(hash crypto.Hash) RSAPSSOption {
+	return func(sv *RSAPSSSignerVerifier) {
+		sv.hash = hash
+	}
+}
+
+/*
+NewRSAPSSSignerVerifier creates an RSAPSSSignerVerifier from a private key.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewRSAPSSSignerVerifier(keyID string, private *rsa.PrivateKey, opts ...RSAPSSOption) (*RSAPSSSignerVerifier, error) {
+	if private == nil {
+		return nil, errors.New("missing rsa private key")
+	}
+
+	sv, err := NewRSAPSSVerifier(keyID, &private.PublicKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	sv.private = private
+
+	return sv, nil
+}
+
+/*
+NewRSAPSSVerifier creates an RSAPSSSignerVerifier that can only verify.
+If keyID is empty, the key ID is derived from the public key with
+SHA256KeyID.
+*/
+func NewRSAPSSVerifier(keyID string, public *rsa.PublicKey, opts ...RSAPSSOption) (*RSAPSSSignerVerifier, error) {
+	if public == nil {
+		return nil, errors.New("missing rsa public key")
+	}
+
+	if keyID == "" {
+		var err error
+		keyID, err = SHA256KeyID(public)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sv := &RSAPSSSignerVerifier{
+		keyID:  keyID,
+		public: public,
+		hash:   crypto.SHA256,
+	}
+	for _, opt := range opts {
+		opt(sv)
+	}
+	if err := checkHash(sv.hash); err != nil {
+		return nil, err
+	}
+
+	return sv, nil
+}
+
+// Sign hashes data and signs the digest with the private key.
+func (sv *RSAPSSSignerVerifier) Sign(data []byte) ([]byte, error) {
+	digest := sv.hash.New()
+	digest.Write(data)
+
+	return sv.SignDigest(digest.Sum(nil), sv.hash)
+}
+
+// SignDigest signs a digest computed with hash.
+func (sv *RSAPSSSignerVerifier) SignDigest(digest []byte, hash crypto.Hash) ([]byte, error) {
+	if sv.private == nil {
+		return nil, ErrNoPrivateKey
+	}
+	if hash != sv.hash {
+		return nil, fmt.Errorf("%w: digest uses %v, signer uses %v", ErrAlgorithmMismatch, hash, sv.hash)
+	}
+
+	return rsa.SignPSS(rand.Reader, sv.private, sv.hash, digest, &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+}
+
+// Verify hashes data and verifies sig over the digest.
+func (sv *RSAPSSSignerVerifier) Verify(data, sig []byte) error {
+	digest := sv.hash.New()
+	digest.Write(data)
+
+	return sv.VerifyDigest(digest.Sum(nil), sv.hash, sig)
+}
+
+// HashFunc returns the hash applied to the message.
+func (sv *RSAPSSSignerVerifier) HashFunc() crypto.Hash {
+	return sv.hash
+}
+
+// VerifyDigest verifies sig over a digest computed with hash.
+func (sv *RSAPSSSignerVerifier) VerifyDigest(digest []byte, hash crypto.Hash, sig []byte) error {
+	if hash != sv.hash {
+		return fmt.Errorf("%w: digest uses %v, verifier uses %v", ErrAlgorithmMismatch, hash, sv.hash)
+	}
+
+	err := rsa.VerifyPSS(sv.public, sv.hash, digest, sig, &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthEqualsHash,
+	})
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// KeyID returns the key ID of the key.
+func (sv *RSAPSSSignerVerifier) KeyID() (string, error) {
+	return sv.keyID, nil
+}
+
+// Public returns the public key.
+func (sv *RSAPSSSignerVerifier) Public() crypto.PublicKey {
+	return sv.public
+}
+
+// Algorithm returns the name of the algorithm, such as "rsa-pss-sha256".
+func (sv *RSAPSSSignerVerifier) Algorithm() string {
+	return "rsa-pss-" + hashName(sv.hash)
+}
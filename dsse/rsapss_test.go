@@ -0,0 +1,41 @@
+package dsse
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRSAPSSSignerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	sv, err := NewRSAPSSSignerVerifier("", key)
+	assert.Nil(t, err, "unexpected error")
+
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	v, err := NewRSAPSSVerifier("", &key.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	ev, err := NewEnvelopeVerifier(v)
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+	_, err = v.Sign([]byte(payload))
+	assert.Equal(t, ErrNoPrivateKey, err, "wrong error")
+
+	err = v.Verify([]byte("tampered"), []byte("not a signature"))
+	assert.Equal(t, ErrSignatureInvalid, err, "wrong error")
+}
@@ -0,0 +1,77 @@
+//go:build secp256k1
+
+package dsse
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+func init() {
+	curve := secp256k1.S256()
+	ecdsaCurves[curve] = crypto.SHA256
+	ecdsaImpls[curve] = ecdsaImpl{sign: secp256k1Sign, verify: secp256k1Verify}
+}
+
+/*
+Secp256k1 returns the secp256k1 curve of SEC 2, as used by Bitcoin and
+Ethereum. It is only available with the secp256k1 build tag. Keys on the
+curve are accepted by NewECDSASignerVerifier and NewECDSAVerifier, hash with
+SHA-256 and are named "ecdsa-secp256k1-sha256". As with the other curves, only
+low-S signatures are produced and accepted, which secp256k1 ecosystems
+require.
+
+Signing and verification use github.com/decred/dcrd/dcrec/secp256k1/v4,
+whose signing is constant time. Its signatures are always deterministic as
+specified by RFC 6979. Create keys with its GeneratePrivateKey and ToECDSA
+rather than with ecdsa.GenerateKey, which does not use constant time
+arithmetic for this curve. SHA256KeyID cannot derive a key ID for secp256k1
+keys, so one must be given.
+*/
+func Secp256k1() elliptic.Curve {
+	return secp256k1.S256()
+}
+
+func secp256k1Sign(private *ecdsa.PrivateKey, digest []byte) (*big.Int, *big.Int, error) {
+	var d secp256k1.ModNScalar
+	if private.D.BitLen() > 256 || d.SetByteSlice(private.D.Bytes()) || d.IsZero() {
+		return nil, nil, errors.New("invalid secp256k1 private key")
+	}
+	key := secp256k1.NewPrivateKey(&d)
+	defer key.Zero()
+
+	sig := secp256k1ecdsa.Sign(key, digest)
+	r, s := sig.R(), sig.S()
+	rb, sb := r.Bytes(), s.Bytes()
+
+	return new(big.Int).SetBytes(rb[:]), new(big.Int).SetBytes(sb[:]), nil
+}
+
+func secp256k1Verify(public *ecdsa.PublicKey, digest []byte, r, s *big.Int) bool {
+	var x, y secp256k1.FieldVal
+	var rs, ss secp256k1.ModNScalar
+	for _, v := range []*big.Int{public.X, public.Y, r, s} {
+		if v.Sign() < 0 || v.BitLen() > 256 {
+			return false
+		}
+	}
+	if x.SetByteSlice(public.X.Bytes()) || y.SetByteSlice(public.Y.Bytes()) {
+		return false
+	}
+	if rs.SetByteSlice(r.Bytes()) || ss.SetByteSlice(s.Bytes()) {
+		return false
+	}
+
+	key := secp256k1.NewPublicKey(&x, &y)
+	if !key.IsOnCurve() {
+		return false
+	}
+
+	return secp256k1ecdsa.NewSignature(&rs, &ss).Verify(digest, key)
+}
@@ -0,0 +1,68 @@
+//go:build secp256k1
+
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecp256k1SignerVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	priv, err := secp256k1.GeneratePrivateKey()
+	assert.Nil(t, err, "unexpected error")
+	sv, err := NewECDSASignerVerifier("k1", priv.ToECDSA())
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ecdsa-secp256k1-sha256", sv.Algorithm(), "wrong algorithm")
+
+	es, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	acceptedKeys, err := es.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "k1", acceptedKeys[0].KeyID, "wrong keyid")
+
+	again, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, env, again, "signature is not deterministic")
+
+	t.Run("High S", func(t *testing.T) {
+		sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+		assert.Nil(t, err, "unexpected error")
+		r, s, err := ecdsaParseDER(sig)
+		assert.Nil(t, err, "unexpected error")
+		high, err := ecdsaMarshalDER(r, new(big.Int).Sub(Secp256k1().Params().N, s))
+		assert.Nil(t, err, "unexpected error")
+
+		assert.Equal(t, ErrHighS, sv.Verify(PAE(payloadType, payload), high), "wrong error")
+	})
+
+	t.Run("Interoperability", func(t *testing.T) {
+		// Signed with OpenSSL:
+		// openssl dgst -sha256 -sign key.pem
+		point, err := hex.DecodeString("04cc54709821aaa3ac76f201979cad8954fe75e1f0515460d9a69c2c3455c2924153d635cea86b02f35444051e0ddfa012baf5e72eb35572faa4b0e2ad42e6386b")
+		assert.Nil(t, err, "unexpected error")
+		public := &ecdsa.PublicKey{
+			Curve: Secp256k1(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		}
+		sig, err := base64.StdEncoding.DecodeString("MEQCIGtM8JknppXklspDlJjuaD0DAo+E7m+ga8CF0txQc7ezAiBdXxPdRQmwm6nupNQUC9gyKII1ZYdIphyaffLyCAZDZA==")
+		assert.Nil(t, err, "unexpected error")
+
+		v, err := NewECDSAVerifier("openssl", public)
+		assert.Nil(t, err, "unexpected error")
+		assert.Nil(t, v.Verify(PAE(payloadType, payload), sig), "unexpected error")
+		assert.Equal(t, ErrSignatureInvalid, v.Verify(PAE(payloadType, []byte("goodbye")), sig), "wrong error")
+	})
+}
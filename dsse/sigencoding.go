@@ -0,0 +1,155 @@
+package dsse
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+)
+
+// ErrUnknownSignatureEncoding indicates that a signature is in none of the
+// supported encodings.
+var ErrUnknownSignatureEncoding = errors.New("unknown signature encoding")
+
+/*
+SignatureEncoding is the wire format of an ECDSA signature.
+
+SignatureEncodingDER is the ASN.1 DER SEQUENCE of r and s used by X.509 and
+most Go and OpenSSL tooling. SignatureEncodingRaw is the IEEE P1363
+concatenation r||s, each left-padded to the byte size of the curve order.
+SignatureEncodingJOSE is the encoding of RFC 7518, section 3.4, as used by
+JWS and WebCrypto; it has the same layout as SignatureEncodingRaw and is
+provided so callers can state which ecosystem they target.
+*/
+type SignatureEncoding int
+
+const (
+	SignatureEncodingDER SignatureEncoding = iota
+	SignatureEncodingRaw
+	SignatureEncodingJOSE
+)
+
+/*
+ConvertECDSASignature converts an ECDSA signature for a key on curve to the
+given encoding. The encoding of sig is detected automatically. The signature
+values are kept as they are, in particular a high-S signature stays high-S.
+*/
+func ConvertECDSASignature(curve elliptic.Curve, sig []byte, to SignatureEncoding) ([]byte, error) {
+	r, s, err := ecdsaParse(curve, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	return ecdsaMarshal(curve, r, s, to)
+}
+
+/*
+ECDSARawToDER converts a raw r||s ECDSA signature for a key on curve, each
+value left-padded to the byte size of the curve order, to ASN.1 DER. Unlike
+ConvertECDSASignature it does not detect the encoding: sig must be exactly
+twice the byte size of the curve order, and r and s must lie in [1, N-1].
+*/
+func ECDSARawToDER(raw []byte, curve elliptic.Curve) ([]byte, error) {
+	r, s, err := ecdsaParseRaw(curve, raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := ecdsaCheckRange(curve, r, s); err != nil {
+		return nil, err
+	}
+
+	return ecdsaMarshalDER(r, s)
+}
+
+/*
+ECDSADERToRaw converts an ASN.1 DER ECDSA signature for a key on curve to raw
+r||s, each value left-padded to the byte size of the curve order. r and s
+must lie in [1, N-1].
+*/
+func ECDSADERToRaw(der []byte, curve elliptic.Curve) ([]byte, error) {
+	r, s, err := ecdsaParseDER(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := ecdsaCheckRange(curve, r, s); err != nil {
+		return nil, err
+	}
+
+	return ecdsaMarshalRaw(curve, r, s)
+}
+
+// ecdsaCheckRange checks that r and s are below the curve order.
+func ecdsaCheckRange(curve elliptic.Curve, r, s *big.Int) error {
+	n := curve.Params().N
+	if r.Cmp(n) >= 0 || s.Cmp(n) >= 0 {
+		return errors.New("ecdsa signature values out of range for curve")
+	}
+
+	return nil
+}
+
+// ecdsaParse parses a signature in any supported encoding.
+func ecdsaParse(curve elliptic.Curve, sig []byte) (*big.Int, *big.Int, error) {
+	if r, s, err := ecdsaParseDER(sig); err == nil {
+		return r, s, nil
+	}
+
+	return ecdsaParseRaw(curve, sig)
+}
+
+// ecdsaParseAs parses a signature in the given encoding only.
+func ecdsaParseAs(curve elliptic.Curve, sig []byte, encoding SignatureEncoding) (*big.Int, *big.Int, error) {
+	switch encoding {
+	case SignatureEncodingDER:
+		return ecdsaParseDER(sig)
+	case SignatureEncodingRaw, SignatureEncodingJOSE:
+		return ecdsaParseRaw(curve, sig)
+	}
+
+	return nil, nil, ErrUnknownSignatureEncoding
+}
+
+func ecdsaMarshal(curve elliptic.Curve, r, s *big.Int, encoding SignatureEncoding) ([]byte, error) {
+	switch encoding {
+	case SignatureEncodingDER:
+		return ecdsaMarshalDER(r, s)
+	case SignatureEncodingRaw, SignatureEncodingJOSE:
+		return ecdsaMarshalRaw(curve, r, s)
+	}
+
+	return nil, ErrUnknownSignatureEncoding
+}
+
+// ecdsaScalarSize returns the byte size of the curve order.
+func ecdsaScalarSize(curve elliptic.Curve) int {
+	return (curve.Params().N.BitLen() + 7) / 8
+}
+
+// ecdsaParseRaw splits r||s at the byte size of the curve order, so that values
+// with leading zero bytes parse correctly.
+func ecdsaParseRaw(curve elliptic.Curve, sig []byte) (*big.Int, *big.Int, error) {
+	size := ecdsaScalarSize(curve)
+	if len(sig) != 2*size {
+		return nil, nil, ErrUnknownSignatureEncoding
+	}
+
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	if r.Sign() <= 0 || s.Sign() <= 0 {
+		return nil, nil, errors.New("invalid ecdsa signature values")
+	}
+
+	return r, s, nil
+}
+
+func ecdsaMarshalRaw(curve elliptic.Curve, r, s *big.Int) ([]byte, error) {
+	size := ecdsaScalarSize(curve)
+	if r.BitLen() > 8*size || s.BitLen() > 8*size {
+		return nil, errors.New("ecdsa signature values too large for curve")
+	}
+
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig, nil
+}
@@ -0,0 +1,160 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertECDSASignature(t *testing.T) {
+	pae := PAE("http://example.com/HelloWorld", []byte("hello world"))
+	der, err := hex.DecodeString(ecdsaLowSVector)
+	assert.Nil(t, err, "unexpected error")
+
+	raw, err := ConvertECDSASignature(elliptic.P256(), der, SignatureEncodingRaw)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, ecdsaLowSVector[8:72]+ecdsaLowSVector[76:], hex.EncodeToString(raw), "wrong raw signature")
+
+	jose, err := ConvertECDSASignature(elliptic.P256(), der, SignatureEncodingJOSE)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, raw, jose, "wrong jose signature")
+
+	back, err := ConvertECDSASignature(elliptic.P256(), raw, SignatureEncodingDER)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, der, back, "wrong der signature")
+
+	v, err := NewECDSAVerifier("", &newEcdsaKey().PublicKey)
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, v.Verify(pae, der), "unexpected error")
+	assert.Equal(t, ErrSignatureInvalid, v.Verify(pae, raw), "wrong error")
+
+	v, err = NewECDSAVerifier("", &newEcdsaKey().PublicKey, WithSignatureEncoding(SignatureEncodingRaw))
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, v.Verify(pae, raw), "unexpected error")
+	assert.Equal(t, ErrSignatureInvalid, v.Verify(pae, der), "wrong error")
+
+	v, err = NewECDSAVerifier("", &newEcdsaKey().PublicKey, WithAllowHighS())
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, v.Verify(pae, der), "unexpected error")
+	assert.Nil(t, v.Verify(pae, raw), "unexpected error")
+
+	_, err = ConvertECDSASignature(elliptic.P256(), raw[1:], SignatureEncodingDER)
+	assert.Equal(t, ErrUnknownSignatureEncoding, err, "wrong error")
+
+	_, err = ConvertECDSASignature(elliptic.P256(), der, SignatureEncoding(42))
+	assert.Equal(t, ErrUnknownSignatureEncoding, err, "wrong error")
+}
+
+func TestECDSASignatureEncoding(t *testing.T) {
+	pae := PAE("http://example.com/HelloWorld", []byte("hello world"))
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P521()} {
+		for _, encoding := range []SignatureEncoding{SignatureEncodingDER, SignatureEncodingRaw, SignatureEncodingJOSE} {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			assert.Nil(t, err, "unexpected error")
+
+			sv, err := NewECDSASignerVerifier("", key, WithSignatureEncoding(encoding))
+			assert.Nil(t, err, "unexpected error")
+
+			sig, err := sv.Sign(pae)
+			assert.Nil(t, err, "sign failed")
+			if encoding == SignatureEncodingDER {
+				_, _, err = ecdsaParseDER(sig)
+				assert.Nil(t, err, "not a der signature")
+			} else {
+				assert.Len(t, sig, 2*ecdsaScalarSize(curve), "wrong raw signature size")
+			}
+
+			v, err := NewECDSAVerifier("", &key.PublicKey, WithSignatureEncoding(encoding))
+			assert.Nil(t, err, "unexpected error")
+			assert.Nil(t, v.Verify(pae, sig), "unexpected error")
+		}
+	}
+}
+
+func TestECDSARawSignatureLeadingZero(t *testing.T) {
+	key := newEcdsaKey()
+	sv, err := NewECDSASignerVerifier("", key, WithSignatureEncoding(SignatureEncodingRaw))
+	assert.Nil(t, err, "unexpected error")
+
+	cases := map[string]func(r []byte) bool{
+		"High bit set": func(r []byte) bool { return r[0]&0x80 != 0 },
+		"Leading zero": func(r []byte) bool { return r[0] == 0 },
+	}
+	for name, match := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Sign distinct messages until r has the wanted form; a leading
+			// zero occurs once in 256 signatures on average.
+			for i := 0; i < 100000; i++ {
+				msg := []byte(fmt.Sprintf("message %d", i))
+				sig, err := sv.Sign(msg)
+				assert.Nil(t, err, "sign failed")
+				assert.Len(t, sig, 64, "wrong raw signature size")
+				if !match(sig[:32]) {
+					continue
+				}
+
+				assert.Nil(t, sv.Verify(msg, sig), "unexpected error")
+
+				der, err := ConvertECDSASignature(elliptic.P256(), sig, SignatureEncodingDER)
+				assert.Nil(t, err, "unexpected error")
+				back, err := ConvertECDSASignature(elliptic.P256(), der, SignatureEncodingRaw)
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, sig, back, "wrong raw signature")
+				return
+			}
+			t.Fatal("no matching signature found")
+		})
+	}
+}
+
+func TestECDSARawDERRoundTrip(t *testing.T) {
+	msg := []byte("hello world")
+
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(curve, rand.Reader)
+			assert.Nil(t, err, "unexpected error")
+			sv, err := NewECDSASignerVerifier("", key)
+			assert.Nil(t, err, "unexpected error")
+
+			for i := 0; i < 32; i++ {
+				der, err := sv.Sign(msg)
+				assert.Nil(t, err, "sign failed")
+
+				raw, err := ECDSADERToRaw(der, curve)
+				assert.Nil(t, err, "unexpected error")
+				assert.Len(t, raw, 2*ecdsaScalarSize(curve), "wrong raw signature size")
+
+				back, err := ECDSARawToDER(raw, curve)
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, der, back, "wrong der signature")
+			}
+
+			// A raw signature with leading zero bytes in r and s.
+			raw := make([]byte, 2*ecdsaScalarSize(curve))
+			raw[len(raw)/2-1], raw[len(raw)-1] = 1, 2
+			der, err := ECDSARawToDER(raw, curve)
+			assert.Nil(t, err, "unexpected error")
+			back, err := ECDSADERToRaw(der, curve)
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, raw, back, "wrong raw signature")
+
+			_, err = ECDSARawToDER(raw[1:], curve)
+			assert.ErrorIs(t, err, ErrUnknownSignatureEncoding, "wrong error")
+			_, err = ECDSADERToRaw(raw, curve)
+			assert.NotNil(t, err, "expected error")
+
+			// r equal to the curve order is out of range.
+			size := ecdsaScalarSize(curve)
+			curve.Params().N.FillBytes(raw[:size])
+			_, err = ECDSARawToDER(raw, curve)
+			assert.NotNil(t, err, "expected error")
+		})
+	}
+}
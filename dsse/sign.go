@@ -6,8 +6,10 @@ package dsse
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 )
 
 // ErrUnknownKey indicates that the implementation does not recognize the
@@ -20,17 +22,45 @@ var ErrNoSignature = errors.New("no signature found")
 // ErrNoSigners indicates that no signer was provided.
 var ErrNoSigners = errors.New("no signers provided")
 
+// ErrEmptySignature indicates that a signature decoded to zero bytes.
+var ErrEmptySignature = errors.New("empty signature")
+
+// ErrUnknownPayloadEncoding indicates that an envelope records a payload
+// encoding other than PayloadEncodingBase64 or PayloadEncodingBase64URL.
+var ErrUnknownPayloadEncoding = errors.New("unknown payload encoding")
+
+// ErrTooManySignatures indicates that an envelope has more signatures than
+// the configured maximum.
+var ErrTooManySignatures = errors.New("too many signatures")
+
+// ErrPayloadTooLarge indicates that the decoded payload of an envelope exceeds
+// the configured maximum size.
+var ErrPayloadTooLarge = errors.New("payload too large")
+
 /*
 Envelope captures an envelope as described by the Secure Systems Lab
 Signing Specification. See here:
 https://github.com/secure-systems-lab/signing-spec/blob/master/envelope.md
 */
 type Envelope struct {
-	PayloadType string      `json:"payloadType"`
-	Payload     string      `json:"payload"`
-	Signatures  []Signature `json:"signatures"`
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	// PayloadEncoding optionally records the base64 alphabet of Payload,
+	// see PayloadEncodingBase64 and PayloadEncodingBase64URL. If empty, both
+	// alphabets are accepted.
+	PayloadEncoding string `json:"payloadEncoding,omitempty"`
+	// AAD optionally holds base64 encoded additional authenticated data
+	// bound to the signatures, see SignPayloadWithAAD.
+	AAD        string      `json:"aad,omitempty"`
+	Signatures []Signature `json:"signatures"`
 }
 
+// Values of Envelope.PayloadEncoding.
+const (
+	PayloadEncodingBase64    = "base64"
+	PayloadEncodingBase64URL = "base64url"
+)
+
 /*
 Signature represents a generic in-toto signature that contains the identifier
 of the key which was used to create the signature.
@@ -38,10 +68,14 @@ The used signature scheme has to be agreed upon by the signer and verifer
 out of band.
 The signature is a base64 encoding of the raw bytes from the signature
 algorithm.
+Extensions carries optional per-signature metadata, such as a certificate or
+a timestamp. Extensions are not covered by the signature, and verification
+ignores extensions it does not know.
 */
 type Signature struct {
-	KeyID string `json:"keyid"`
-	Sig   string `json:"sig"`
+	KeyID      string                     `json:"keyid"`
+	Sig        string                     `json:"sig"`
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
 }
 
 /*
@@ -49,9 +83,37 @@ PAE implementes the DSSE Pre-Authentic Encoding
 https://github.com/secure-systems-lab/dsse/blob/master/protocol.md#signature-definition
 */
 func PAE(payloadType string, payload []byte) []byte {
-	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s",
-		len(payloadType), payloadType,
-		len(payload), payload))
+	// "DSSEv1", three spaces and two lengths of at most 20 digits.
+	n := len("DSSEv1") + 3 + 2*20 + len(payloadType) + len(payload)
+	return PAEInto(make([]byte, 0, n), payloadType, payload)
+}
+
+// buildPAE builds the encoding signed by SignPayload; tests replace it to
+// count the encodings built.
+var buildPAE = PAE
+
+/*
+PAEInto writes the pre-authentication encoding of payloadType and payload to
+buf, reusing its storage, and returns the result, which is identical to PAE.
+No memory is allocated if buf is large enough, so that a buffer, for example
+from a sync.Pool, can be reused on hot paths.
+*/
+func PAEInto(buf []byte, payloadType string, payload []byte) []byte {
+	buf = appendPAEHeader(buf[:0], payloadType, int64(len(payload)))
+	return append(buf, payload...)
+}
+
+// appendPAEHeader appends the pre-authentication encoding up to the payload,
+// which is payloadLen bytes long, to buf.
+func appendPAEHeader(buf []byte, payloadType string, payloadLen int64) []byte {
+	buf = append(buf, "DSSEv1 "...)
+	buf = strconv.AppendInt(buf, int64(len(payloadType)), 10)
+	buf = append(buf, ' ')
+	buf = append(buf, payloadType...)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, payloadLen, 10)
+
+	return append(buf, ' ')
 }
 
 /*
@@ -64,7 +126,11 @@ depends on hashing of the message prior to signature calculation, the
 implementor of this interface must perform such hashing.
 The function must return raw bytes representing the calculated signature
 using the current algorithm, and the key used (if applicable).
-For an example see EcdsaSigner in sign_test.go.
+Implementations shared by an EnvelopeSigner that is used from several
+goroutines must be safe for concurrent use. The bundled signers, such as
+ECDSASignerVerifier, keep no mutable state and are safe for concurrent use.
+For a minimal example see EcdsaSigner in sign_test.go; note that it records
+state on every call and is therefore not safe for concurrent use.
 */
 type Signer interface {
 	Sign(data []byte) ([]byte, error)
@@ -77,16 +143,23 @@ type SignVerifier interface {
 	Verifier
 }
 
-// EnvelopeSigner creates signed Envelopes.
+/*
+EnvelopeSigner creates signed Envelopes.
+An EnvelopeSigner holds no mutable state of its own. SignPayload and Verify
+may be called concurrently from multiple goroutines, provided the configured
+signers and verifiers are safe for concurrent use.
+*/
 type EnvelopeSigner struct {
 	providers []SignVerifier
 	ev        *envelopeVerifier
+	opts      options
 }
 
 /*
 NewEnvelopeSigner creates an EnvelopeSigner that uses 1+ Signer
 algorithms to sign the data.
 Creates a verifier with threshold=1, at least one of the providers must validate signitures successfully.
+An error is returned if the KeyID method of any signer fails.
 */
 func NewEnvelopeSigner(p ...SignVerifier) (*EnvelopeSigner, error) {
 	return NewMultiEnvelopeSigner(1, p...)
@@ -99,6 +172,14 @@ Creates a verifier with threshold.
 threashold indicates the amount of providers that must validate the envelope.
 */
 func NewMultiEnvelopeSigner(threshold int, p ...SignVerifier) (*EnvelopeSigner, error) {
+	return NewEnvelopeSignerWithOptions(threshold, p)
+}
+
+/*
+NewEnvelopeSignerWithOptions creates an EnvelopeSigner with the given
+threshold and signers, configured by opts.
+*/
+func NewEnvelopeSignerWithOptions(threshold int, p []SignVerifier, opts ...Option) (*EnvelopeSigner, error) {
 	var providers []SignVerifier
 
 	for _, sv := range p {
@@ -111,12 +192,19 @@ func NewMultiEnvelopeSigner(threshold int, p ...SignVerifier) (*EnvelopeSigner,
 		return nil, ErrNoSigners
 	}
 
+	// Fail fast on misconfigured signers rather than in SignPayload.
+	for _, sv := range providers {
+		if _, err := sv.KeyID(); err != nil {
+			return nil, err
+		}
+	}
+
 	evps := []Verifier{}
 	for _, p := range providers {
 		evps = append(evps, p.(Verifier))
 	}
 
-	ev, err := NewMultiEnvelopeVerifier(threshold, evps...)
+	ev, err := NewEnvelopeVerifierWithOptions(threshold, evps, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -124,6 +212,7 @@ func NewMultiEnvelopeSigner(threshold int, p ...SignVerifier) (*EnvelopeSigner,
 	return &EnvelopeSigner{
 		providers: providers,
 		ev:        ev,
+		opts:      ev.opts,
 	}, nil
 }
 
@@ -132,32 +221,206 @@ SignPayload signs a payload and payload type according to DSSE.
 Returned is an envelope as defined here:
 https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
 One signature will be added for each Signer in the EnvelopeSigner.
+The key ID of each signature is the key ID reported by the signer. If the
+signer reports an empty key ID, it is derived from the public key of the
+signer with SHA256KeyID, as verifiers do, and left empty only if that fails.
+An empty or nil body is valid and yields an envelope with an empty payload,
+which verifies like any other.
 */
 func (es *EnvelopeSigner) SignPayload(payloadType string, body []byte) (*Envelope, error) {
+	return es.sign(payloadType, es.opts.encode(body), body)
+}
+
+/*
+SignEncodedPayload signs a payload that is already base64 encoded, using
+either the standard or the URL safe alphabet. The payload is stored in the
+envelope as given, and the signatures are computed over the decoded bytes, so
+the payload is not encoded a second time.
+*/
+func (es *EnvelopeSigner) SignEncodedPayload(payloadType, b64Payload string) (*Envelope, error) {
+	body, err := es.opts.decode(b64Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.sign(payloadType, b64Payload, body)
+}
+
+// sign creates an envelope for the encoded payload, signing the decoded body.
+func (es *EnvelopeSigner) sign(payloadType, payload string, body []byte) (*Envelope, error) {
+	payloadType = es.opts.normalizePayloadType(payloadType)
+	if err := ValidatePayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	if err := es.checkSignerPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	es.opts.notePayloadType(payloadType)
+
 	var e = Envelope{
-		Payload:     base64.StdEncoding.EncodeToString(body),
+		Payload:     payload,
 		PayloadType: payloadType,
 	}
 
-	paeEnc := PAE(payloadType, body)
+	// The encoding is built once and the same bytes are handed to every
+	// signer.
+	signatures, err := es.signPAE(buildPAE(payloadType, body))
+	if err != nil {
+		return nil, err
+	}
+	e.Signatures = signatures
+
+	return &e, nil
+}
 
+/*
+AppendSignature adds signatures from every Signer to an envelope that may
+already be signed by others, as in sequential approval workflows. The
+existing signatures are verified first: each must be accepted by one of the
+signers of es or one of verifiers, the keys of the earlier signers, and
+otherwise the verification error is returned, so that a tampered envelope or
+one signed by unknown keys is not approved. The new signatures are computed
+over the payload exactly as it is stored in env. env is not modified; a copy
+with the existing signatures followed by the new ones is returned.
+*/
+func (es *EnvelopeSigner) AppendSignature(env *Envelope, verifiers ...Verifier) (*Envelope, error) {
+	if env == nil {
+		return nil, ErrNoEnvelopes
+	}
+
+	p := make([]Verifier, 0, len(es.providers)+len(verifiers))
+	for _, sv := range es.providers {
+		p = append(p, sv)
+	}
+	p = append(p, verifiers...)
+	// The existing signatures are checked with the options of es, but are
+	// not reported as verified envelopes or signatures.
+	o := es.opts
+	o.auditSink, o.perSignatureCallback = nil, nil
+	ev, err := newEnvelopeVerifier(1, p, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.appendSignature(env, ev)
+}
+
+/*
+appendSignature implements AppendSignature. If ev is not nil, every existing
+signature of env must be accepted by it.
+*/
+func (es *EnvelopeSigner) appendSignature(env *Envelope, ev *envelopeVerifier) (*Envelope, error) {
+	body, err := es.opts.decodePayload(env)
+	if err != nil {
+		return nil, err
+	}
+
+	aad, err := env.decodedAAD()
+	if err != nil {
+		return nil, err
+	}
+
+	payloadType := es.opts.normalizePayloadType(env.PayloadType)
+	if err := es.checkSignerPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+
+	if ev != nil {
+		for _, s := range env.Signatures {
+			single := *env
+			single.Signatures = []Signature{s}
+			if _, err := ev.VerifyWithAAD(&single, aad); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	es.opts.notePayloadType(payloadType)
+	signatures, err := es.signPAE(PAEWithAAD(payloadType, body, aad))
+	if err != nil {
+		return nil, err
+	}
+
+	e := env.Clone()
+	e.Signatures = append(e.Signatures, signatures...)
+
+	return e, nil
+}
+
+/*
+ReSign verifies env with verifier, typically the key being retired, and then
+adds a signature by newSigner over the identical payload, as AppendSignature
+does. The result is signed by both keys, so it is accepted by consumers that
+have not yet moved to the new key. If env does not verify, it is not signed
+and the verification error is returned, so tampered envelopes are not carried
+over to the new key. Other signatures of env are carried over unchecked.
+*/
+func ReSign(env *Envelope, verifier Verifier, newSigner SignVerifier) (*Envelope, error) {
+	if env == nil {
+		return nil, ErrNoEnvelopes
+	}
+
+	ev, err := NewEnvelopeVerifier(verifier)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ev.Verify(env); err != nil {
+		return nil, err
+	}
+
+	es, err := NewEnvelopeSigner(newSigner)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.appendSignature(env, nil)
+}
+
+// signPAE signs the pre-authentication encoding with every signer.
+func (es *EnvelopeSigner) signPAE(paeEnc []byte) ([]Signature, error) {
+	return es.signMessage(&message{pae: paeEnc})
+}
+
+/*
+signMessage signs the message with every signer. A PrehashSigner is given the
+digest of the message, which is computed once per hash and shared by all
+signers.
+*/
+func (es *EnvelopeSigner) signMessage(msg *message) ([]Signature, error) {
+	var signatures []Signature
 	for _, signer := range es.providers {
-		sig, err := signer.Sign(paeEnc)
+		keyID := verifierKeyID(signer)
+		es.opts.debugSign(keyID, msg.pae)
+
+		var sig []byte
+		var err error
+		if ps, ok := prehashSigner(signer); ok {
+			es.opts.debug("signing digest", "keyid", keyID, "hash", ps.HashFunc())
+			digest, ok := msg.digest(ps.HashFunc())
+			if !ok {
+				return nil, fmt.Errorf("%w: %v", ErrUnsupportedHash, ps.HashFunc())
+			}
+			sig, err = ps.SignDigest(digest, ps.HashFunc())
+		} else if msg.pae == nil {
+			return nil, ErrStreamingUnsupported
+		} else {
+			sig, err = signer.Sign(msg.pae)
+		}
 		if err != nil {
 			return nil, err
 		}
-		keyID, err := signer.KeyID()
+		extensions, err := es.opts.signatureExtensions(signer)
 		if err != nil {
-			keyID = ""
+			return nil, err
 		}
-
-		e.Signatures = append(e.Signatures, Signature{
-			KeyID: keyID,
-			Sig:   base64.StdEncoding.EncodeToString(sig),
+		signatures = append(signatures, Signature{
+			KeyID:      keyID,
+			Sig:        es.opts.encode(sig),
+			Extensions: extensions,
 		})
 	}
 
-	return &e, nil
+	return signatures, nil
 }
 
 /*
@@ -170,9 +433,19 @@ func (es *EnvelopeSigner) Verify(e *Envelope) ([]AcceptedKey, error) {
 	return es.ev.Verify(e)
 }
 
+// VerifyAndExtract verifies e and returns its payload type, decoded payload
+// and accepted keys. See the VerifyAndExtract method of the envelope verifier.
+func (es *EnvelopeSigner) VerifyAndExtract(e *Envelope) (string, []byte, []AcceptedKey, error) {
+	return es.ev.VerifyAndExtract(e)
+}
+
 /*
 Both standard and url encoding are allowed:
 https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+The fallback is unambiguous: the alphabets only differ in the characters for
+62 and 63, which each alphabet rejects from the other, so a string that
+decodes under both alphabets decodes to the same bytes, and a string mixing
+both alphabets is rejected.
 */
 func b64Decode(s string) ([]byte, error) {
 	b, err := base64.StdEncoding.DecodeString(s)
@@ -185,3 +458,32 @@ func b64Decode(s string) ([]byte, error) {
 
 	return b, nil
 }
+
+/*
+decodePayload decodes payload with the alphabet named by encoding, falling
+back to b64Decode if encoding is empty.
+*/
+func decodePayload(payload, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return b64Decode(payload)
+	case PayloadEncodingBase64:
+		return base64.StdEncoding.DecodeString(payload)
+	case PayloadEncodingBase64URL:
+		return base64.URLEncoding.DecodeString(payload)
+	}
+
+	return nil, ErrUnknownPayloadEncoding
+}
+
+/*
+b64DecodedLen returns the length of the data encoded in s, without decoding
+it. Malformed input is left for b64Decode to reject.
+*/
+func b64DecodedLen(s string) int64 {
+	n := int64(len(s)) / 4 * 3
+	for i := len(s) - 1; i >= 0 && s[i] == '='; i-- {
+		n--
+	}
+	return n
+}
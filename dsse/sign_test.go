@@ -1,14 +1,18 @@
 package dsse
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"sync"
 	"testing"
 
 	"github.com/codahale/rfc6979"
@@ -38,6 +42,150 @@ func TestPAE(t *testing.T) {
 	})
 }
 
+func TestPAEInto(t *testing.T) {
+	buf := make([]byte, 0, 8)
+	for _, tc := range []struct {
+		payloadType string
+		payload     []byte
+	}{
+		{"", nil},
+		{"http://example.com/HelloWorld", []byte("hello world")},
+		{"x", bytes.Repeat([]byte("a"), 1000)},
+		{"http://example.com/HelloWorld", []byte("ಠ")},
+	} {
+		want := fmt.Sprintf("DSSEv1 %d %s %d %s", len(tc.payloadType), tc.payloadType, len(tc.payload), tc.payload)
+		buf = PAEInto(buf, tc.payloadType, tc.payload)
+		assert.Equal(t, want, string(buf), "Wrong encoding")
+		assert.Equal(t, PAE(tc.payloadType, tc.payload), buf, "Wrong encoding")
+	}
+}
+
+/*
+parsePAE is the inverse of PAEWithAAD: it recovers the payload type, payload
+and additional authenticated data from their encoding, requiring every
+length to match the field it prefixes exactly. That such an inverse exists is
+what makes the encoding injective.
+*/
+func parsePAE(enc []byte) (string, []byte, []byte, error) {
+	rest, ok := bytes.CutPrefix(enc, []byte("DSSEv1 "))
+	if !ok {
+		return "", nil, nil, errors.New("missing prefix")
+	}
+
+	field := func() ([]byte, error) {
+		digits, after, ok := bytes.Cut(rest, []byte(" "))
+		if !ok || len(digits) == 0 || (len(digits) > 1 && digits[0] == '0') {
+			return nil, errLength
+		}
+		n := 0
+		for _, d := range digits {
+			if d < '0' || d > '9' || n > len(after) {
+				return nil, errLength
+			}
+			n = 10*n + int(d-'0')
+		}
+		if n > len(after) {
+			return nil, errLength
+		}
+		rest = after[n:]
+		return after[:n], nil
+	}
+	sep := func() error {
+		if len(rest) == 0 || rest[0] != ' ' {
+			return errors.New("missing separator")
+		}
+		rest = rest[1:]
+		return nil
+	}
+
+	payloadType, err := field()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if err := sep(); err != nil {
+		return "", nil, nil, err
+	}
+	payload, err := field()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(rest) == 0 {
+		return string(payloadType), payload, nil, nil
+	}
+	if err := sep(); err != nil {
+		return "", nil, nil, err
+	}
+	aad, err := field()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(rest) != 0 || len(aad) == 0 {
+		return "", nil, nil, errors.New("trailing data")
+	}
+
+	return string(payloadType), payload, aad, nil
+}
+
+func TestPAEInjective(t *testing.T) {
+	// Pairs that would collide if the fields were only separated by spaces.
+	collisions := [][2]struct {
+		payloadType string
+		payload     string
+	}{
+		{{"X Y", "Z"}, {"X", "Y Z"}},
+		{{"X", "1 Y"}, {"X 1", "Y"}},
+		{{"", "0 "}, {"0 ", ""}},
+		{{"a 1 b", "c"}, {"a", "b 1 c"}},
+		{{"t", "p 3 aad"}, {"t 1 p", "aad"}},
+	}
+	for _, c := range collisions {
+		a := PAE(c[0].payloadType, []byte(c[0].payload))
+		b := PAE(c[1].payloadType, []byte(c[1].payload))
+		assert.NotEqual(t, a, b, "PAE collision for %q and %q", c[0], c[1])
+	}
+
+	// PAE with additional authenticated data cannot collide with a longer
+	// payload carrying the same bytes.
+	withAAD := PAEWithAAD("t", []byte("p"), []byte("aad"))
+	assert.NotEqual(t, PAE("t", []byte("p 3 aad")), withAAD, "PAE collision with aad")
+}
+
+func FuzzPAE(f *testing.F) {
+	f.Add("X Y", []byte("Z"), []byte(nil))
+	f.Add("X", []byte("Y Z"), []byte(nil))
+	f.Add("", []byte(""), []byte(nil))
+	f.Add("0 ", []byte("0 "), []byte("0 "))
+	f.Add("http://example.com/HelloWorld", []byte("hello world"), []byte("run 1"))
+	f.Add("t", []byte("p 3 aad"), []byte(nil))
+
+	f.Fuzz(func(t *testing.T, payloadType string, payload, aad []byte) {
+		gotType, gotPayload, gotAAD, err := parsePAE(PAEWithAAD(payloadType, payload, aad))
+		if err != nil {
+			t.Fatalf("cannot decode PAE(%q, %q, %q): %v", payloadType, payload, aad, err)
+		}
+		if gotType != payloadType || !bytes.Equal(gotPayload, payload) || !bytes.Equal(gotAAD, aad) {
+			t.Fatalf("PAE(%q, %q, %q) decodes to (%q, %q, %q)", payloadType, payload, aad, gotType, gotPayload, gotAAD)
+		}
+	})
+}
+
+func BenchmarkPAE(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		PAE("http://example.com/HelloWorld", payload)
+	}
+}
+
+func BenchmarkPAEInto(b *testing.B) {
+	payload := bytes.Repeat([]byte("a"), 1024)
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = PAEInto(buf, "http://example.com/HelloWorld", payload)
+	}
+}
+
 type nilsigner int
 
 func (n nilsigner) Sign(data []byte) ([]byte, error) {
@@ -178,6 +326,63 @@ func TestNoSigners(t *testing.T) {
 	})
 }
 
+type keyiderrsigner struct {
+	nilsigner
+}
+
+func (n keyiderrsigner) KeyID() (string, error) {
+	return "", errors.New("keyid error")
+}
+
+func TestSignerKeyIDError(t *testing.T) {
+	var ns nilsigner
+	var kes keyiderrsigner
+
+	signer, err := NewEnvelopeSigner(ns, kes)
+	assert.Nil(t, signer, "unexpected signer")
+	assert.Equal(t, "keyid error", err.Error(), "wrong error")
+
+	signer, err = NewMultiEnvelopeSigner(1, kes)
+	assert.Nil(t, signer, "unexpected signer")
+	assert.NotNil(t, err, "error expected")
+}
+
+// emptyKeyIDSigner reports an empty key ID for the wrapped signer.
+type emptyKeyIDSigner struct {
+	SignVerifier
+}
+
+func (s emptyKeyIDSigner) KeyID() (string, error) {
+	return "", nil
+}
+
+func TestSignEmptyKeyID(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	want, err := SHA256KeyID(sv.Public())
+	assert.Nil(t, err, "unexpected error")
+
+	signer, err := NewEnvelopeSigner(emptyKeyIDSigner{sv})
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, want, env.Signatures[0].KeyID, "key ID not derived from public key")
+
+	acceptedKeys, err := signer.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, want, acceptedKeys[0].KeyID, "wrong key ID")
+
+	// Without usable key material the key ID stays empty.
+	signer, err = NewEnvelopeSigner(emptyKeyIDSigner{nullsigner(0)})
+	assert.Nil(t, err, "unexpected error")
+	env, err = signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, "", env.Signatures[0].KeyID, "wrong key ID")
+}
+
 func TestNilSign(t *testing.T) {
 	var keyID = "nil"
 	var payloadType = "http://example.com/HelloWorld"
@@ -331,6 +536,44 @@ func TestEcdsaSign(t *testing.T) {
 	assert.Equal(t, acceptedKeys[0].KeyID, keyID, "unexpected keyid")
 }
 
+func TestSignEmptyPayload(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	for name, body := range map[string][]byte{"nil": nil, "empty": {}} {
+		t.Run(name, func(t *testing.T) {
+			env, err := signer.SignPayload(payloadType, body)
+			assert.Nil(t, err, "sign failed")
+			assert.Equal(t, "", env.Payload, "wrong payload")
+			assert.Nil(t, sv.Verify(PAE(payloadType, nil), mustB64Decode(t, env.Signatures[0].Sig)), "signature not over empty payload")
+
+			data, err := json.Marshal(env)
+			assert.Nil(t, err, "unexpected error")
+			assert.Nil(t, ValidateEnvelopeJSON(data), "unexpected error")
+
+			var got Envelope
+			assert.Nil(t, json.Unmarshal(data, &got), "unexpected error")
+			acceptedKeys, err := signer.Verify(&got)
+			assert.Nil(t, err, "unexpected error")
+			assert.Len(t, acceptedKeys, 1, "unexpected keys")
+
+			decoded, err := got.DecodedPayload()
+			assert.Nil(t, err, "unexpected error")
+			assert.Empty(t, decoded, "unexpected payload")
+		})
+	}
+}
+
+func mustB64Decode(t *testing.T, s string) []byte {
+	b, err := b64Decode(s)
+	assert.Nil(t, err, "unexpected error")
+	return b
+}
+
 func TestB64Decode(t *testing.T) {
 	var want = make([]byte, 256)
 	for i := range want {
@@ -362,6 +605,33 @@ func TestB64Decode(t *testing.T) {
 		assert.NotNil(t, err, "expected error")
 		assert.Nil(t, got, "wrong data")
 	})
+	t.Run("Mixed alphabets", func(t *testing.T) {
+		got, err := b64Decode("+-__")
+		assert.NotNil(t, err, "expected error")
+		assert.Nil(t, got, "wrong data")
+	})
+
+	// A string that decodes under both alphabets must decode to the same
+	// bytes, or the fallback would let an envelope be read two ways.
+	t.Run("Unambiguous", func(t *testing.T) {
+		for i := 0; i < 256; i++ {
+			data := bytes.Repeat([]byte{byte(i), byte(255 - i), byte(i * 7)}, 3)
+			for n := 1; n <= len(data); n++ {
+				std := base64.StdEncoding.EncodeToString(data[:n])
+				url := base64.URLEncoding.EncodeToString(data[:n])
+				for _, s := range []string{std, url} {
+					fromStd, errStd := base64.StdEncoding.DecodeString(s)
+					fromURL, errURL := base64.URLEncoding.DecodeString(s)
+					if errStd == nil && errURL == nil {
+						assert.Equal(t, fromStd, fromURL, "ambiguous encoding %q", s)
+					}
+					got, err := b64Decode(s)
+					assert.Nil(t, err, "unexpected error")
+					assert.Equal(t, data[:n], got, "wrong data")
+				}
+			}
+		}
+	})
 }
 
 func TestVerifyOneProvider(t *testing.T) {
@@ -434,7 +704,34 @@ func TestVerifyErr(t *testing.T) {
 	assert.Nil(t, err, "sign failed")
 
 	_, err = signer.Verify(env)
-	assert.Equal(t, errVerify, err, "wrong error")
+	assert.EqualError(t, err, errVerify.Error(), "wrong error")
+	assert.True(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
+
+	var verr *VerificationError
+	assert.True(t, errors.As(err, &verr), "wrong error type")
+	assert.Equal(t, 0, verr.Found, "wrong found count")
+	assert.Equal(t, 1, verr.Expected, "wrong expected count")
+}
+
+func TestVerifyNoMatchingKey(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	var errv errverifier
+	ev, err := NewEnvelopeVerifier(errv)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = ev.Verify(env)
+	assert.EqualError(t, err, errVerify.Error(), "wrong error")
+	assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+	assert.False(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
 }
 
 func TestBadVerifier(t *testing.T) {
@@ -653,3 +950,338 @@ func TestVerifyPublicKeyID(t *testing.T) {
 	assert.Len(t, acceptedKeys, 1, "unexpected keys")
 	assert.Equal(t, acceptedKeys[0].KeyID, keyID, "unexpected keyid")
 }
+
+func TestSignEncodedPayload(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte{0xfb, 0xff, 0xfe, 'h', 'i'}
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	want, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	t.Run("Standard encoding", func(t *testing.T) {
+		got, err := signer.SignEncodedPayload(payloadType, base64.StdEncoding.EncodeToString(payload))
+		assert.Nil(t, err, "sign failed")
+		assert.Equal(t, want, got, "wrong envelope")
+	})
+
+	t.Run("URL encoding", func(t *testing.T) {
+		b64Payload := base64.URLEncoding.EncodeToString(payload)
+		got, err := signer.SignEncodedPayload(payloadType, b64Payload)
+		assert.Nil(t, err, "sign failed")
+		assert.Equal(t, b64Payload, got.Payload, "payload re-encoded")
+		assert.Equal(t, want.Signatures, got.Signatures, "wrong signatures")
+
+		_, err = signer.Verify(got)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Invalid encoding", func(t *testing.T) {
+		got, err := signer.SignEncodedPayload(payloadType, "Not base 64")
+		assert.IsType(t, base64.CorruptInputError(0), err, "wrong error")
+		assert.Nil(t, got, "expected nil")
+	})
+}
+
+func TestEnvelopeSignerConcurrent(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	var ns nilsigner
+	var null nullsigner
+	signer, err := NewEnvelopeSigner(ns, null)
+	assert.Nil(t, err, "unexpected error")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			env, err := signer.SignPayload(payloadType, []byte(fmt.Sprintf("payload %d", i)))
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			acceptedKeys, err := signer.Verify(env)
+			if err == nil && len(acceptedKeys) != 2 {
+				err = fmt.Errorf("expected 2 accepted keys, got %d", len(acceptedKeys))
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.Nil(t, err, "unexpected error")
+	}
+}
+
+func TestNewEnvelopeSignerWithOptions(t *testing.T) {
+	var ns nilsigner
+	var null nullsigner
+
+	signer, err := NewEnvelopeSignerWithOptions(2, []SignVerifier{ns, null}, WithMaxPayloadSize(1))
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload("http://example.com/HelloWorld", []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	// Verification options apply to the signer's Verify.
+	_, err = signer.Verify(env)
+	assert.Equal(t, ErrPayloadTooLarge, err, "wrong error")
+
+	_, err = NewEnvelopeSignerWithOptions(3, []SignVerifier{ns, null})
+	assert.Equal(t, errThreshold, err, "wrong error")
+}
+
+func TestAppendSignature(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	var ns nilsigner
+	var null nullsigner
+	first, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	second, err := NewEnvelopeSigner(null)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := first.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	got, err := second.AppendSignature(env, ns)
+	assert.Nil(t, err, "sign failed")
+	assert.Len(t, env.Signatures, 1, "input modified")
+	assert.Len(t, got.Signatures, 2, "wrong signatures")
+	assert.Equal(t, env.Signatures[0], got.Signatures[0], "existing signature modified")
+	assert.Equal(t, env.Payload, got.Payload, "payload modified")
+
+	ev, err := NewMultiEnvelopeVerifier(2, ns, null)
+	assert.Nil(t, err, "unexpected error")
+	acceptedKeys, err := ev.Verify(got)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 2, "unexpected keys")
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		ed, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+		assert.Nil(t, err, "unexpected error")
+		first, err := NewEnvelopeSigner(ed)
+		assert.Nil(t, err, "unexpected error")
+		env, err := first.SignPayload(payloadType, []byte(payload))
+		assert.Nil(t, err, "sign failed")
+
+		tampered := *env
+		tampered.Payload = base64.StdEncoding.EncodeToString([]byte("goodbye"))
+		_, err = second.AppendSignature(&tampered, ed)
+		assert.True(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
+	})
+
+	t.Run("Signer options", func(t *testing.T) {
+		key := newEd25519Key()
+		ed, err := NewEd25519SignerVerifier("ABCD", key)
+		assert.Nil(t, err, "unexpected error")
+		lower, err := NewEd25519Verifier("abcd", key.Public().(ed25519.PublicKey))
+		assert.Nil(t, err, "unexpected error")
+		first, err := NewEnvelopeSigner(ed)
+		assert.Nil(t, err, "unexpected error")
+		env, err := first.SignPayload(payloadType, []byte(payload))
+		assert.Nil(t, err, "sign failed")
+
+		var sink recordingSink
+		calls := 0
+		second, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{null},
+			WithNormalizedKeyIDs(),
+			WithAuditSink(&sink),
+			WithPerSignatureCallback(func(string, bool, error) { calls++ }))
+		assert.Nil(t, err, "unexpected error")
+		_, err = second.AppendSignature(env, lower)
+		assert.Nil(t, err, "sign failed")
+		assert.Empty(t, sink.events, "existing signatures audited")
+		assert.Equal(t, 0, calls, "existing signatures reported")
+	})
+
+	t.Run("Unknown signer", func(t *testing.T) {
+		_, err := second.AppendSignature(env)
+		assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+	})
+
+	t.Run("Invalid payload", func(t *testing.T) {
+		_, err := second.AppendSignature(&Envelope{Payload: "Not base 64"})
+		assert.IsType(t, base64.CorruptInputError(0), err, "wrong error")
+	})
+
+	t.Run("Nil envelope", func(t *testing.T) {
+		_, err := second.AppendSignature(nil)
+		assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	})
+}
+
+func TestReSign(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	oldKey, err := NewEd25519SignerVerifier("old", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	newKey, err := NewECDSASignerVerifier("new", newEcdsaKey())
+	assert.Nil(t, err, "unexpected error")
+
+	signer, err := NewEnvelopeSigner(oldKey)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	got, err := ReSign(env, oldKey, newKey)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, env.Signatures, 1, "input modified")
+	assert.Len(t, got.Signatures, 2, "wrong signatures")
+	assert.Equal(t, env.Signatures[0], got.Signatures[0], "existing signature modified")
+	assert.Equal(t, "new", got.Signatures[1].KeyID, "wrong keyid")
+
+	ev, err := NewEnvelopeVerifierRequireAll(oldKey, newKey)
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(got)
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("Tampered payload", func(t *testing.T) {
+		tampered := *env
+		tampered.Payload = base64.StdEncoding.EncodeToString([]byte("goodbye"))
+
+		_, err := ReSign(&tampered, oldKey, newKey)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, ErrSignatureInvalid, verr.Err, "wrong error")
+	})
+
+	t.Run("No envelope", func(t *testing.T) {
+		_, err := ReSign(nil, oldKey, newKey)
+		assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	})
+}
+
+func TestSignVerifyDebug(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	var signed, verified [][]byte
+	var keyIDs []string
+	es, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{sv}, WithSignDebug(func(keyID string, pae []byte) {
+		keyIDs = append(keyIDs, keyID)
+		signed = append(signed, pae)
+	}))
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, WithVerifyDebug(func(keyID string, pae []byte) {
+		keyIDs = append(keyIDs, keyID)
+		verified = append(verified, pae)
+	}))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	assert.Equal(t, []string{"ed", "ed"}, keyIDs, "wrong keyids")
+	assert.Equal(t, [][]byte{PAE(payloadType, payload)}, signed, "wrong signed pae")
+	assert.Equal(t, signed, verified, "signed and verified pae differ")
+
+	t.Run("Payload type mismatch", func(t *testing.T) {
+		verified = nil
+		env.PayloadType = "http://example.com/Other"
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+		assert.NotEqual(t, signed, verified, "pae should differ")
+	})
+}
+
+func TestBase64Encoding(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world?>")
+
+	enc := base64.NewEncoding("zyxwvutsrqponmlkjihgfedcbaZYXWVUTSRQPONMLKJIHGFEDCBA9876543210._").WithPadding(base64.NoPadding)
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	es, err := NewEnvelopeSignerWithOptions(1, []SignVerifier{sv}, WithBase64Encoding(enc))
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, enc.EncodeToString(payload), env.Payload, "wrong payload encoding")
+
+	_, err = es.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, WithBase64Encoding(enc))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("Default verifier", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifier(sv)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Standard envelope", func(t *testing.T) {
+		std, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+		env, err := std.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+// spySigner records the messages it is asked to sign.
+type spySigner struct {
+	nilsigner
+	keyID    string
+	messages *[][]byte
+}
+
+func (s spySigner) Sign(data []byte) ([]byte, error) {
+	*s.messages = append(*s.messages, data)
+	return s.nilsigner.Sign(data)
+}
+
+func (s spySigner) KeyID() (string, error) {
+	return s.keyID, nil
+}
+
+func TestSignPayloadBuildsPAEOnce(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	var builds int
+	buildPAE = func(payloadType string, payload []byte) []byte {
+		builds++
+		return PAE(payloadType, payload)
+	}
+	defer func() { buildPAE = PAE }()
+
+	var messages [][]byte
+	es, err := NewEnvelopeSigner(
+		spySigner{keyID: "a", messages: &messages},
+		spySigner{keyID: "b", messages: &messages},
+		spySigner{keyID: "c", messages: &messages},
+	)
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, 1, builds, "pae built more than once")
+	assert.Len(t, messages, 3, "wrong number of signed messages")
+	for _, m := range messages {
+		assert.Equal(t, PAE(payloadType, payload), m, "wrong pae")
+		assert.True(t, &m[0] == &messages[0][0], "pae not shared")
+	}
+}
@@ -0,0 +1,51 @@
+package dsse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSimpleSigning indicates that a document is not in the simple
+// signing format.
+var ErrInvalidSimpleSigning = errors.New("invalid simple signing document")
+
+// simpleSigning holds the required fields of a simple signing document.
+type simpleSigning struct {
+	Critical *struct {
+		Type     string `json:"type"`
+		Identity *struct {
+			DockerReference *string `json:"docker-reference"`
+		} `json:"identity"`
+		Image *struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+/*
+FromSimpleSigning wraps a document in the simple signing format, as used by
+cosign and by container signatures before DSSE, in an envelope with payload
+type PayloadTypeSimpleSigning signed by signer. The document is stored as
+is, so that its digest is unchanged. An error wrapping ErrInvalidSimpleSigning
+is returned if the document lacks the required critical fields.
+*/
+func FromSimpleSigning(blob []byte, signer *EnvelopeSigner) (*Envelope, error) {
+	var doc simpleSigning
+	if err := json.Unmarshal(blob, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSimpleSigning, err)
+	}
+
+	switch {
+	case doc.Critical == nil:
+		return nil, fmt.Errorf("%w: missing critical", ErrInvalidSimpleSigning)
+	case doc.Critical.Type == "":
+		return nil, fmt.Errorf("%w: missing critical.type", ErrInvalidSimpleSigning)
+	case doc.Critical.Identity == nil || doc.Critical.Identity.DockerReference == nil:
+		return nil, fmt.Errorf("%w: missing critical.identity.docker-reference", ErrInvalidSimpleSigning)
+	case doc.Critical.Image == nil || doc.Critical.Image.DockerManifestDigest == "":
+		return nil, fmt.Errorf("%w: missing critical.image.docker-manifest-digest", ErrInvalidSimpleSigning)
+	}
+
+	return signer.SignPayload(PayloadTypeSimpleSigning, blob)
+}
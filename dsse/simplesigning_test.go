@@ -0,0 +1,40 @@
+package dsse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromSimpleSigning(t *testing.T) {
+	blob := []byte(`{"critical":{"identity":{"docker-reference":"example.com/app"},"image":{"docker-manifest-digest":"sha256:0123456789abcdef"},"type":"cosign container image signature"},"optional":{"creator":"test"}}` + "\n")
+
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := FromSimpleSigning(blob, signer)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, PayloadTypeSimpleSigning, env.PayloadType, "wrong payload type")
+
+	payload, err := env.DecodedPayload()
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, blob, payload, "payload not preserved")
+
+	_, err = signer.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	invalid := []string{
+		`not json`,
+		`{"optional":{}}`,
+		`{"critical":{"identity":{"docker-reference":"example.com/app"},"image":{"docker-manifest-digest":"sha256:00"}}}`,
+		`{"critical":{"image":{"docker-manifest-digest":"sha256:00"},"type":"cosign container image signature"}}`,
+		`{"critical":{"identity":{"docker-reference":"example.com/app"},"type":"cosign container image signature"}}`,
+	}
+	for _, doc := range invalid {
+		_, err := FromSimpleSigning([]byte(doc), signer)
+		assert.True(t, errors.Is(err, ErrInvalidSimpleSigning), "wrong error for %s", doc)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Package sshagent implements a DSSE SignVerifier that signs with a key held by
+an SSH agent, so that developers can sign attestations with the keys they
+already use for SSH, including keys the agent keeps on hardware. Ed25519 and
+ECDSA keys are supported. The key ID is the SHA-256 fingerprint of the key,
+as printed by ssh-keygen -l and returned by dsse.SHA256KeyID.
+
+Signatures are plain Ed25519 signatures and ASN.1 DER encoded ECDSA
+signatures over the PAE, so envelopes verify with the verifiers of the dsse
+package, for example one created from an authorized_keys line with
+VerifierFromAuthorizedKey. Security key backed keys, such as
+sk-ssh-ed25519@openssh.com, sign a different message and are not supported.
+
+A signer for the agent of the current session is created with:
+
+	conn, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	signer, err := sshagent.NewSigner(agent.NewClient(conn), "me@example.com")
+*/
+package sshagent
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrKeyNotFound indicates that the agent holds no key with the requested
+// comment or fingerprint.
+var ErrKeyNotFound = errors.New("key not found in ssh agent")
+
+/*
+Signer is a dsse.SignVerifier that signs with a key of an SSH agent and
+verifies locally with its public key.
+*/
+type Signer struct {
+	agent    agent.Agent
+	key      ssh.PublicKey
+	verifier dsse.Verifier
+}
+
+/*
+NewSigner creates a Signer for the key of the agent a whose comment or
+SHA-256 fingerprint, such as "SHA256:...", is match. If several keys match,
+the first one listed by the agent is used. An error wrapping ErrKeyNotFound
+is returned if no key matches, and one wrapping dsse.ErrUnsupportedKey if the
+matching key is neither Ed25519 nor ECDSA.
+*/
+func NewSigner(a agent.Agent, match string) (*Signer, error) {
+	keys, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if key.Comment != match && ssh.FingerprintSHA256(key) != match {
+			continue
+		}
+		// The agent lists keys in wire format only.
+		pub, err := ssh.ParsePublicKey(key.Marshal())
+		if err != nil {
+			return nil, err
+		}
+		v, err := verifierForKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		return &Signer{agent: a, key: pub, verifier: v}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, match)
+}
+
+/*
+VerifierFromAuthorizedKey creates a verifier for the public key of a line in
+the authorized_keys format, such as the content of an id_ed25519.pub file.
+The key ID is the SHA-256 fingerprint of the key, as for Signer.
+*/
+func VerifierFromAuthorizedKey(line []byte) (dsse.Verifier, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(line)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifierForKey(key)
+}
+
+// Sign asks the agent to sign data.
+func (s *Signer) Sign(data []byte) ([]byte, error) {
+	sig, err := s.agent.Sign(s.key, data)
+	if err != nil {
+		return nil, err
+	}
+	if sig.Format != s.key.Type() {
+		return nil, fmt.Errorf("unexpected signature format %q", sig.Format)
+	}
+
+	if sig.Format == ssh.KeyAlgoED25519 {
+		return sig.Blob, nil
+	}
+	return ecdsaSignature(s.verifier.Public().(*ecdsa.PublicKey), sig.Blob)
+}
+
+// Verify verifies sig over data with the public key.
+func (s *Signer) Verify(data, sig []byte) error {
+	return s.verifier.Verify(data, sig)
+}
+
+// KeyID returns the SHA-256 fingerprint of the key.
+func (s *Signer) KeyID() (string, error) {
+	return s.verifier.KeyID()
+}
+
+// Public returns the public key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.verifier.Public()
+}
+
+// verifierForKey returns a dsse verifier for an Ed25519 or ECDSA SSH key.
+func verifierForKey(key ssh.PublicKey) (dsse.Verifier, error) {
+	ck, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", dsse.ErrUnsupportedKey, key.Type())
+	}
+	keyID := ssh.FingerprintSHA256(key)
+
+	switch pub := ck.CryptoPublicKey().(type) {
+	case ed25519.PublicKey:
+		return dsse.NewEd25519Verifier(keyID, pub)
+	case *ecdsa.PublicKey:
+		return dsse.NewECDSAVerifier(keyID, pub)
+	}
+
+	return nil, fmt.Errorf("%w: %s", dsse.ErrUnsupportedKey, key.Type())
+}
+
+/*
+ecdsaSignature converts the SSH encoding of an ECDSA signature, two mpints,
+to ASN.1 DER in low-S form, which the dsse ECDSA verifiers require. The agent
+hashes the message with the hash of the curve, as the dsse verifiers do by
+default.
+*/
+func ecdsaSignature(pub *ecdsa.PublicKey, blob []byte) ([]byte, error) {
+	var sig struct {
+		R *big.Int
+		S *big.Int
+	}
+	if err := ssh.Unmarshal(blob, &sig); err != nil {
+		return nil, err
+	}
+
+	n := pub.Curve.Params().N
+	if sig.S.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		sig.S = new(big.Int).Sub(n, sig.S)
+	}
+
+	return asn1.Marshal(sig)
+}
@@ -0,0 +1,73 @@
+package sshagent
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestSigner(t *testing.T) {
+	keyring := agent.NewKeyring()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, keyring.Add(agent.AddedKey{PrivateKey: edKey, Comment: "ed@example.com"}), "unexpected error")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, keyring.Add(agent.AddedKey{PrivateKey: ecKey, Comment: "ec@example.com"}), "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+	assert.Nil(t, keyring.Add(agent.AddedKey{PrivateKey: rsaKey, Comment: "rsa@example.com"}), "unexpected error")
+
+	ecPub, err := ssh.NewPublicKey(&ecKey.PublicKey)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, match := range []string{"ed@example.com", ssh.FingerprintSHA256(ecPub)} {
+		t.Run(match, func(t *testing.T) {
+			signer, err := NewSigner(keyring, match)
+			assert.Nil(t, err, "unexpected error")
+			keyID, err := signer.KeyID()
+			assert.Nil(t, err, "unexpected error")
+			fingerprint, err := dsse.SHA256KeyID(signer.Public())
+			assert.Nil(t, err, "unexpected error")
+			assert.Equal(t, fingerprint, keyID, "wrong keyid")
+
+			es, err := dsse.NewEnvelopeSigner(signer)
+			assert.Nil(t, err, "unexpected error")
+			for i := 0; i < 10; i++ {
+				env, err := es.SignPayload(dsse.PayloadTypeInToto, []byte("{}"))
+				assert.Nil(t, err, "sign failed")
+
+				sshPub, err := ssh.NewPublicKey(signer.Public())
+				assert.Nil(t, err, "unexpected error")
+				v, err := VerifierFromAuthorizedKey(ssh.MarshalAuthorizedKey(sshPub))
+				assert.Nil(t, err, "unexpected error")
+				ev, err := dsse.NewEnvelopeVerifier(v)
+				assert.Nil(t, err, "unexpected error")
+				acceptedKeys, err := ev.Verify(env)
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, keyID, acceptedKeys[0].KeyID, "wrong keyid")
+			}
+		})
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := NewSigner(keyring, "nobody@example.com")
+		assert.True(t, errors.Is(err, ErrKeyNotFound), "wrong error")
+
+		_, err = NewSigner(keyring, "rsa@example.com")
+		assert.True(t, errors.Is(err, dsse.ErrUnsupportedKey), "wrong error")
+
+		_, err = VerifierFromAuthorizedKey([]byte("not a key"))
+		assert.NotNil(t, err, "expected error")
+	})
+}
@@ -0,0 +1,130 @@
+package dsse
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/secure-systems-lab/go-securesystemslib/cjson"
+)
+
+// ErrInvalidDigest indicates that a subject digest is not hex encoded or not
+// of the length of its algorithm.
+var ErrInvalidDigest = errors.New("invalid subject digest")
+
+// ErrNoSubjects indicates that a statement would have no subjects.
+var ErrNoSubjects = errors.New("no subjects")
+
+// StatementTypeV1 is the _type of the in-toto statements built by
+// BuildStatement.
+const StatementTypeV1 = "https://in-toto.io/Statement/v1"
+
+/*
+digestSizes holds the digest size in bytes of the algorithms of the in-toto
+digest set that have a fixed size. Digests of other algorithms only need to be
+hex encoded.
+*/
+var digestSizes = map[string]int{
+	"md5":        16,
+	"sha1":       20,
+	"sha224":     28,
+	"sha256":     32,
+	"sha384":     48,
+	"sha512":     64,
+	"sha512_224": 28,
+	"sha512_256": 32,
+	"sha3_224":   28,
+	"sha3_256":   32,
+	"sha3_384":   48,
+	"sha3_512":   64,
+	"ripemd160":  20,
+	"sm3":        32,
+}
+
+type statementSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+type statement struct {
+	Type          string             `json:"_type"`
+	Subject       []statementSubject `json:"subject"`
+	PredicateType string             `json:"predicateType"`
+	Predicate     interface{}        `json:"predicate"`
+}
+
+/*
+BuildStatement returns the canonical JSON encoding of an in-toto v1
+statement about subjects, which maps the name of each artifact to its
+digests, keyed by algorithm such as "sha256" and hex encoded. The subjects
+are sorted by name and the algorithms and digests are lowercased. A digest
+that is not hex encoded, or whose length does not match its algorithm, is
+rejected with an error wrapping ErrInvalidDigest. The predicate must encode
+to JSON without floating point numbers, which canonical JSON does not allow;
+a nil predicate is encoded as an empty object.
+*/
+func BuildStatement(predicateType string, predicate interface{}, subjects map[string]map[string]string) ([]byte, error) {
+	if predicateType == "" {
+		return nil, errors.New("empty predicate type")
+	}
+	if len(subjects) == 0 {
+		return nil, ErrNoSubjects
+	}
+
+	s := statement{
+		Type:          StatementTypeV1,
+		Subject:       make([]statementSubject, 0, len(subjects)),
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	}
+	if s.Predicate == nil {
+		s.Predicate = struct{}{}
+	}
+	for name, digests := range subjects {
+		if len(digests) == 0 {
+			return nil, fmt.Errorf("%w: subject %q has no digests", ErrInvalidDigest, name)
+		}
+		normalized := normalizeDigests(digests)
+		for alg, digest := range normalized {
+			if err := validateDigest(alg, digest); err != nil {
+				return nil, fmt.Errorf("subject %q: %w", name, err)
+			}
+		}
+		s.Subject = append(s.Subject, statementSubject{Name: name, Digest: normalized})
+	}
+	sort.Slice(s.Subject, func(i, j int) bool {
+		return s.Subject[i].Name < s.Subject[j].Name
+	})
+
+	return cjson.EncodeCanonical(s)
+}
+
+/*
+BuildAndSignStatement builds an in-toto statement with BuildStatement and
+signs it with es, using PayloadTypeInToto as the payload type.
+*/
+func BuildAndSignStatement(es *EnvelopeSigner, predicateType string, predicate interface{}, subjects map[string]map[string]string) (*Envelope, error) {
+	payload, err := BuildStatement(predicateType, predicate, subjects)
+	if err != nil {
+		return nil, err
+	}
+
+	return es.SignPayload(PayloadTypeInToto, payload)
+}
+
+// validateDigest checks that digest is a hex encoded digest of alg.
+func validateDigest(alg, digest string) error {
+	if alg == "" {
+		return fmt.Errorf("%w: empty algorithm", ErrInvalidDigest)
+	}
+	raw, err := hex.DecodeString(digest)
+	if err != nil || len(raw) == 0 {
+		return fmt.Errorf("%w: %s digest %q is not hex", ErrInvalidDigest, alg, digest)
+	}
+	if size, ok := digestSizes[alg]; ok && len(raw) != size {
+		return fmt.Errorf("%w: %s digest has %d bytes, want %d", ErrInvalidDigest, alg, len(raw), size)
+	}
+
+	return nil
+}
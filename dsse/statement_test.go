@@ -0,0 +1,85 @@
+package dsse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStatement(t *testing.T) {
+	sha256 := strings.Repeat("ab", 32)
+	sha512 := strings.Repeat("cd", 64)
+
+	t.Run("Canonical", func(t *testing.T) {
+		statement, err := BuildStatement("https://slsa.dev/provenance/v1", map[string]interface{}{"builder": "ci"},
+			map[string]map[string]string{
+				"lib": {"sha256": strings.ToUpper(sha256)},
+				"app": {"SHA512": sha512, "sha256": sha256},
+			})
+		assert.Nil(t, err, "unexpected error")
+		assert.Equal(t, `{"_type":"https://in-toto.io/Statement/v1","predicate":{"builder":"ci"},`+
+			`"predicateType":"https://slsa.dev/provenance/v1","subject":[`+
+			`{"digest":{"sha256":"`+sha256+`","sha512":"`+sha512+`"},"name":"app"},`+
+			`{"digest":{"sha256":"`+sha256+`"},"name":"lib"}]}`, string(statement))
+
+		assert.Nil(t, VerifySubject(statement, map[string]string{"sha512": sha512}), "unexpected error")
+	})
+
+	t.Run("Nil predicate", func(t *testing.T) {
+		statement, err := BuildStatement("https://example.com/p", nil, map[string]map[string]string{"app": {"sha256": sha256}})
+		assert.Nil(t, err, "unexpected error")
+		assert.Contains(t, string(statement), `"predicate":{}`)
+	})
+
+	tests := []struct {
+		name     string
+		subjects map[string]map[string]string
+		err      error
+	}{
+		{"No subjects", nil, ErrNoSubjects},
+		{"No digests", map[string]map[string]string{"app": {}}, ErrInvalidDigest},
+		{"Not hex", map[string]map[string]string{"app": {"sha256": strings.Repeat("zz", 32)}}, ErrInvalidDigest},
+		{"Wrong length", map[string]map[string]string{"app": {"sha256": sha512}}, ErrInvalidDigest},
+		{"Empty digest", map[string]map[string]string{"app": {"blake2b": ""}}, ErrInvalidDigest},
+		{"Unknown algorithm", map[string]map[string]string{"app": {"blake2b": "abcd"}}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := BuildStatement("https://example.com/p", nil, test.subjects)
+			if test.err == nil {
+				assert.Nil(t, err, "unexpected error")
+				return
+			}
+			assert.True(t, errors.Is(err, test.err), "wrong error")
+		})
+	}
+
+	t.Run("Floating point predicate", func(t *testing.T) {
+		_, err := BuildStatement("https://example.com/p", map[string]interface{}{"x": 1.5},
+			map[string]map[string]string{"app": {"sha256": sha256}})
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+func TestBuildAndSignStatement(t *testing.T) {
+	sv, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+
+	subjects := map[string]map[string]string{"app": {"sha256": strings.Repeat("ab", 32)}}
+	env, err := BuildAndSignStatement(signer, "https://slsa.dev/provenance/v1", nil, subjects)
+	assert.Nil(t, err, "sign failed")
+	assert.Equal(t, PayloadTypeInToto, env.PayloadType)
+
+	_, err = signer.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	payload, err := env.DecodedPayload()
+	assert.Nil(t, err, "unexpected error")
+	want, err := BuildStatement("https://slsa.dev/provenance/v1", nil, subjects)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, want, payload)
+}
@@ -0,0 +1,225 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// ErrUnknownPayloadSize indicates that the size of a payload reader cannot be
+// determined without reading it.
+var ErrUnknownPayloadSize = errors.New("unknown payload size")
+
+// ErrStreamingUnsupported indicates that a verifier or signer cannot verify or
+// sign a streamed payload.
+var ErrStreamingUnsupported = errors.New("streaming not supported")
+
+/*
+VerifyStream verifies a detached envelope against a payload read from r. The
+Payload field of the envelope is ignored. The size of the payload must be
+known up front, as it is part of the pre-authentication encoding: r must be
+an io.Seeker or implement Len() int, like *os.File, *bytes.Reader and
+*strings.Reader do. Otherwise ErrUnknownPayloadSize is returned.
+
+The payload is read once and hashed incrementally for every PrehashVerifier.
+If any verifier is not a PrehashVerifier, the pre-authentication encoding is
+additionally buffered in memory for it.
+*/
+func (ev *envelopeVerifier) VerifyStream(e *Envelope, r io.Reader) ([]AcceptedKey, error) {
+	if ev.opts.auditSink != nil {
+		var event VerifyEvent
+		if e != nil {
+			detached := *e
+			detached.Payload = ""
+			event = ev.opts.envelopeEvent(&detached)
+		}
+		return ev.audit(event, func(av *envelopeVerifier) ([]AcceptedKey, error) {
+			return av.VerifyStream(e, r)
+		})
+	}
+
+	if e == nil {
+		return nil, ErrNoEnvelopes
+	}
+	if len(e.Signatures) == 0 {
+		return nil, ErrNoSignature
+	}
+	if err := ev.opts.checkSignatureCount(len(e.Signatures)); err != nil {
+		return nil, err
+	}
+	if err := e.checkAAD(nil); err != nil {
+		return nil, err
+	}
+
+	payloadType := ev.opts.normalizePayloadType(e.PayloadType)
+	if err := ev.opts.checkPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+
+	size, err := payloadSize(r)
+	if err != nil {
+		return nil, err
+	}
+	if ev.opts.maxPayloadSize > 0 && size > ev.opts.maxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	// The verifiers must be known to pick the hashes of the payload.
+	if ev, err = ev.forSignatures(e.Signatures); err != nil {
+		return nil, err
+	}
+
+	msg, err := ev.streamMessage(payloadType, r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return ev.verifySignatures(msg, e.Signatures)
+}
+
+// VerifyStream verifies a detached envelope against a payload read from r.
+// See the VerifyStream method of the envelope verifier.
+func (es *EnvelopeSigner) VerifyStream(e *Envelope, r io.Reader) ([]AcceptedKey, error) {
+	return es.ev.VerifyStream(e, r)
+}
+
+/*
+SignPayloadReader signs a payload read from r without holding it in memory.
+It returns a detached envelope, whose Payload field is empty, which is
+verified against the payload with VerifyStream. The size of the payload must
+be known up front, as for VerifyStream.
+Only signers that hash the message before signing it can sign a stream. Among
+the bundled signers, these are ECDSASignerVerifier, RSAPSSSignerVerifier and
+Ed25519SignerVerifier with WithEd25519ph. If any signer is not a
+PrehashSigner, ErrStreamingUnsupported is returned before r is read.
+*/
+func (es *EnvelopeSigner) SignPayloadReader(payloadType string, r io.Reader) (*Envelope, error) {
+	payloadType = es.opts.normalizePayloadType(payloadType)
+	if err := ValidatePayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	if err := es.checkSignerPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+
+	var hashes []crypto.Hash
+	for _, signer := range es.providers {
+		ps, ok := prehashSigner(signer)
+		if !ok {
+			return nil, fmt.Errorf("%w: %T", ErrStreamingUnsupported, signer)
+		}
+		hashes = append(hashes, ps.HashFunc())
+	}
+
+	size, err := payloadSize(r)
+	if err != nil {
+		return nil, err
+	}
+	es.opts.notePayloadType(payloadType)
+
+	msg, err := readMessage(payloadType, r, size, hashes, false)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := es.signMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Envelope{
+		PayloadType: payloadType,
+		Signatures:  signatures,
+	}, nil
+}
+
+// streamMessage reads the payload once, hashing it for every verifier.
+func (ev *envelopeVerifier) streamMessage(payloadType string, r io.Reader, size int64) (*message, error) {
+	var hashes []crypto.Hash
+	full := false
+	for _, v := range ev.providers {
+		if pv, ok := prehashVerifier(v); ok {
+			hashes = append(hashes, pv.HashFunc())
+		} else {
+			full = true
+		}
+	}
+
+	return readMessage(payloadType, r, size, hashes, full)
+}
+
+/*
+readMessage reads a payload of the given size from r and computes the digest
+of its pre-authentication encoding for each of hashes. If full is true, the
+encoding itself is kept as well.
+*/
+func readMessage(payloadType string, r io.Reader, size int64, hashes []crypto.Hash, full bool) (*message, error) {
+	hashers := make(map[crypto.Hash]hash.Hash)
+	for _, h := range hashes {
+		if !h.Available() {
+			return nil, fmt.Errorf("hash %v unavailable", h)
+		}
+		if _, ok := hashers[h]; !ok {
+			hashers[h] = h.New()
+		}
+	}
+	var buf *bytes.Buffer
+	if full {
+		buf = &bytes.Buffer{}
+	}
+
+	var writers []io.Writer
+	for _, h := range hashers {
+		writers = append(writers, h)
+	}
+	if buf != nil {
+		writers = append(writers, buf)
+	}
+	w := io.MultiWriter(writers...)
+
+	if _, err := w.Write(appendPAEHeader(nil, payloadType, size)); err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return nil, err
+	}
+	if n != size {
+		return nil, fmt.Errorf("payload size changed while reading: expected %d bytes, read %d", size, n)
+	}
+
+	msg := &message{digests: make(map[crypto.Hash][]byte)}
+	for hf, h := range hashers {
+		msg.digests[hf] = h.Sum(nil)
+	}
+	if buf != nil {
+		msg.pae = buf.Bytes()
+	}
+
+	return msg, nil
+}
+
+// payloadSize returns the number of bytes remaining in r.
+func payloadSize(r io.Reader) (int64, error) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len()), nil
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := v.Seek(cur, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return end - cur, nil
+	}
+
+	return 0, ErrUnknownPayloadSize
+}
@@ -0,0 +1,169 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyStream(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = bytes.Repeat([]byte("hello world "), 1000)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	ecSV, err := NewECDSASignerVerifier("ec", ecKey)
+	assert.Nil(t, err, "unexpected error")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+	rsaSV, err := NewRSAPSSSignerVerifier("rsa", rsaKey)
+	assert.Nil(t, err, "unexpected error")
+
+	edSV, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+
+	signer, err := NewMultiEnvelopeSigner(3, ecSV, rsaSV, edSV)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	detached := *env
+	detached.Payload = ""
+
+	t.Run("bytes reader", func(t *testing.T) {
+		acceptedKeys, err := signer.VerifyStream(&detached, bytes.NewReader(payload))
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 3, "unexpected keys")
+	})
+
+	t.Run("prehash only", func(t *testing.T) {
+		ev, err := NewMultiEnvelopeVerifier(2, ecSV, rsaSV)
+		assert.Nil(t, err, "unexpected error")
+
+		acceptedKeys, err := ev.VerifyStream(&detached, bytes.NewReader(payload))
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2, "unexpected keys")
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "payload")
+		assert.Nil(t, os.WriteFile(path, payload, 0600), "unexpected error")
+		f, err := os.Open(path)
+		assert.Nil(t, err, "unexpected error")
+		defer f.Close()
+
+		acceptedKeys, err := signer.VerifyStream(&detached, f)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 3, "unexpected keys")
+	})
+
+	t.Run("tampered payload", func(t *testing.T) {
+		tampered := append([]byte{}, payload...)
+		tampered[0] = 'H'
+
+		_, err := signer.VerifyStream(&detached, bytes.NewReader(tampered))
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("unknown size", func(t *testing.T) {
+		_, err := signer.VerifyStream(&detached, io.MultiReader(strings.NewReader(string(payload))))
+		assert.Equal(t, ErrUnknownPayloadSize, err, "wrong error")
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ecSV}, WithMaxPayloadSize(10))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.VerifyStream(&detached, bytes.NewReader(payload))
+		assert.Equal(t, ErrPayloadTooLarge, err, "wrong error")
+	})
+
+	t.Run("no signatures", func(t *testing.T) {
+		_, err := signer.VerifyStream(&Envelope{PayloadType: payloadType}, bytes.NewReader(payload))
+		assert.Equal(t, ErrNoSignature, err, "wrong error")
+	})
+}
+
+func TestPayloadSize(t *testing.T) {
+	r := strings.NewReader("hello world")
+	_, err := r.Seek(6, io.SeekStart)
+	assert.Nil(t, err, "unexpected error")
+
+	size, err := payloadSize(r)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, int64(5), size, "wrong size")
+
+	path := filepath.Join(t.TempDir(), "payload")
+	assert.Nil(t, os.WriteFile(path, []byte("hello world"), 0600), "unexpected error")
+	f, err := os.Open(path)
+	assert.Nil(t, err, "unexpected error")
+	defer f.Close()
+	_, err = f.Seek(6, io.SeekStart)
+	assert.Nil(t, err, "unexpected error")
+
+	size, err = payloadSize(f)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, int64(5), size, "wrong size")
+
+	rest, err := io.ReadAll(f)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "world", string(rest), "seek position not restored")
+}
+
+func TestSignPayloadReader(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = strings.Repeat("hello world ", 1000)
+
+	ec, err := NewECDSASignerVerifier("ec", newEcdsaKey())
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+	pss, err := NewRSAPSSSignerVerifier("pss", rsaKey)
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewMultiEnvelopeSigner(2, ec, pss)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayloadReader(payloadType, strings.NewReader(payload))
+	assert.Nil(t, err, "sign failed")
+	assert.Empty(t, env.Payload, "payload not detached")
+	assert.Len(t, env.Signatures, 2, "wrong number of signatures")
+
+	acceptedKeys, err := signer.VerifyStream(env, strings.NewReader(payload))
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 2, "unexpected keys")
+
+	inline, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+	env.Payload = inline.Payload
+	_, err = signer.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("Unknown size", func(t *testing.T) {
+		_, err := signer.SignPayloadReader(payloadType, io.MultiReader(strings.NewReader(payload)))
+		assert.Equal(t, ErrUnknownPayloadSize, err, "wrong error")
+	})
+
+	t.Run("Signer needs full message", func(t *testing.T) {
+		ed, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+		assert.Nil(t, err, "unexpected error")
+		signer, err := NewEnvelopeSigner(ec, ed)
+		assert.Nil(t, err, "unexpected error")
+
+		r := strings.NewReader(payload)
+		_, err = signer.SignPayloadReader(payloadType, r)
+		assert.True(t, errors.Is(err, ErrStreamingUnsupported), "wrong error")
+		assert.Equal(t, len(payload), r.Len(), "payload read")
+	})
+}
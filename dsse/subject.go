@@ -0,0 +1,118 @@
+package dsse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSubjectMismatch indicates that no subject of an in-toto statement
+// matches the digests of an artifact.
+var ErrSubjectMismatch = errors.New("no matching subject")
+
+// ErrWeakDigest indicates that an artifact only matches a subject of an
+// in-toto statement by a SHA-1 digest, which is not accepted by default.
+var ErrWeakDigest = errors.New("subject only matches by sha1 digest")
+
+// SubjectOption configures VerifySubject.
+type SubjectOption func(*subjectOptions)
+
+type subjectOptions struct {
+	allowSHA1 bool
+}
+
+/*
+WithSHA1Subjects makes VerifySubject accept subjects that only match by a
+SHA-1 digest, for statements about legacy artifacts. SHA-1 is not collision
+resistant, so this should only be used when no stronger digest is available.
+*/
+func WithSHA1Subjects() SubjectOption {
+	return func(o *subjectOptions) {
+		o.allowSHA1 = true
+	}
+}
+
+// statementSubjects is the part of an in-toto statement naming its subjects.
+type statementSubjects struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+/*
+VerifySubject checks that an artifact with the given digests, keyed by
+algorithm such as "sha256", "sha512" or "sha1" and hex encoded, is a subject
+of the in-toto statement, typically the decoded payload of a verified
+envelope. A subject matches if the algorithms present both in the subject
+and in digests agree, and there is at least one such algorithm. Algorithms
+present on only one side are ignored. A subject that agrees only on a SHA-1
+digest is rejected with ErrWeakDigest unless WithSHA1Subjects is given. If no
+subject matches, an error wrapping ErrSubjectMismatch is returned. Algorithm
+names and hex digests are compared case-insensitively.
+*/
+func VerifySubject(statement []byte, digests map[string]string, opts ...SubjectOption) error {
+	var o subjectOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+
+	var s statementSubjects
+	if err := json.Unmarshal(statement, &s); err != nil {
+		return err
+	}
+
+	want := normalizeDigests(digests)
+	weak := false
+	for _, subject := range s.Subject {
+		strong, ok := compareDigests(normalizeDigests(subject.Digest), want)
+		if !ok {
+			continue
+		}
+		if strong || o.allowSHA1 {
+			return nil
+		}
+		weak = true
+	}
+
+	if weak {
+		return ErrWeakDigest
+	}
+	return fmt.Errorf("%w among %d subjects", ErrSubjectMismatch, len(s.Subject))
+}
+
+// normalizeDigests lowercases the algorithms and the hex digests.
+func normalizeDigests(digests map[string]string) map[string]string {
+	normalized := make(map[string]string, len(digests))
+	for alg, digest := range digests {
+		normalized[strings.ToLower(alg)] = strings.ToLower(digest)
+	}
+
+	return normalized
+}
+
+/*
+compareDigests reports whether the digests agree on every algorithm present
+in both and there is at least one, and whether an algorithm other than SHA-1
+is among them.
+*/
+func compareDigests(subject, want map[string]string) (strong, ok bool) {
+	for alg, digest := range want {
+		other, present := subject[alg]
+		if !present {
+			continue
+		}
+		if other != digest {
+			return false, false
+		}
+		ok = true
+		if alg != "sha1" {
+			strong = true
+		}
+	}
+
+	return strong, ok
+}
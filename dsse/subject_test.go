@@ -0,0 +1,55 @@
+package dsse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySubject(t *testing.T) {
+	var statement = []byte(`{
+		"_type": "https://in-toto.io/Statement/v1",
+		"subject": [
+			{"name": "app", "digest": {"sha256": "aaaa", "sha512": "bbbb"}},
+			{"name": "legacy", "digest": {"sha1": "cccc"}},
+			{"name": "lib", "digest": {"sha256": "dddd", "sha1": "eeee"}}
+		],
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"predicate": {}
+	}`)
+
+	tests := []struct {
+		name    string
+		digests map[string]string
+		opts    []SubjectOption
+		err     error
+	}{
+		{"All algorithms", map[string]string{"sha256": "aaaa", "sha512": "bbbb"}, nil, nil},
+		{"Common algorithm", map[string]string{"sha512": "bbbb", "md5": "ffff"}, nil, nil},
+		{"Case", map[string]string{"SHA256": "AAAA"}, nil, nil},
+		{"Mismatch", map[string]string{"sha256": "aaaa", "sha512": "0000"}, nil, ErrSubjectMismatch},
+		{"No common algorithm", map[string]string{"md5": "aaaa"}, nil, ErrSubjectMismatch},
+		{"SHA-1 only", map[string]string{"sha1": "cccc"}, nil, ErrWeakDigest},
+		{"SHA-1 allowed", map[string]string{"sha1": "cccc"}, []SubjectOption{WithSHA1Subjects()}, nil},
+		{"SHA-1 and SHA-256", map[string]string{"sha1": "eeee", "sha256": "dddd"}, nil, nil},
+		{"SHA-1 mismatch", map[string]string{"sha1": "0000", "sha256": "dddd"}, nil, ErrSubjectMismatch},
+		{"No digests", nil, nil, ErrSubjectMismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := VerifySubject(statement, test.digests, test.opts...)
+			if test.err == nil {
+				assert.Nil(t, err, "unexpected error")
+				return
+			}
+			assert.True(t, errors.Is(err, test.err), "wrong error")
+		})
+	}
+
+	t.Run("Not JSON", func(t *testing.T) {
+		err := VerifySubject([]byte("not json"), map[string]string{"sha256": "aaaa"})
+		assert.NotNil(t, err, "expected error")
+	})
+}
@@ -0,0 +1,386 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ErrNoTimestamp indicates that a signature carries no signing time.
+var ErrNoTimestamp = errors.New("no timestamp")
+
+// ErrInvalidTimestamp indicates that a timestamp extension cannot be parsed
+// or does not belong to the signature.
+var ErrInvalidTimestamp = errors.New("invalid timestamp")
+
+// Signature extensions that record the signing time.
+const (
+	// ExtensionTimestamp holds the base64 encoded DER of an RFC 3161
+	// TimeStampToken over the raw signature bytes.
+	ExtensionTimestamp = "timestamp"
+	// ExtensionSignedAt holds the signing time claimed by the signer, in
+	// RFC 3339 format.
+	ExtensionSignedAt = "signedAt"
+)
+
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidRSASSAPSS     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+
+	timestampHashes = map[string]crypto.Hash{
+		"1.3.14.3.2.26":          crypto.SHA1,
+		"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+		"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+		"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+	}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapContentInfo
+	Certificates     rawContent   `asn1:"optional,tag:0"`
+	CRLs             rawContent   `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,tag:0"`
+}
+
+// rawContent captures an optional implicitly tagged element, including its
+// tag and length.
+type rawContent struct {
+	Raw asn1.RawContent
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        rawContent `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      rawContent `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+// timeStampToken is a parsed RFC 3161 TimeStampToken.
+type timeStampToken struct {
+	signedData signedData
+	info       tstInfo
+}
+
+/*
+UnverifiedSigningTime returns the time at which the signature claims to have
+been made. If the signature carries an RFC 3161 timestamp token in the
+ExtensionTimestamp extension, the time asserted in the token is returned,
+after checking that the token covers the signature. Otherwise the time
+claimed in the ExtensionSignedAt extension is returned. If neither is
+present, ErrNoTimestamp is returned.
+
+Neither extension is covered by the signature, so anyone relaying the
+envelope can change them, and the signature of the timestamp authority over
+the token is not verified. The time is only suitable for display; use
+VerifySigningTime for policy decisions.
+*/
+func (s Signature) UnverifiedSigningTime() (time.Time, error) {
+	if raw, ok := s.Extensions[ExtensionTimestamp]; ok {
+		token, err := s.timestampToken(raw)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return token.info.GenTime, nil
+	}
+
+	if raw, ok := s.Extensions[ExtensionSignedAt]; ok {
+		var signedAt string
+		if err := json.Unmarshal(raw, &signedAt); err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s: %v", ErrInvalidTimestamp, ExtensionSignedAt, err)
+		}
+		t, err := time.Parse(time.RFC3339, signedAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s: %v", ErrInvalidTimestamp, ExtensionSignedAt, err)
+		}
+		return t, nil
+	}
+
+	return time.Time{}, ErrNoTimestamp
+}
+
+/*
+VerifySigningTime returns the time asserted by the RFC 3161 timestamp token
+in the ExtensionTimestamp extension, after verifying that the token covers
+the signature and is signed by a timestamp authority whose certificate chains
+to roots and is valid for time stamping at that time. Intermediate
+certificates are taken from the token. The unsigned ExtensionSignedAt
+extension is ignored; if there is no token, ErrNoTimestamp is returned.
+*/
+func (s Signature) VerifySigningTime(roots *x509.CertPool) (time.Time, error) {
+	raw, ok := s.Extensions[ExtensionTimestamp]
+	if !ok {
+		return time.Time{}, ErrNoTimestamp
+	}
+	token, err := s.timestampToken(raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := token.verify(roots); err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+
+	return token.info.GenTime, nil
+}
+
+// timestampToken parses the RFC 3161 token in raw and checks that it covers
+// the signature.
+func (s Signature) timestampToken(raw json.RawMessage) (*timeStampToken, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+
+	token, err := parseTimeStampToken(der)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTimestamp, err)
+	}
+
+	imprint := token.info.MessageImprint
+	hash, ok := timestampHashes[imprint.HashAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() {
+		return nil, fmt.Errorf("%w: unsupported hash %v", ErrInvalidTimestamp, imprint.HashAlgorithm.Algorithm)
+	}
+	sig, err := b64Decode(s.Sig)
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(sig)
+	if !bytes.Equal(h.Sum(nil), imprint.HashedMessage) {
+		return nil, fmt.Errorf("%w: token does not cover the signature", ErrInvalidTimestamp)
+	}
+
+	return token, nil
+}
+
+// parseTimeStampToken parses a DER encoded TimeStampToken.
+func parseTimeStampToken(der []byte) (*timeStampToken, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after token")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("token is not signed data")
+	}
+
+	var token timeStampToken
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &token.signedData); err != nil {
+		return nil, err
+	}
+	encap := token.signedData.EncapContentInfo
+	if !encap.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("token does not contain TSTInfo")
+	}
+	if _, err := asn1.Unmarshal(encap.EContent, &token.info); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+/*
+verify checks the CMS signature of the token: its single signer must have
+signed the content type and the digest of the TSTInfo with the key of a
+certificate in the token, and that certificate must chain to roots and be
+valid for time stamping at the time asserted by the token.
+*/
+func (t *timeStampToken) verify(roots *x509.CertPool) error {
+	if len(t.signedData.SignerInfos) != 1 {
+		return fmt.Errorf("token has %d signers, want 1", len(t.signedData.SignerInfos))
+	}
+	si := t.signedData.SignerInfos[0]
+
+	var certs []*x509.Certificate
+	if raw := t.signedData.Certificates.Raw; len(raw) > 0 {
+		var set asn1.RawValue
+		if _, err := asn1.Unmarshal(raw, &set); err != nil {
+			return err
+		}
+		var err error
+		if certs, err = x509.ParseCertificates(set.Bytes); err != nil {
+			return err
+		}
+	}
+	signer, err := si.signerCertificate(certs)
+	if err != nil {
+		return err
+	}
+
+	hash, ok := timestampHashes[si.DigestAlgorithm.Algorithm.String()]
+	if !ok || !hash.Available() || hash == crypto.SHA1 {
+		return fmt.Errorf("unsupported signer digest %v", si.DigestAlgorithm.Algorithm)
+	}
+	signed, err := si.checkSignedAttrs(hash, t.signedData.EncapContentInfo.EContent)
+	if err != nil {
+		return err
+	}
+	if err := checkCMSSignature(signer.PublicKey, si.SignatureAlgorithm, hash, signed, si.Signature); err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		if c != signer {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   t.info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	return err
+}
+
+// signerCertificate returns the certificate among certs identified by the
+// signer identifier of si.
+func (si signerInfo) signerCertificate(certs []*x509.Certificate) (*x509.Certificate, error) {
+	for _, c := range certs {
+		switch {
+		case si.SID.Class == asn1.ClassUniversal && si.SID.Tag == asn1.TagSequence:
+			var ias issuerAndSerialNumber
+			if _, err := asn1.Unmarshal(si.SID.FullBytes, &ias); err != nil {
+				return nil, err
+			}
+			if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.SerialNumber) == 0 {
+				return c, nil
+			}
+		case si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0:
+			if len(c.SubjectKeyId) > 0 && bytes.Equal(c.SubjectKeyId, si.SID.Bytes) {
+				return c, nil
+			}
+		}
+	}
+
+	return nil, errors.New("signer certificate not found in token")
+}
+
+/*
+checkSignedAttrs checks that the signed attributes of si name the TSTInfo
+content type and carry the digest of content, and returns their encoding as
+it is signed, with the SET OF tag in place of the implicit tag.
+*/
+func (si signerInfo) checkSignedAttrs(hash crypto.Hash, content []byte) ([]byte, error) {
+	if len(si.SignedAttrs.Raw) == 0 {
+		return nil, errors.New("missing signed attributes")
+	}
+	signed := append([]byte(nil), si.SignedAttrs.Raw...)
+	signed[0] = 0x31
+
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return nil, err
+	}
+
+	var contentType asn1.ObjectIdentifier
+	var digest []byte
+	for _, a := range attrs {
+		var err error
+		switch {
+		case a.Type.Equal(oidContentType):
+			_, err = asn1.Unmarshal(a.Values.Bytes, &contentType)
+		case a.Type.Equal(oidMessageDigest):
+			_, err = asn1.Unmarshal(a.Values.Bytes, &digest)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return nil, errors.New("signed content type is not TSTInfo")
+	}
+	h := hash.New()
+	h.Write(content)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return nil, errors.New("message digest does not match TSTInfo")
+	}
+
+	return signed, nil
+}
+
+// checkCMSSignature verifies a CMS signature over data with pub.
+func checkCMSSignature(pub crypto.PublicKey, alg pkix.AlgorithmIdentifier, hash crypto.Hash, data, sig []byte) error {
+	if k, ok := pub.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(k, data, sig) {
+			return ErrSignatureInvalid
+		}
+		return nil
+	}
+
+	h := hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, digest, sig) {
+			return ErrSignatureInvalid
+		}
+		return nil
+	case *rsa.PublicKey:
+		if alg.Algorithm.Equal(oidRSASSAPSS) {
+			return rsa.VerifyPSS(k, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto})
+		}
+		return rsa.VerifyPKCS1v15(k, hash, digest, sig)
+	}
+
+	return fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
@@ -0,0 +1,274 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTSA is a timestamp authority with a self-signed root.
+type testTSA struct {
+	roots *x509.CertPool
+	cert  *x509.Certificate
+	key   *ecdsa.PrivateKey
+}
+
+func newTestTSA(t *testing.T) *testTSA {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rootTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test tsa root"},
+		NotBefore:             time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:              time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTmpl, rootTmpl, &rootKey.PublicKey, rootKey)
+	assert.Nil(t, err, "unexpected error")
+	root, err := x509.ParseCertificate(rootDER)
+	assert.Nil(t, err, "unexpected error")
+
+	tsa := &testTSA{roots: x509.NewCertPool()}
+	tsa.roots.AddCert(root)
+	tsa.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test tsa"},
+		NotBefore:    rootTmpl.NotBefore,
+		NotAfter:     rootTmpl.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, root, &tsa.key.PublicKey, rootKey)
+	assert.Nil(t, err, "unexpected error")
+	tsa.cert, err = x509.ParseCertificate(der)
+	assert.Nil(t, err, "unexpected error")
+
+	return tsa
+}
+
+/*
+newTimeStampToken builds an RFC 3161 token over sig. It is signed by tsa, or
+unsigned if tsa is nil.
+*/
+func newTimeStampToken(t *testing.T, tsa *testTSA, sig []byte, genTime time.Time) json.RawMessage {
+	sha256OID := asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	digest := sha256.Sum256(sig)
+	info, err := asn1.Marshal(tstInfo{
+		Version: 1,
+		Policy:  asn1.ObjectIdentifier{1, 2, 3},
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: sha256OID},
+			HashedMessage: digest[:],
+		},
+		SerialNumber: big.NewInt(42),
+		GenTime:      genTime,
+	})
+	assert.Nil(t, err, "unexpected error")
+
+	var certificates asn1.RawValue
+	signerInfos := []asn1.RawValue{}
+	if tsa != nil {
+		certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: tsa.cert.Raw}
+
+		infoDigest := sha256.Sum256(info)
+		contentType, err := asn1.Marshal(oidTSTInfo)
+		assert.Nil(t, err, "unexpected error")
+		messageDigest, err := asn1.Marshal(infoDigest[:])
+		assert.Nil(t, err, "unexpected error")
+		signed, err := asn1.MarshalWithParams([]attribute{
+			{Type: oidContentType, Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: contentType}},
+			{Type: oidMessageDigest, Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: messageDigest}},
+		}, "set")
+		assert.Nil(t, err, "unexpected error")
+		signedDigest := sha256.Sum256(signed)
+		signature, err := ecdsa.SignASN1(rand.Reader, tsa.key, signedDigest[:])
+		assert.Nil(t, err, "sign failed")
+
+		sid, err := asn1.Marshal(issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: tsa.cert.RawIssuer},
+			SerialNumber: tsa.cert.SerialNumber,
+		})
+		assert.Nil(t, err, "unexpected error")
+		implicit := append([]byte{0xa0}, signed[1:]...)
+		si, err := asn1.Marshal(struct {
+			Version            int
+			SID                asn1.RawValue
+			DigestAlgorithm    pkix.AlgorithmIdentifier
+			SignedAttrs        asn1.RawValue
+			SignatureAlgorithm pkix.AlgorithmIdentifier
+			Signature          []byte
+		}{
+			Version:            1,
+			SID:                asn1.RawValue{FullBytes: sid},
+			DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: sha256OID},
+			SignedAttrs:        asn1.RawValue{FullBytes: implicit},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+			Signature:          signature,
+		})
+		assert.Nil(t, err, "unexpected error")
+		signerInfos = append(signerInfos, asn1.RawValue{FullBytes: si})
+	}
+
+	sd, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo encapContentInfo
+		Certificates     asn1.RawValue   `asn1:"optional"`
+		SignerInfos      []asn1.RawValue `asn1:"set"`
+	}{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{},
+		EncapContentInfo: encapContentInfo{EContentType: oidTSTInfo, EContent: info},
+		Certificates:     certificates,
+		SignerInfos:      signerInfos,
+	})
+	assert.Nil(t, err, "unexpected error")
+
+	token, err := asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+	assert.Nil(t, err, "unexpected error")
+
+	raw, err := json.Marshal(base64.StdEncoding.EncodeToString(token))
+	assert.Nil(t, err, "unexpected error")
+	return raw
+}
+
+func TestUnverifiedSigningTime(t *testing.T) {
+	sig := []byte("signature")
+	genTime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	t.Run("Timestamp token", func(t *testing.T) {
+		s := Signature{
+			Sig: base64.StdEncoding.EncodeToString(sig),
+			Extensions: map[string]json.RawMessage{
+				ExtensionTimestamp: newTimeStampToken(t, nil, sig, genTime),
+				ExtensionSignedAt:  json.RawMessage(`"2000-01-01T00:00:00Z"`),
+			},
+		}
+		got, err := s.UnverifiedSigningTime()
+		assert.Nil(t, err, "unexpected error")
+		assert.True(t, genTime.Equal(got), "wrong time")
+	})
+
+	t.Run("Token for other signature", func(t *testing.T) {
+		s := Signature{
+			Sig: base64.StdEncoding.EncodeToString([]byte("other")),
+			Extensions: map[string]json.RawMessage{
+				ExtensionTimestamp: newTimeStampToken(t, nil, sig, genTime),
+			},
+		}
+		_, err := s.UnverifiedSigningTime()
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		s := Signature{
+			Sig: base64.StdEncoding.EncodeToString(sig),
+			Extensions: map[string]json.RawMessage{
+				ExtensionTimestamp: json.RawMessage(`"AAAA"`),
+			},
+		}
+		_, err := s.UnverifiedSigningTime()
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Signed at", func(t *testing.T) {
+		s := Signature{
+			Extensions: map[string]json.RawMessage{
+				ExtensionSignedAt: json.RawMessage(`"2023-04-05T06:07:08Z"`),
+			},
+		}
+		got, err := s.UnverifiedSigningTime()
+		assert.Nil(t, err, "unexpected error")
+		assert.True(t, genTime.Equal(got), "wrong time")
+
+		s.Extensions[ExtensionSignedAt] = json.RawMessage(`"yesterday"`)
+		_, err = s.UnverifiedSigningTime()
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("No timestamp", func(t *testing.T) {
+		_, err := Signature{Sig: "c2ln"}.UnverifiedSigningTime()
+		assert.Equal(t, ErrNoTimestamp, err, "wrong error")
+	})
+}
+
+func TestVerifySigningTime(t *testing.T) {
+	sig := []byte("signature")
+	genTime := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	tsa := newTestTSA(t)
+
+	timestamped := func(token json.RawMessage) Signature {
+		return Signature{
+			Sig: base64.StdEncoding.EncodeToString(sig),
+			Extensions: map[string]json.RawMessage{
+				ExtensionTimestamp: token,
+				ExtensionSignedAt:  json.RawMessage(`"2000-01-01T00:00:00Z"`),
+			},
+		}
+	}
+
+	got, err := timestamped(newTimeStampToken(t, tsa, sig, genTime)).VerifySigningTime(tsa.roots)
+	assert.Nil(t, err, "unexpected error")
+	assert.True(t, genTime.Equal(got), "wrong time")
+
+	t.Run("Unsigned token", func(t *testing.T) {
+		_, err := timestamped(newTimeStampToken(t, nil, sig, genTime)).VerifySigningTime(tsa.roots)
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Untrusted authority", func(t *testing.T) {
+		_, err := timestamped(newTimeStampToken(t, newTestTSA(t), sig, genTime)).VerifySigningTime(tsa.roots)
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Token for other signature", func(t *testing.T) {
+		_, err := timestamped(newTimeStampToken(t, tsa, []byte("other"), genTime)).VerifySigningTime(tsa.roots)
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Tampered time", func(t *testing.T) {
+		var encoded string
+		assert.Nil(t, json.Unmarshal(newTimeStampToken(t, tsa, sig, genTime), &encoded), "unexpected error")
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		assert.Nil(t, err, "unexpected error")
+		i := bytes.Index(der, []byte("20230405060708Z"))
+		assert.True(t, i >= 0, "time not found")
+		copy(der[i:], "2024")
+		token, err := json.Marshal(base64.StdEncoding.EncodeToString(der))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = timestamped(token).VerifySigningTime(tsa.roots)
+		assert.True(t, errors.Is(err, ErrInvalidTimestamp), "wrong error")
+	})
+
+	t.Run("Signed at only", func(t *testing.T) {
+		s := Signature{
+			Sig:        base64.StdEncoding.EncodeToString(sig),
+			Extensions: map[string]json.RawMessage{ExtensionSignedAt: json.RawMessage(`"2023-04-05T06:07:08Z"`)},
+		}
+		_, err := s.VerifySigningTime(tsa.roots)
+		assert.Equal(t, ErrNoTimestamp, err, "wrong error")
+	})
+}
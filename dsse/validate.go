@@ -0,0 +1,331 @@
+package dsse
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidEnvelope indicates that an envelope is structurally invalid.
+var ErrInvalidEnvelope = errors.New("invalid envelope")
+
+var (
+	errMissing      = errors.New("missing")
+	errNotString    = errors.New("not a string")
+	errUnknownField = errors.New("unknown field")
+)
+
+// Fields allowed by DecodeEnvelopeStrict.
+var (
+	envelopeFields  = map[string]bool{"payloadType": true, "payload": true, "payloadEncoding": true, "aad": true, "signatures": true}
+	signatureFields = map[string]bool{"keyid": true, "sig": true, "extensions": true}
+)
+
+/*
+ValidationError describes a structural problem with a field of an envelope.
+It matches ErrInvalidEnvelope with errors.Is.
+*/
+type ValidationError struct {
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("invalid envelope: %v", e.Err)
+	}
+	return fmt.Sprintf("invalid envelope: %s: %v", e.Field, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidEnvelope
+}
+
+/*
+ValidateEnvelopeJSON checks that data is a structurally valid JSON envelope:
+payloadType and payload are present, payload is valid base64, and signatures
+is a non-empty array of objects that each carry a keyid or a sig, with sig
+being valid base64. No cryptographic verification is performed, which makes
+this a cheap check to run on untrusted input before verification.
+The returned error is a *ValidationError.
+*/
+func ValidateEnvelopeJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	if _, err := stringField(fields, "payloadType"); err != nil {
+		return err
+	}
+
+	payload, err := stringField(fields, "payload")
+	if err != nil {
+		return err
+	}
+	var encoding string
+	if _, ok := fields["payloadEncoding"]; ok {
+		if encoding, err = stringField(fields, "payloadEncoding"); err != nil {
+			return err
+		}
+	}
+	if _, err := decodePayload(payload, encoding); err != nil {
+		if err == ErrUnknownPayloadEncoding {
+			return &ValidationError{Field: "payloadEncoding", Err: err}
+		}
+		return &ValidationError{Field: "payload", Err: err}
+	}
+
+	if _, ok := fields["aad"]; ok {
+		aad, err := stringField(fields, "aad")
+		if err != nil {
+			return err
+		}
+		if _, err := b64Decode(aad); err != nil {
+			return &ValidationError{Field: "aad", Err: err}
+		}
+	}
+
+	raw, ok := fields["signatures"]
+	if !ok {
+		return &ValidationError{Field: "signatures", Err: errMissing}
+	}
+	var signatures []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &signatures); err != nil {
+		return &ValidationError{Field: "signatures", Err: err}
+	}
+	if len(signatures) == 0 {
+		return &ValidationError{Field: "signatures", Err: ErrNoSignature}
+	}
+
+	for i, s := range signatures {
+		field := fmt.Sprintf("signatures[%d]", i)
+		if s == nil {
+			return &ValidationError{Field: field, Err: errors.New("not an object")}
+		}
+
+		_, hasKeyID := s["keyid"]
+		_, hasSig := s["sig"]
+		if !hasKeyID && !hasSig {
+			return &ValidationError{Field: field, Err: errors.New("neither keyid nor sig present")}
+		}
+
+		if hasKeyID {
+			if _, err := stringField(s, "keyid"); err != nil {
+				err.Field = field + ".keyid"
+				return err
+			}
+		}
+		if hasSig {
+			sig, err := stringField(s, "sig")
+			if err != nil {
+				err.Field = field + ".sig"
+				return err
+			}
+			if _, err := b64Decode(sig); err != nil {
+				return &ValidationError{Field: field + ".sig", Err: err}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Validate checks that the envelope is structurally valid without any
+cryptographic verification: payloadType is present and valid as checked by
+ValidatePayloadType, payload and the additional authenticated data are valid
+base64, and there is at least one signature, each with a valid base64 sig.
+It is the counterpart of ValidateEnvelopeJSON for an envelope that was
+already unmarshaled. The first problem found is returned as a
+*ValidationError.
+*/
+func (e *Envelope) Validate() error {
+	if e.PayloadType == "" {
+		return &ValidationError{Field: "payloadType", Err: errMissing}
+	}
+	if err := ValidatePayloadType(e.PayloadType); err != nil {
+		return &ValidationError{Field: "payloadType", Err: err}
+	}
+
+	if _, err := e.DecodedPayload(); err != nil {
+		if err == ErrUnknownPayloadEncoding {
+			return &ValidationError{Field: "payloadEncoding", Err: err}
+		}
+		return &ValidationError{Field: "payload", Err: err}
+	}
+	if _, err := e.decodedAAD(); err != nil {
+		return &ValidationError{Field: "aad", Err: err}
+	}
+
+	if len(e.Signatures) == 0 {
+		return &ValidationError{Field: "signatures", Err: ErrNoSignature}
+	}
+	for i, s := range e.Signatures {
+		if _, err := b64Decode(s.Sig); err != nil {
+			return &ValidationError{Field: fmt.Sprintf("signatures[%d].sig", i), Err: err}
+		}
+	}
+
+	return nil
+}
+
+/*
+DecodeEnvelopeStrict decodes a JSON envelope from untrusted input. In addition
+to the checks of ValidateEnvelopeJSON, it rejects fields of the envelope and
+of its signatures that are not part of Envelope and Signature, so that no
+field ignored here can carry meaning to another consumer of the same envelope.
+Field names must match exactly, unlike with json.Unmarshal. Extensions of a
+signature must be an object but may carry any members. The returned error is
+a *ValidationError.
+*/
+func DecodeEnvelopeStrict(data []byte) (*Envelope, error) {
+	if err := ValidateEnvelopeJSON(data); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	if err := checkFields(fields, envelopeFields, ""); err != nil {
+		return nil, err
+	}
+
+	var signatures []map[string]json.RawMessage
+	if err := json.Unmarshal(fields["signatures"], &signatures); err != nil {
+		return nil, &ValidationError{Field: "signatures", Err: err}
+	}
+	for i, s := range signatures {
+		field := fmt.Sprintf("signatures[%d]", i)
+		if err := checkFields(s, signatureFields, field+"."); err != nil {
+			return nil, err
+		}
+		if raw, ok := s["extensions"]; ok {
+			var extensions map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &extensions); err != nil {
+				return nil, &ValidationError{Field: field + ".extensions", Err: err}
+			}
+		}
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	return &env, nil
+}
+
+/*
+DecodeEnvelopeFlexible decodes a JSON envelope whose signatures are either the
+standard array or an object keyed by key ID, as emitted by some producers.
+The value for a key ID is either a signature object, whose keyid must then be
+absent or equal to the key, or the base64 encoded signature itself. The
+signatures of an object are ordered by key ID. The envelope is otherwise
+checked like ValidateEnvelopeJSON, and the returned error is a
+*ValidationError. DecodeEnvelopeStrict still rejects the object form.
+*/
+func DecodeEnvelopeFlexible(data []byte) (*Envelope, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	if raw := bytes.TrimSpace(fields["signatures"]); len(raw) > 0 && raw[0] == '{' {
+		signatures, verr := keyedSignatures(raw)
+		if verr != nil {
+			return nil, verr
+		}
+		var err error
+		if fields["signatures"], err = json.Marshal(signatures); err != nil {
+			return nil, &ValidationError{Field: "signatures", Err: err}
+		}
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, &ValidationError{Err: err}
+		}
+	}
+
+	if err := ValidateEnvelopeJSON(data); err != nil {
+		return nil, err
+	}
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	return &env, nil
+}
+
+// keyedSignatures converts signatures keyed by key ID to the array form.
+func keyedSignatures(raw json.RawMessage) ([]map[string]json.RawMessage, *ValidationError) {
+	var keyed map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keyed); err != nil {
+		return nil, &ValidationError{Field: "signatures", Err: err}
+	}
+
+	keyIDs := make([]string, 0, len(keyed))
+	for keyID := range keyed {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	signatures := make([]map[string]json.RawMessage, 0, len(keyed))
+	for _, keyID := range keyIDs {
+		field := fmt.Sprintf("signatures[%q]", keyID)
+		encodedKeyID, err := json.Marshal(keyID)
+		if err != nil {
+			return nil, &ValidationError{Field: field, Err: err}
+		}
+
+		value := bytes.TrimSpace(keyed[keyID])
+		s := make(map[string]json.RawMessage)
+		if len(value) > 0 && value[0] == '"' {
+			s["sig"] = value
+		} else if err := json.Unmarshal(value, &s); err != nil || s == nil {
+			return nil, &ValidationError{Field: field, Err: errors.New("neither a signature object nor a string")}
+		}
+
+		if own, ok := s["keyid"]; ok {
+			var ownKeyID string
+			if err := json.Unmarshal(own, &ownKeyID); err != nil || ownKeyID != keyID {
+				return nil, &ValidationError{Field: field + ".keyid", Err: errors.New("does not match key")}
+			}
+		}
+		s["keyid"] = encodedKeyID
+		signatures = append(signatures, s)
+	}
+
+	return signatures, nil
+}
+
+// checkFields returns an error for the first member of fields not in allowed.
+func checkFields(fields map[string]json.RawMessage, allowed map[string]bool, prefix string) *ValidationError {
+	for name := range fields {
+		if !allowed[name] {
+			return &ValidationError{Field: prefix + name, Err: errUnknownField}
+		}
+	}
+
+	return nil
+}
+
+func stringField(fields map[string]json.RawMessage, name string) (string, *ValidationError) {
+	raw, ok := fields[name]
+	if !ok {
+		return "", &ValidationError{Field: name, Err: errMissing}
+	}
+
+	var s *string
+	if err := json.Unmarshal(raw, &s); err != nil || s == nil {
+		return "", &ValidationError{Field: name, Err: errNotString}
+	}
+
+	return *s, nil
+}
@@ -0,0 +1,174 @@
+package dsse
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEnvelopeJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+		field string
+	}{
+		{"Valid", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":"k","sig":"c2ln"}]}`, true, ""},
+		{"Only keyid", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":"k"}]}`, true, ""},
+		{"Only sig", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln"}]}`, true, ""},
+		{"Not JSON", `not json`, false, ""},
+		{"Not an object", `[]`, false, ""},
+		{"Missing payloadType", `{"payload":"aGVsbG8=","signatures":[{"sig":"c2ln"}]}`, false, "payloadType"},
+		{"Null payloadType", `{"payloadType":null,"payload":"aGVsbG8=","signatures":[{"sig":"c2ln"}]}`, false, "payloadType"},
+		{"Missing payload", `{"payloadType":"t","signatures":[{"sig":"c2ln"}]}`, false, "payload"},
+		{"Payload not base64", `{"payloadType":"t","payload":"not base 64","signatures":[{"sig":"c2ln"}]}`, false, "payload"},
+		{"Payload encoding", `{"payloadType":"t","payload":"-_8=","payloadEncoding":"base64url","signatures":[{"sig":"c2ln"}]}`, true, ""},
+		{"Payload encoding mismatch", `{"payloadType":"t","payload":"-_8=","payloadEncoding":"base64","signatures":[{"sig":"c2ln"}]}`, false, "payload"},
+		{"Unknown payload encoding", `{"payloadType":"t","payload":"aGVsbG8=","payloadEncoding":"hex","signatures":[{"sig":"c2ln"}]}`, false, "payloadEncoding"},
+		{"Missing signatures", `{"payloadType":"t","payload":"aGVsbG8="}`, false, "signatures"},
+		{"Signatures not array", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{}}`, false, "signatures"},
+		{"Empty signatures", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[]}`, false, "signatures"},
+		{"Null signature", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[null]}`, false, "signatures[0]"},
+		{"Empty signature", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln"},{}]}`, false, "signatures[1]"},
+		{"Keyid not string", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":1}]}`, false, "signatures[0].keyid"},
+		{"Sig not base64", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"not base 64"}]}`, false, "signatures[0].sig"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateEnvelopeJSON([]byte(test.data))
+			if test.valid {
+				assert.Nil(t, err, "unexpected error")
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrInvalidEnvelope), "wrong error")
+			var verr *ValidationError
+			assert.True(t, errors.As(err, &verr), "wrong error type")
+			assert.Equal(t, test.field, verr.Field, "wrong field")
+		})
+	}
+}
+
+func TestDecodeEnvelopeStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		valid bool
+		field string
+	}{
+		{"Valid", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":"k","sig":"c2ln"}]}`, true, ""},
+		{"Extensions", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":"k","sig":"c2ln","extensions":{"alg":"ed25519"}}]}`, true, ""},
+		{"Payload encoding", `{"payloadType":"t","payload":"-_8=","payloadEncoding":"base64url","signatures":[{"sig":"c2ln"}]}`, true, ""},
+		{"Invalid", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[]}`, false, "signatures"},
+		{"Unknown envelope field", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln"}],"x":1}`, false, "x"},
+		{"Unknown signature field", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln"},{"sig":"c2ln","cert":"x"}]}`, false, "signatures[1].cert"},
+		{"Field case", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln","KeyID":"k"}]}`, false, "signatures[0].KeyID"},
+		{"Extensions not object", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"sig":"c2ln","extensions":[]}]}`, false, "signatures[0].extensions"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := DecodeEnvelopeStrict([]byte(test.data))
+			if test.valid {
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, "t", env.PayloadType, "wrong payload type")
+				return
+			}
+
+			assert.Nil(t, env, "unexpected envelope")
+			var verr *ValidationError
+			assert.True(t, errors.As(err, &verr), "wrong error type")
+			assert.Equal(t, test.field, verr.Field, "wrong field")
+		})
+	}
+}
+
+func TestDecodeEnvelopeFlexible(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		keyIDs []string
+		field  string
+	}{
+		{"Array", `{"payloadType":"t","payload":"aGVsbG8=","signatures":[{"keyid":"k","sig":"c2ln"}]}`, []string{"k"}, ""},
+		{"Objects by key ID", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{"b":{"sig":"c2ln"},"a":{"keyid":"a","sig":"c2ln","extensions":{"alg":"ed25519"}}}}`, []string{"a", "b"}, ""},
+		{"Strings by key ID", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{"k":"c2ln"}}`, []string{"k"}, ""},
+		{"Key ID mismatch", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{"k":{"keyid":"x","sig":"c2ln"}}}`, nil, `signatures["k"].keyid`},
+		{"Invalid value", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{"k":1}}`, nil, `signatures["k"]`},
+		{"Invalid sig", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{"k":"!"}}`, nil, "signatures[0].sig"},
+		{"Empty object", `{"payloadType":"t","payload":"aGVsbG8=","signatures":{}}`, nil, "signatures"},
+		{"Not JSON", `not json`, nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env, err := DecodeEnvelopeFlexible([]byte(test.data))
+			if test.keyIDs != nil {
+				assert.Nil(t, err, "unexpected error")
+				assert.Equal(t, test.keyIDs, env.SignerKeyIDs(), "wrong key IDs")
+				for _, s := range env.Signatures {
+					assert.Equal(t, "c2ln", s.Sig, "wrong signature")
+				}
+				return
+			}
+
+			assert.Nil(t, env, "unexpected envelope")
+			var verr *ValidationError
+			assert.True(t, errors.As(err, &verr), "wrong error type")
+			assert.Equal(t, test.field, verr.Field, "wrong field")
+		})
+	}
+
+	t.Run("Strict rejects objects", func(t *testing.T) {
+		_, err := DecodeEnvelopeStrict([]byte(`{"payloadType":"t","payload":"aGVsbG8=","signatures":{"k":"c2ln"}}`))
+		assert.ErrorIs(t, err, ErrInvalidEnvelope, "wrong error")
+	})
+}
+
+func TestEnvelopeValidate(t *testing.T) {
+	valid := func() *Envelope {
+		return &Envelope{
+			PayloadType: "t",
+			Payload:     "aGVsbG8=",
+			Signatures:  []Signature{{KeyID: "k", Sig: "c2ln"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *Envelope)
+		field  string
+		err    error
+	}{
+		{"Valid", func(e *Envelope) {}, "", nil},
+		{"Empty payloadType", func(e *Envelope) { e.PayloadType = "" }, "payloadType", errMissing},
+		{"Invalid payloadType", func(e *Envelope) { e.PayloadType = "t\n" }, "payloadType", ErrInvalidPayloadType},
+		{"Payload not base64", func(e *Envelope) { e.Payload = "not base 64" }, "payload", nil},
+		{"Unknown payload encoding", func(e *Envelope) { e.PayloadEncoding = "hex" }, "payloadEncoding", ErrUnknownPayloadEncoding},
+		{"AAD not base64", func(e *Envelope) { e.AAD = "not base 64" }, "aad", nil},
+		{"No signatures", func(e *Envelope) { e.Signatures = nil }, "signatures", ErrNoSignature},
+		{"Sig not base64", func(e *Envelope) { e.Signatures = append(e.Signatures, Signature{Sig: "not base 64"}) }, "signatures[1].sig", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := valid()
+			test.modify(e)
+			err := e.Validate()
+			if test.field == "" {
+				assert.Nil(t, err, "unexpected error")
+				return
+			}
+
+			assert.True(t, errors.Is(err, ErrInvalidEnvelope), "wrong error")
+			var verr *ValidationError
+			assert.True(t, errors.As(err, &verr), "wrong error type")
+			assert.Equal(t, test.field, verr.Field, "wrong field")
+			if test.err != nil {
+				assert.True(t, errors.Is(err, test.err), "wrong cause")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package dsse
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrKeyExpired indicates that a key was used after its validity window.
+var ErrKeyExpired = errors.New("key expired")
+
+// ErrKeyNotYetValid indicates that a key was used before its validity window.
+var ErrKeyNotYetValid = errors.New("key not yet valid")
+
+/*
+ValidityWindowVerifier wraps a Verifier and rejects signatures whose
+verification time falls outside [NotBefore, NotAfter]. A zero NotBefore or
+NotAfter leaves that side of the window open.
+Verify checks against the current time. An envelope verifier configured with
+WithVerificationTime checks against the supplied time instead, for example a
+signing time taken from a trusted timestamp. The algorithm and the hash of the
+wrapped verifier are forwarded, so algorithm checks apply to it and a wrapped
+PrehashVerifier can still verify streamed payloads.
+*/
+type ValidityWindowVerifier struct {
+	Verifier
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+/*
+NewValidityWindowVerifier wraps v so that it only accepts signatures verified
+within [notBefore, notAfter].
+*/
+func NewValidityWindowVerifier(v Verifier, notBefore, notAfter time.Time) *ValidityWindowVerifier {
+	return &ValidityWindowVerifier{
+		Verifier:  v,
+		NotBefore: notBefore,
+		NotAfter:  notAfter,
+	}
+}
+
+// Verify verifies sig over data at the current time.
+func (v *ValidityWindowVerifier) Verify(data, sig []byte) error {
+	return v.VerifyAt(data, sig, time.Now())
+}
+
+/*
+VerifyAt verifies sig over data, treating t as the verification time. It
+returns ErrKeyNotYetValid or ErrKeyExpired if t is outside the validity
+window.
+*/
+func (v *ValidityWindowVerifier) VerifyAt(data, sig []byte, t time.Time) error {
+	if err := v.check(t); err != nil {
+		return err
+	}
+
+	return v.Verifier.Verify(data, sig)
+}
+
+// HashFunc returns the hash of the wrapped verifier, or 0 if it is not a
+// PrehashVerifier.
+func (v *ValidityWindowVerifier) HashFunc() crypto.Hash {
+	if pv, ok := v.Verifier.(PrehashVerifier); ok {
+		return pv.HashFunc()
+	}
+	return 0
+}
+
+// VerifyDigest verifies sig over a digest computed with hash at the current
+// time.
+func (v *ValidityWindowVerifier) VerifyDigest(digest []byte, hash crypto.Hash, sig []byte) error {
+	return v.VerifyDigestAt(digest, hash, sig, time.Now())
+}
+
+/*
+VerifyDigestAt verifies sig over a digest computed with hash, treating t as
+the verification time. The wrapped verifier must be a PrehashVerifier.
+*/
+func (v *ValidityWindowVerifier) VerifyDigestAt(digest []byte, hash crypto.Hash, sig []byte, t time.Time) error {
+	pv, ok := prehashVerifier(v.Verifier)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrStreamingUnsupported, v.Verifier)
+	}
+	if err := v.check(t); err != nil {
+		return err
+	}
+
+	return pv.VerifyDigest(digest, hash, sig)
+}
+
+// Algorithm returns the algorithm of the wrapped verifier, if it names one.
+func (v *ValidityWindowVerifier) Algorithm() string {
+	return verifierAlgorithm(v.Verifier)
+}
+
+// check returns an error if t is outside the validity window.
+func (v *ValidityWindowVerifier) check(t time.Time) error {
+	if !v.NotBefore.IsZero() && t.Before(v.NotBefore) {
+		return ErrKeyNotYetValid
+	}
+	if !v.NotAfter.IsZero() && t.After(v.NotAfter) {
+		return ErrKeyExpired
+	}
+
+	return nil
+}
+
+// timeVerifier is implemented by verifiers that depend on the verification time.
+type timeVerifier interface {
+	VerifyAt(data, sig []byte, t time.Time) error
+	VerifyDigestAt(digest []byte, hash crypto.Hash, sig []byte, t time.Time) error
+}
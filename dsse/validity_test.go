@@ -0,0 +1,134 @@
+package dsse
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidityWindowVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	notBefore := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := NewValidityWindowVerifier(ns, notBefore, notAfter)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"Within window", notBefore.Add(time.Hour), nil},
+		{"Before window", notBefore.Add(-time.Hour), ErrKeyNotYetValid},
+		{"After window", notAfter.Add(time.Hour), ErrKeyExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var results []error
+			ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{v},
+				WithVerificationTime(test.at),
+				WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+					results = append(results, err)
+				}))
+			assert.Nil(t, err, "unexpected error")
+
+			_, err = ev.Verify(env)
+			assert.Equal(t, []error{test.want}, results, "wrong signature result")
+			if test.want == nil {
+				assert.Nil(t, err, "unexpected error")
+			} else {
+				assert.NotNil(t, err, "expected error")
+			}
+		})
+	}
+
+	t.Run("Clock", func(t *testing.T) {
+		now := notBefore.Add(-time.Hour)
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{v}, WithClock(func() time.Time {
+			return now
+		}))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+
+		now = notBefore.Add(time.Hour)
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+
+		// An explicit verification time takes precedence over the clock.
+		ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{v},
+			WithClock(func() time.Time { return now }),
+			WithVerificationTime(notAfter.Add(time.Hour)))
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+	})
+
+	t.Run("Current time", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifier(v)
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.NotNil(t, err, "expected error")
+
+		open := NewValidityWindowVerifier(ns, notBefore, time.Time{})
+		ev, err = NewEnvelopeVerifier(open)
+		assert.Nil(t, err, "unexpected error")
+
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+	})
+
+	t.Run("Wrapped verifier", func(t *testing.T) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+		sv, err := NewECDSASignerVerifier("ec", key)
+		assert.Nil(t, err, "unexpected error")
+		signer, err := NewEnvelopeSigner(sv)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, []byte(payload))
+		assert.Nil(t, err, "sign failed")
+		detached := *env
+		detached.Payload = ""
+
+		v := NewValidityWindowVerifier(sv, notBefore, notAfter)
+		assert.Equal(t, sv.Algorithm(), v.Algorithm(), "wrong algorithm")
+		assert.Equal(t, sv.HashFunc(), v.HashFunc(), "wrong hash")
+
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{v},
+			WithAllowedAlgorithms(sv.Algorithm()),
+			WithVerificationTime(notBefore.Add(time.Hour)))
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.VerifyStream(&detached, bytes.NewReader([]byte(payload)))
+		assert.Nil(t, err, "unexpected error")
+
+		ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{v},
+			WithVerificationTime(notAfter.Add(time.Hour)))
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.VerifyStream(&detached, bytes.NewReader([]byte(payload)))
+		assert.NotNil(t, err, "expected error")
+		digest := PAEDigest(payloadType, []byte(payload), sv.HashFunc())
+		err = v.VerifyDigestAt(digest, sv.HashFunc(), nil, notAfter.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrKeyExpired, "wrong error")
+
+		assert.ErrorIs(t, NewValidityWindowVerifier(ns, notBefore, notAfter).VerifyDigest(nil, 0, nil), ErrStreamingUnsupported, "wrong error")
+	})
+}
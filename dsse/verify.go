@@ -2,6 +2,9 @@ package dsse
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"errors"
 	"fmt"
 
@@ -20,71 +23,315 @@ type Verifier interface {
 	Public() crypto.PublicKey
 }
 
+/*
+EnvelopeVerifier verifies envelopes. It is implemented by the verifiers
+returned by NewEnvelopeVerifier and its variants, and by EnvelopeSigner.
+*/
+type EnvelopeVerifier interface {
+	Verify(e *Envelope) ([]AcceptedKey, error)
+}
+
+var (
+	_ EnvelopeVerifier = (*envelopeVerifier)(nil)
+	_ EnvelopeVerifier = (*EnvelopeSigner)(nil)
+)
+
+// ErrMissingSignature indicates that a required key did not sign an envelope.
+var ErrMissingSignature = errors.New("missing required signature")
+
+// ErrNoMatchingKey indicates that none of the keys of a verifier matches the
+// signatures of an envelope.
+var ErrNoMatchingKey = errors.New("no matching key")
+
+// ErrSignatureInvalid indicates that a signature was made by a known key but
+// does not verify.
+var ErrSignatureInvalid = errors.New("invalid signature")
+
+/*
+ErrVerifierUnavailable indicates that a verifier could not check a signature,
+for example because a remote key service is unreachable. Verifiers wrap it to
+report such failures, which are not evidence that the signature is invalid:
+verification moves on to the remaining verifiers, and the envelope is
+accepted if they meet the threshold.
+*/
+var ErrVerifierUnavailable = errors.New("verifier unavailable")
+
+/*
+VerificationError is returned when an envelope does not have enough valid
+signatures. Err is ErrSignatureInvalid if any signature whose key ID matched
+one of the keys failed to verify, which suggests the envelope was tampered
+with, and ErrNoMatchingKey otherwise, which suggests the keys are not
+configured as expected. If such a signature was rejected because it records
+a different algorithm than the verifier uses, Err is ErrAlgorithmMismatch.
+If the only failures were verifiers wrapping ErrVerifierUnavailable, Err is
+ErrVerifierUnavailable. If no key matched but a key ID differs from a
+verifier's only in format, Err is a *KeyIDFormatError. A signature without a
+key ID that no key verifies is considered not to match, as the key it was
+made with cannot be identified.
+*/
+type VerificationError struct {
+	Found    int
+	Expected int
+	Err      error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("Accepted signatures do not match threshold, Found: %d, Expected %d", e.Found, e.Expected)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
 type envelopeVerifier struct {
 	providers []Verifier
+	// keyIDs holds the key ID of each provider, and index the positions of
+	// the providers by key ID, so that a signature is only offered to the
+	// providers it may match. all holds the positions of all providers.
+	keyIDs    []string
+	index     map[string][]int
+	all       []int
 	threshold int
+	// required holds the key IDs that must have signed, see
+	// NewEnvelopeVerifierWithPolicy.
+	required []string
+	// resolve fetches the providers for the key IDs of the signatures of
+	// each envelope, see NewEnvelopeVerifierFunc, and lazy marks a copy
+	// holding the providers for the signatures of an envelope, see
+	// forSignatures, which may be fewer than the threshold.
+	resolve func(keyID string) (Verifier, error)
+	lazy    bool
+	// embedded marks the providers for keys embedded in the signatures,
+	// see WithTrustEmbeddedKeys, and countEmbedded lets them count toward
+	// the threshold, see NewEmbeddedKeyVerifier.
+	embedded      map[int]bool
+	countEmbedded bool
+	opts          options
 }
 
 type AcceptedKey struct {
 	Public crypto.PublicKey
 	KeyID  string
 	Sig    Signature
+	// Embedded is true if the key was embedded in the signature, see
+	// WithTrustEmbeddedKeys. Such keys do not count toward the threshold,
+	// except with NewEmbeddedKeyVerifier.
+	Embedded bool
 }
 
+/*
+Verify decodes the payload and verifies the signatures. Envelopes carrying
+additional authenticated data are rejected with ErrAADMismatch; use
+VerifyWithAAD for them.
+*/
 func (ev *envelopeVerifier) Verify(e *Envelope) ([]AcceptedKey, error) {
+	return ev.VerifyWithAAD(e, nil)
+}
+
+/*
+VerifyAndExtract verifies e like Verify and, only if verification succeeds,
+returns the payload type, the decoded payload and the accepted keys, which is
+what a policy engine needs to evaluate a verified statement. On failure the
+payload is not returned, so that unverified data cannot reach the policy by
+mistake.
+*/
+func (ev *envelopeVerifier) VerifyAndExtract(e *Envelope) (string, []byte, []AcceptedKey, error) {
+	acceptedKeys, err := ev.Verify(e)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	body, err := ev.opts.decodePayload(e)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return e.PayloadType, body, acceptedKeys, nil
+}
+
+/*
+VerifyWithAAD is like Verify, but requires the envelope to carry the
+additional authenticated data aad, which the signatures must cover. An
+envelope with different or no additional authenticated data is rejected with
+ErrAADMismatch. See SignPayloadWithAAD.
+*/
+func (ev *envelopeVerifier) VerifyWithAAD(e *Envelope, aad []byte) ([]AcceptedKey, error) {
+	if ev.opts.auditSink != nil {
+		return ev.audit(ev.opts.envelopeEvent(e), func(av *envelopeVerifier) ([]AcceptedKey, error) {
+			return av.VerifyWithAAD(e, aad)
+		})
+	}
+
+	if e == nil {
+		return nil, ErrNoEnvelopes
+	}
 	if len(e.Signatures) == 0 {
 		return nil, ErrNoSignature
 	}
+	if err := ev.opts.checkSignatureCount(len(e.Signatures)); err != nil {
+		return nil, err
+	}
+	if err := e.checkAAD(aad); err != nil {
+		return nil, err
+	}
+
+	payloadType := ev.opts.normalizePayloadType(e.PayloadType)
+	if err := ev.opts.checkPayloadType(payloadType); err != nil {
+		return nil, err
+	}
+	if err := ev.opts.checkPayloadSize(e.Payload); err != nil {
+		return nil, err
+	}
 
 	// Decode payload (i.e serialized body)
-	body, err := b64Decode(e.Payload)
+	body, err := ev.opts.decodePayload(e)
 	if err != nil {
 		return nil, err
 	}
 	// Generate PAE(payloadtype, serialized body)
-	paeEnc := PAE(e.PayloadType, body)
+	paeEnc := PAEWithAAD(payloadType, body, aad)
+
+	return ev.verifySignatures(&message{pae: paeEnc}, e.Signatures)
+}
+
+/*
+message is the pre-authentication encoding being verified. If the encoding was
+streamed, pae holds it only if some verifier needs the full message, and
+digests holds its digest for every hash used by a PrehashVerifier.
+*/
+type message struct {
+	pae     []byte
+	digests map[crypto.Hash][]byte
+}
 
+// verifySignatures verifies the signatures over the message.
+func (ev *envelopeVerifier) verifySignatures(msg *message, signatures []Signature) ([]AcceptedKey, error) {
+	ev, err := ev.forSignatures(signatures)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ev.checkDuplicateKeyIDs(msg, signatures); err != nil {
+		return nil, err
+	}
+
+	if len(signatures) == 1 && len(ev.providers) == 1 && ev.threshold == 1 && len(ev.embedded) == 0 {
+		if _, ok := ev.providers[0].(AggregateVerifier); !ok {
+			return ev.verifySingle(msg, signatures[0])
+		}
+	}
+
+	return ev.verifyAll(msg, signatures)
+}
+
+// verifyAll matches the signatures to the verifiers and checks the threshold.
+func (ev *envelopeVerifier) verifyAll(msg *message, signatures []Signature) ([]AcceptedKey, error) {
 	// If *any* signature is found to be incorrect, it is skipped
 	var acceptedKeys []AcceptedKey
 	usedKeyids := make(map[string]string)
-	unverified_providers := ev.providers
-	for _, s := range e.Signatures {
-		sig, err := b64Decode(s.Sig)
+	verifiedProviders := make([]bool, len(ev.providers))
+	var cause error
+
+	// Aggregate verifiers see all signatures at once.
+	aggregated := make(map[string]bool)
+	for i, v := range ev.providers {
+		av, ok := v.(AggregateVerifier)
+		if !ok {
+			continue
+		}
+		verifiedProviders[i] = true
+
+		keys, err := ev.verifyAggregate(av, msg, signatures)
 		if err != nil {
-			return nil, err
+			cause = ev.opts.failureCause(cause, Signature{KeyID: ev.keyIDs[i]}, ev.keyIDs[i], err)
+			continue
+		}
+		// The signatures of the aggregate verifier itself and of the keys
+		// it accepts are accounted for.
+		if len(keys) > 0 {
+			aggregated[ev.keyIDs[i]] = true
+		}
+		for _, k := range keys {
+			aggregated[k.KeyID] = true
+			if _, ok := usedKeyids[k.KeyID]; ok {
+				continue
+			}
+			usedKeyids[k.KeyID] = ""
+			acceptedKeys = append(acceptedKeys, k)
+		}
+	}
+
+	// A malformed signature rejects the envelope, but the remaining
+	// signatures are still verified so that each of them is reported.
+	var malformed error
+	for _, s := range signatures {
+		if s.KeyID != "" && aggregated[s.KeyID] {
+			ev.opts.reportSignature(s.KeyID, true, nil)
+			continue
+		}
+
+		sig, err := ev.opts.decode(s.Sig)
+		if err == nil {
+			err = ev.opts.checkSignatureAlgorithm(s)
+		}
+		if err != nil {
+			if malformed == nil {
+				malformed = err
+			}
+			ev.opts.reportSignature(s.KeyID, false, err)
+			continue
 		}
 
-		// Loop over the providers.
-		// If provider and signature include key IDs but do not match skip.
+		// An empty signature is never valid, whatever the verifier says.
+		if len(sig) == 0 {
+			if cause == nil || cause == ErrVerifierUnavailable {
+				cause = ErrSignatureInvalid
+			}
+			ev.opts.reportSignature(s.KeyID, false, ErrEmptySignature)
+			continue
+		}
+
+		verified := false
+		matchedKeyID := s.KeyID
+		var sigErr error = ErrUnknownKey
+
+		// Loop over the providers that may match the key ID of the
+		// signature, see candidates.
 		// If a provider recognizes the key, we exit
 		// the loop and use the result.
-		providers := unverified_providers
-		for i, v := range providers {
-			keyID, err := v.KeyID()
-
-			// Verifiers that do not provide a keyid will be generated one using public.
-			if err != nil || keyID == "" {
-				keyID, err = SHA256KeyID(v.Public())
-				if err != nil {
-					keyID = ""
-				}
+		for _, i := range ev.candidates(s.KeyID) {
+			v, keyID := ev.providers[i], ev.keyIDs[i]
+			_, aggregate := v.(AggregateVerifier)
+			reverify := verifiedProviders[i] && ev.opts.verifyAll && !aggregate
+			if verifiedProviders[i] && !reverify {
+				continue
 			}
 
-			if s.KeyID != "" && keyID != "" && err == nil && s.KeyID != keyID {
+			offered, err := ev.offer(v, keyID, msg, s, sig)
+			if !offered {
 				continue
 			}
-
-			err = v.Verify(paeEnc, sig)
 			if err != nil {
+				if !verified {
+					sigErr = err
+				}
+				cause = ev.opts.failureCause(cause, s, keyID, err)
 				continue
 			}
+			verified, matchedKeyID, sigErr = true, keyID, nil
+			if reverify {
+				// Another valid signature by a key that is already
+				// counted.
+				break
+			}
 
 			acceptedKey := AcceptedKey{
-				Public: v.Public(),
-				KeyID:  keyID,
-				Sig:    s,
+				Public:   v.Public(),
+				KeyID:    keyID,
+				Sig:      s,
+				Embedded: ev.embedded[i],
 			}
-			unverified_providers = removeIndex(providers, i)
+			verifiedProviders[i] = true
 
 			// See https://github.com/in-toto/in-toto/pull/251
 			if _, ok := usedKeyids[keyID]; ok {
@@ -96,34 +343,319 @@ func (ev *envelopeVerifier) Verify(e *Envelope) ([]AcceptedKey, error) {
 			acceptedKeys = append(acceptedKeys, acceptedKey)
 			break
 		}
+
+		if sigErr == ErrUnknownKey {
+			ev.opts.debug("no verifier for signature", "signature_keyid", s.KeyID)
+		}
+		ev.opts.reportSignature(matchedKeyID, verified, sigErr)
+	}
+	if malformed != nil {
+		return nil, malformed
 	}
 
 	// Sanity if with some reflect magic this happens.
-	if ev.threshold <= 0 || ev.threshold > len(ev.providers) {
+	if !ev.lazy && !validThreshold(ev.threshold, ev.providers) {
 		return nil, errors.New("Invalid threshold")
 	}
 
-	if len(usedKeyids) < ev.threshold {
-		return acceptedKeys, errors.New(fmt.Sprintf("Accepted signatures do not match threshold, Found: %d, Expected %d", len(acceptedKeys), ev.threshold))
+	for _, keyID := range ev.required {
+		if _, ok := usedKeyids[keyID]; !ok {
+			return acceptedKeys, fmt.Errorf("%w: KeyID=%s", ErrMissingSignature, keyID)
+		}
+	}
+
+	// Keys embedded in the signatures are reported, but only configured
+	// keys count toward the threshold, unless there are none.
+	counted := 0
+	for _, k := range acceptedKeys {
+		if !k.Embedded || ev.countEmbedded {
+			counted++
+		}
+	}
+	if counted < ev.threshold {
+		return acceptedKeys, ev.thresholdError(counted, cause, signatures)
 	}
 
 	return acceptedKeys, nil
 }
 
+/*
+verifySingle verifies a single signature with a single verifier and a
+threshold of one. It skips the bookkeeping verifySignatures needs to match
+several signatures to several verifiers, and otherwise behaves identically.
+*/
+func (ev *envelopeVerifier) verifySingle(msg *message, s Signature) ([]AcceptedKey, error) {
+	sig, err := ev.opts.decode(s.Sig)
+	if err != nil {
+		ev.opts.reportSignature(s.KeyID, false, err)
+		return nil, err
+	}
+
+	if err := ev.opts.checkSignatureAlgorithm(s); err != nil {
+		ev.opts.reportSignature(s.KeyID, false, err)
+		return nil, err
+	}
+
+	v, keyID := ev.providers[0], ev.keyIDs[0]
+	var cause error
+	var sigErr error = ErrUnknownKey
+	if len(sig) == 0 {
+		cause, sigErr = ErrSignatureInvalid, ErrEmptySignature
+	} else if offered, err := ev.offer(v, keyID, msg, s, sig); offered {
+		if err == nil {
+			ev.opts.reportSignature(keyID, true, nil)
+			return []AcceptedKey{{Public: v.Public(), KeyID: keyID, Sig: s}}, nil
+		}
+		sigErr, cause = err, ev.opts.failureCause(nil, s, keyID, err)
+	}
+	if sigErr == ErrUnknownKey {
+		ev.opts.debug("no verifier for signature", "signature_keyid", s.KeyID)
+	}
+	ev.opts.reportSignature(s.KeyID, false, sigErr)
+
+	if len(ev.required) > 0 {
+		return nil, fmt.Errorf("%w: KeyID=%s", ErrMissingSignature, keyID)
+	}
+
+	return nil, ev.thresholdError(0, cause, []Signature{s})
+}
+
+/*
+Keys returns the public keys of the verifiers, in the order the verifiers
+were given, for example to display who can sign envelopes accepted by ev.
+Verifiers without a public key are skipped.
+*/
+func (ev *envelopeVerifier) Keys() []crypto.PublicKey {
+	keys := make([]crypto.PublicKey, 0, len(ev.providers))
+	for _, v := range ev.providers {
+		if pub := v.Public(); pub != nil {
+			keys = append(keys, pub)
+		}
+	}
+
+	return keys
+}
+
+/*
+KeyIDs returns the key IDs of the verifiers, in the order the verifiers were
+given. A verifier that does not report a key ID is listed with the key ID
+derived from its public key with SHA256KeyID, or the empty string if that
+fails.
+*/
+func (ev *envelopeVerifier) KeyIDs() []string {
+	return append([]string(nil), ev.keyIDs...)
+}
+
+/*
+offer verifies the signature s, decoded to sig, with v if the signature is
+offered to v, which depends on their key IDs and on the allowed algorithms.
+It reports whether the signature was offered and the verification result.
+*/
+func (ev *envelopeVerifier) offer(v Verifier, keyID string, msg *message, s Signature, sig []byte) (bool, error) {
+	if !ev.opts.keyIDsMatch(s.KeyID, keyID) {
+		ev.opts.debug("skipping verifier: key ID does not match", "keyid", keyID, "signature_keyid", s.KeyID)
+		return false, nil
+	}
+	if alg := verifierAlgorithm(v); !ev.opts.algorithmAllowed(alg) {
+		ev.opts.debug("skipping verifier: algorithm not allowed", "keyid", keyID, "algorithm", alg)
+		return false, nil
+	}
+
+	if err := checkAlgorithm(v, s); err != nil {
+		ev.opts.debug("signature algorithm does not match verifier", "keyid", keyID, "error", err)
+		return true, err
+	}
+
+	ev.opts.debugVerify(keyID, msg.pae)
+	if err := ev.verify(v, msg, sig); err != nil {
+		ev.opts.debug("verifier rejected signature", "keyid", keyID, "error", err)
+		return true, err
+	}
+	return true, nil
+}
+
+/*
+candidates returns the positions of the providers whose key ID may match
+sigKeyID, in the order the providers were given. A signature with a key ID
+is only offered to the providers with that key ID and to providers without a
+key ID; a signature without a key ID is offered to every provider. With
+strict key ID matching, only providers with the same key ID are returned.
+*/
+func (ev *envelopeVerifier) candidates(sigKeyID string) []int {
+	if ev.opts.strictKeyIDs {
+		if sigKeyID == "" {
+			return nil
+		}
+		return ev.index[ev.opts.keyID(sigKeyID)]
+	}
+	if sigKeyID == "" {
+		return ev.all
+	}
+
+	matching, unnamed := ev.index[ev.opts.keyID(sigKeyID)], ev.index[""]
+	if len(unnamed) == 0 {
+		return matching
+	}
+
+	// Merge the two ascending lists of positions.
+	merged := make([]int, 0, len(matching)+len(unnamed))
+	for len(matching) > 0 && len(unnamed) > 0 {
+		if matching[0] < unnamed[0] {
+			merged, matching = append(merged, matching[0]), matching[1:]
+		} else {
+			merged, unnamed = append(merged, unnamed[0]), unnamed[1:]
+		}
+	}
+	merged = append(merged, matching...)
+	return append(merged, unnamed...)
+}
+
+/*
+failureCause updates the cause of a verification failure after the verifier
+with key ID keyID rejected s with err. An unavailable verifier only counts if
+nothing else failed, as it says nothing about the signature.
+*/
+func (o *options) failureCause(cause error, s Signature, keyID string, err error) error {
+	if errors.Is(err, ErrVerifierUnavailable) {
+		if cause == nil {
+			return ErrVerifierUnavailable
+		}
+		return cause
+	}
+	if s.KeyID == "" || o.keyID(s.KeyID) != o.keyID(keyID) {
+		return cause
+	}
+	if errors.Is(err, ErrAlgorithmMismatch) {
+		return ErrAlgorithmMismatch
+	}
+	if cause == nil || cause == ErrVerifierUnavailable {
+		return ErrSignatureInvalid
+	}
+
+	return cause
+}
+
+/*
+thresholdError returns the error for too few accepted signatures. If no key
+matched the signatures, the cause points out a key ID of the signatures that
+identifies the same key as a verifier in a different format, if any.
+*/
+func (ev *envelopeVerifier) thresholdError(found int, cause error, signatures []Signature) error {
+	if cause == nil {
+		cause = ev.keyIDFormatError(signatures)
+	}
+
+	return &VerificationError{
+		Found:    found,
+		Expected: ev.threshold,
+		Err:      cause,
+	}
+}
+
+/*
+verifierKeyID returns the key ID of v. Verifiers that do not provide a key ID
+are assigned one derived from their public key, or the empty string if that
+fails.
+*/
+func verifierKeyID(v Verifier) string {
+	keyID, err := v.KeyID()
+	if err != nil || keyID == "" {
+		keyID, err = SHA256KeyID(v.Public())
+		if err != nil {
+			keyID = ""
+		}
+	}
+
+	return keyID
+}
+
+/*
+verify verifies sig over the message with v, honoring the verification time.
+A PrehashVerifier is given the digest of the message, which is computed once
+per hash and shared by all verifiers.
+*/
+func (ev *envelopeVerifier) verify(v Verifier, msg *message, sig []byte) error {
+	tv, timed := v.(timeVerifier)
+
+	if pv, ok := prehashVerifier(v); ok {
+		if digest, ok := msg.digest(pv.HashFunc()); ok {
+			if timed {
+				return tv.VerifyDigestAt(digest, pv.HashFunc(), sig, ev.opts.now())
+			}
+			return pv.VerifyDigest(digest, pv.HashFunc(), sig)
+		}
+		ev.opts.debug("no digest for prehash verifier, verifying the message", "hash", pv.HashFunc())
+	}
+
+	if msg.pae == nil {
+		return ErrStreamingUnsupported
+	}
+	if timed {
+		return tv.VerifyAt(msg.pae, sig, ev.opts.now())
+	}
+	return v.Verify(msg.pae, sig)
+}
+
 func NewEnvelopeVerifier(v ...Verifier) (*envelopeVerifier, error) {
 	return NewMultiEnvelopeVerifier(1, v...)
 }
 
 func NewMultiEnvelopeVerifier(threshold int, p ...Verifier) (*envelopeVerifier, error) {
+	return NewEnvelopeVerifierWithOptions(threshold, p)
+}
 
-	if threshold <= 0 || threshold > len(p) {
+/*
+NewEnvelopeVerifierRequireAll creates an envelope verifier that only accepts
+envelopes with a valid signature from every one of the given verifiers. Unlike
+a threshold of len(v), this guarantees that each specific key signed, which is
+useful for mandatory dual control. If a key did not sign, the error wraps
+ErrMissingSignature.
+*/
+func NewEnvelopeVerifierRequireAll(v ...Verifier) (*envelopeVerifier, error) {
+	ev, err := NewMultiEnvelopeVerifier(len(v), v...)
+	if err != nil {
+		return nil, err
+	}
+	ev.required = ev.keyIDs
+
+	return ev, nil
+}
+
+/*
+NewEnvelopeVerifierWithOptions creates an envelope verifier with the given
+threshold and verifiers, configured by opts.
+The key IDs of the verifiers are read once, when the envelope verifier is
+created, and index the verifiers so that a signature with a key ID is only
+checked by the verifiers it may match.
+The threshold must be positive and at most the number of verifiers, unless
+an AggregateVerifier, which may accept several keys, is among them.
+*/
+func NewEnvelopeVerifierWithOptions(threshold int, p []Verifier, opts ...Option) (*envelopeVerifier, error) {
+	return newEnvelopeVerifier(threshold, p, newOptions(opts...))
+}
+
+// newEnvelopeVerifier implements NewEnvelopeVerifierWithOptions for options
+// that are already applied.
+func newEnvelopeVerifier(threshold int, p []Verifier, o options) (*envelopeVerifier, error) {
+	if !validThreshold(threshold, p) {
 		return nil, errors.New("Invalid threshold")
 	}
 
 	ev := envelopeVerifier{
 		providers: p,
+		keyIDs:    make([]string, len(p)),
+		index:     make(map[string][]int),
+		all:       make([]int, len(p)),
 		threshold: threshold,
+		opts:      o,
+	}
+	for i, v := range p {
+		keyID := verifierKeyID(v)
+		ev.keyIDs[i] = keyID
+		ev.index[ev.opts.keyID(keyID)] = append(ev.index[ev.opts.keyID(keyID)], i)
+		ev.all[i] = i
 	}
+
 	return &ev, nil
 }
 
@@ -137,6 +669,48 @@ func SHA256KeyID(pub crypto.PublicKey) (string, error) {
 	return fingerprint, nil
 }
 
-func removeIndex(v []Verifier, index int) []Verifier {
-	return append(v[:index], v[index+1:]...)
+/*
+VerifySignature verifies sig over the PAE of payloadType and payload with a
+public key, without an envelope or key ID matching. The algorithm is picked
+from the type of the key: Ed25519 for ed25519.PublicKey, ECDSA with the
+default hash of the curve for *ecdsa.PublicKey, and RSA-PSS with SHA-256 for
+*rsa.PublicKey. Other keys yield an error wrapping ErrUnsupportedKey. As the
+signature may come from any ECDSA implementation, high-S signatures are
+accepted.
+*/
+func VerifySignature(pub crypto.PublicKey, payloadType string, payload, sig []byte) error {
+	v, err := verifierForKey("", pub)
+	if err != nil {
+		return err
+	}
+
+	return v.Verify(PAE(payloadType, payload), sig)
+}
+
+// verifierForKey returns a bundled verifier for pub with key ID keyID.
+func verifierForKey(keyID string, pub crypto.PublicKey) (Verifier, error) {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return NewEd25519Verifier(keyID, k)
+	case *ecdsa.PublicKey:
+		return NewECDSAVerifier(keyID, k, WithAllowHighS())
+	case *rsa.PublicKey:
+		return NewRSAPSSVerifier(keyID, k)
+	}
+
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+// validThreshold reports whether threshold can be met by the verifiers p.
+func validThreshold(threshold int, p []Verifier) bool {
+	if threshold <= 0 {
+		return false
+	}
+	for _, v := range p {
+		if _, ok := v.(AggregateVerifier); ok {
+			return true
+		}
+	}
+
+	return threshold <= len(p)
 }
@@ -1,8 +1,18 @@
 package dsse
 
 import (
+	"bytes"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -62,3 +72,630 @@ func TestVerify(t *testing.T) {
 	assert.Error(t, err)
 
 }
+
+func TestVerifyPerSignatureCallback(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	var ns nilsigner
+	var null nullsigner
+	signer, err := NewEnvelopeSigner(ns, null)
+	assert.Nil(t, err, "unexpected error")
+
+	env, err := signer.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+	env.Signatures = append(env.Signatures, Signature{
+		KeyID: "unknown",
+		Sig:   env.Signatures[0].Sig,
+	})
+
+	type result struct {
+		keyID string
+		ok    bool
+		err   error
+	}
+	var results []result
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns, null},
+		WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+			results = append(results, result{keyID, ok, err})
+		}))
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, acceptedKeys, 2, "unexpected keys")
+	assert.Equal(t, []result{
+		{"nil", true, nil},
+		{"null", true, nil},
+		{"unknown", false, ErrUnknownKey},
+	}, results, "unexpected callback results")
+
+	// A malformed signature rejects the envelope, and the others are still
+	// reported.
+	results = nil
+	env.Signatures[0].Sig = "!"
+	_, err = ev.Verify(env)
+	assert.NotNil(t, err, "expected error")
+	assert.Len(t, results, 3, "unexpected callback results")
+	assert.Equal(t, "nil", results[0].keyID, "wrong keyid")
+	assert.False(t, results[0].ok, "malformed signature accepted")
+	assert.Equal(t, result{"null", true, nil}, results[1], "unexpected callback result")
+}
+
+func TestVerifyMaxPayloadSize(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, payload := range []string{"hello world", "hello worl", "hello wor"} {
+		env, err := signer.SignPayload(payloadType, []byte(payload))
+		assert.Nil(t, err, "sign failed")
+
+		ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{ns}, WithMaxPayloadSize(int64(len(payload))))
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+
+		ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{ns}, WithMaxPayloadSize(int64(len(payload)-1)))
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		assert.Equal(t, ErrPayloadTooLarge, err, "wrong error")
+	}
+}
+
+func TestVerifyMaxSignatures(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	var ns nilsigner
+	signer, err := NewEnvelopeSigner(ns)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+	env.Signatures = append(env.Signatures, env.Signatures[0], env.Signatures[0])
+
+	var calls int
+	cv := countingVerifier{Verifier: ns, calls: &calls}
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{cv}, WithMaxSignatures(3))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, 1, calls, "wrong number of verify calls")
+
+	calls = 0
+	ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{cv}, WithMaxSignatures(2))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.True(t, errors.Is(err, ErrTooManySignatures), "wrong error")
+	_, err = ev.VerifyStream(env, strings.NewReader("hello world"))
+	assert.True(t, errors.Is(err, ErrTooManySignatures), "wrong error")
+	_, err = ev.VerifyMultiPayload(&MultiPayloadEnvelope{
+		Payloads:   []EncodedPayload{{PayloadType: payloadType, Payload: env.Payload}},
+		Signatures: env.Signatures,
+	})
+	assert.True(t, errors.Is(err, ErrTooManySignatures), "wrong error")
+	assert.Equal(t, 0, calls, "verifier called")
+}
+
+func TestVerifyEmptySignature(t *testing.T) {
+	e := Envelope{
+		Payload:     "aGVsbG8gd29ybGQ=",
+		PayloadType: "http://example.com/HelloWorld",
+		Signatures: []Signature{
+			{KeyID: "mock", Sig: ""},
+		},
+	}
+
+	var results []error
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{&mockVerifier{}},
+		WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+			results = append(results, err)
+		}))
+	assert.Nil(t, err, "unexpected error")
+
+	// mockVerifier accepts anything, including an empty signature.
+	acceptedKeys, err := ev.Verify(&e)
+	assert.NotNil(t, err, "expected error")
+	assert.Empty(t, acceptedKeys, "unexpected keys")
+	assert.Equal(t, []error{ErrEmptySignature}, results, "wrong signature result")
+}
+
+func TestVerifyNilEnvelope(t *testing.T) {
+	ev, err := NewEnvelopeVerifier(&mockVerifier{})
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = ev.Verify(nil)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	_, err = ev.VerifyStream(nil, strings.NewReader("hello world"))
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	_, err = ev.VerifyMultiPayload(nil)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+
+	_, err = ev.Verify(&Envelope{
+		Payload:     "aGVsbG8gd29ybGQ=",
+		PayloadType: "http://example.com/HelloWorld",
+	})
+	assert.Equal(t, ErrNoSignature, err, "wrong error")
+	_, err = ev.VerifyStream(&Envelope{PayloadType: "http://example.com/HelloWorld"}, strings.NewReader("hello world"))
+	assert.Equal(t, ErrNoSignature, err, "wrong error")
+	_, err = ev.VerifyMultiPayload(&MultiPayloadEnvelope{})
+	assert.Equal(t, ErrNoSignature, err, "wrong error")
+}
+
+func TestVerifyStrictKeyIDMatching(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	noKeyID := *env
+	noKeyID.Signatures = []Signature{env.Signatures[0]}
+	noKeyID.Signatures[0].KeyID = ""
+
+	lenient, err := NewEnvelopeVerifier(sv)
+	assert.Nil(t, err, "unexpected error")
+	strict, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, WithStrictKeyIDMatching())
+	assert.Nil(t, err, "unexpected error")
+
+	_, err = lenient.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	_, err = lenient.Verify(&noKeyID)
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := strict.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ed", acceptedKeys[0].KeyID, "wrong key ID")
+	_, err = strict.Verify(&noKeyID)
+	assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+}
+
+// unavailableVerifier fails like a verifier backed by an unreachable service.
+type unavailableVerifier struct{}
+
+func (unavailableVerifier) Verify(data, sig []byte) error {
+	return fmt.Errorf("%w: connection refused", ErrVerifierUnavailable)
+}
+
+func (unavailableVerifier) KeyID() (string, error) {
+	return "remote", nil
+}
+
+func (unavailableVerifier) Public() crypto.PublicKey {
+	return "remote-public"
+}
+
+func TestVerifyUnavailableVerifier(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("local", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	env.Signatures = append(env.Signatures, Signature{KeyID: "remote", Sig: "c2ln"})
+
+	t.Run("Threshold met", func(t *testing.T) {
+		ev, err := NewEnvelopeVerifier(unavailableVerifier{}, sv)
+		assert.Nil(t, err, "unexpected error")
+		acceptedKeys, err := ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 1, "unexpected keys")
+		assert.Equal(t, "local", acceptedKeys[0].KeyID, "wrong key ID")
+	})
+
+	t.Run("Threshold not met", func(t *testing.T) {
+		ev, err := NewMultiEnvelopeVerifier(2, unavailableVerifier{}, sv)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(env)
+		var verr *VerificationError
+		assert.True(t, errors.As(err, &verr), "wrong error")
+		assert.Equal(t, ErrVerifierUnavailable, verr.Err, "wrong cause")
+	})
+
+	t.Run("Invalid signature takes precedence", func(t *testing.T) {
+		tampered := *env
+		tampered.Payload = base64.StdEncoding.EncodeToString([]byte("tampered"))
+		ev, err := NewEnvelopeVerifier(unavailableVerifier{}, sv)
+		assert.Nil(t, err, "unexpected error")
+		_, err = ev.Verify(&tampered)
+		assert.True(t, errors.Is(err, ErrSignatureInvalid), "wrong error")
+	})
+}
+
+// countingVerifier counts the signatures it is asked to verify.
+type countingVerifier struct {
+	Verifier
+	calls *int
+}
+
+func (v countingVerifier) Verify(data, sig []byte) error {
+	*v.calls++
+	return v.Verifier.Verify(data, sig)
+}
+
+func TestVerifyKeyIDIndex(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	var calls int
+	var verifiers []Verifier
+	for i := 0; i < 20; i++ {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.Nil(t, err, "unexpected error")
+		v, err := NewECDSAVerifier(fmt.Sprintf("key-%d", i), &key.PublicKey)
+		assert.Nil(t, err, "unexpected error")
+		verifiers = append(verifiers, countingVerifier{v, &calls})
+	}
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	verifiers = append(verifiers, countingVerifier{sv, &calls})
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	ev, err := NewEnvelopeVerifier(verifiers...)
+	assert.Nil(t, err, "unexpected error")
+
+	acceptedKeys, err := ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ed", acceptedKeys[0].KeyID, "wrong keyid")
+	assert.Equal(t, 1, calls, "signature offered to verifiers with other key IDs")
+
+	// Without a key ID, the signature is offered to every verifier in order.
+	calls = 0
+	env.Signatures[0].KeyID = ""
+	acceptedKeys, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, "ed", acceptedKeys[0].KeyID, "wrong keyid")
+	assert.Equal(t, len(verifiers), calls, "signature not offered to every verifier")
+}
+
+func TestVerifyRequireAll(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	var ns nilsigner
+	var null nullsigner
+	ev, err := NewEnvelopeVerifierRequireAll(ns, null)
+	assert.Nil(t, err, "unexpected error")
+
+	t.Run("All signed", func(t *testing.T) {
+		signer, err := NewEnvelopeSigner(ns, null)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		acceptedKeys, err := ev.Verify(env)
+		assert.Nil(t, err, "unexpected error")
+		assert.Len(t, acceptedKeys, 2, "unexpected keys")
+	})
+
+	t.Run("One missing", func(t *testing.T) {
+		signer, err := NewEnvelopeSigner(ns)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		_, err = ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrMissingSignature), "wrong error")
+		assert.Contains(t, err.Error(), "KeyID=null", "missing key not reported")
+	})
+
+	t.Run("Same key twice", func(t *testing.T) {
+		signer, err := NewEnvelopeSigner(ns, ns)
+		assert.Nil(t, err, "unexpected error")
+		env, err := signer.SignPayload(payloadType, payload)
+		assert.Nil(t, err, "sign failed")
+
+		_, err = ev.Verify(env)
+		assert.True(t, errors.Is(err, ErrMissingSignature), "wrong error")
+	})
+
+	t.Run("No verifiers", func(t *testing.T) {
+		_, err := NewEnvelopeVerifierRequireAll()
+		assert.NotNil(t, err, "expected error")
+	})
+}
+
+func TestVerifySingleMatchesAll(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+	valid := env.Signatures[0]
+
+	withSig := func(modify func(s *Signature)) Signature {
+		s := valid
+		modify(&s)
+		return s
+	}
+	signatures := map[string]Signature{
+		"valid":        valid,
+		"no key ID":    withSig(func(s *Signature) { s.KeyID = "" }),
+		"other key ID": withSig(func(s *Signature) { s.KeyID = "other" }),
+		"invalid":      withSig(func(s *Signature) { s.Sig = base64.StdEncoding.EncodeToString(make([]byte, 64)) }),
+		"empty":        withSig(func(s *Signature) { s.Sig = "" }),
+		"bad base64":   withSig(func(s *Signature) { s.Sig = "!!!!" }),
+		"other alg": withSig(func(s *Signature) {
+			s.Extensions = map[string]json.RawMessage{ExtensionAlgorithm: json.RawMessage(`"rsa-pss-sha256"`)}
+		}),
+		"malformed alg": withSig(func(s *Signature) {
+			s.Extensions = map[string]json.RawMessage{ExtensionAlgorithm: json.RawMessage(`1`)}
+		}),
+	}
+	options := map[string][]Option{
+		"default":      nil,
+		"strict":       {WithStrictKeyIDMatching()},
+		"allowed algs": {WithAllowedAlgorithms("rsa-pss-sha256")},
+	}
+
+	msg := &message{pae: PAE(payloadType, payload)}
+	for sigName, s := range signatures {
+		for optName, opts := range options {
+			for _, requireAll := range []bool{false, true} {
+				t.Run(fmt.Sprintf("%s/%s/%v", sigName, optName, requireAll), func(t *testing.T) {
+					var singleCalls, allCalls []string
+					record := func(calls *[]string) Option {
+						return WithPerSignatureCallback(func(keyID string, ok bool, err error) {
+							*calls = append(*calls, fmt.Sprintf("%s %v %v", keyID, ok, err))
+						})
+					}
+
+					single, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, append(opts, record(&singleCalls))...)
+					assert.Nil(t, err, "unexpected error")
+					all, err := NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, append(opts, record(&allCalls))...)
+					assert.Nil(t, err, "unexpected error")
+					if requireAll {
+						single.required, all.required = single.keyIDs, all.keyIDs
+					}
+
+					singleKeys, singleErr := single.verifySingle(msg, s)
+					allKeys, allErr := all.verifyAll(msg, []Signature{s})
+					assert.Equal(t, allKeys, singleKeys, "accepted keys differ")
+					assert.Equal(t, allErr, singleErr, "errors differ")
+					assert.Equal(t, allCalls, singleCalls, "callbacks differ")
+				})
+			}
+		}
+	}
+}
+
+func benchmarkVerify(b *testing.B, n int) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	var svs []SignVerifier
+	var vs []Verifier
+	for i := 0; i < n; i++ {
+		seed := make([]byte, ed25519.SeedSize)
+		seed[0] = byte(i)
+		sv, err := NewEd25519SignerVerifier("", ed25519.NewKeyFromSeed(seed))
+		if err != nil {
+			b.Fatal(err)
+		}
+		svs = append(svs, sv)
+		vs = append(vs, sv)
+	}
+
+	signer, err := NewEnvelopeSignerWithOptions(n, svs)
+	if err != nil {
+		b.Fatal(err)
+	}
+	env, err := signer.SignPayload(payloadType, payload)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ev, err := NewEnvelopeVerifierWithOptions(n, vs)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ev.Verify(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkVerifySingle(b *testing.B) {
+	benchmarkVerify(b, 1)
+}
+
+func BenchmarkVerifyMany(b *testing.B) {
+	benchmarkVerify(b, 8)
+}
+
+func TestVerifySignature(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err, "unexpected error")
+
+	edSV, err := NewEd25519SignerVerifier("", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	ecSV, err := NewECDSASignerVerifier("", ecKey)
+	assert.Nil(t, err, "unexpected error")
+	rsaSV, err := NewRSAPSSSignerVerifier("", rsaKey)
+	assert.Nil(t, err, "unexpected error")
+
+	for _, sv := range []SignVerifier{edSV, ecSV, rsaSV} {
+		sig, err := sv.Sign(PAE(payloadType, payload))
+		assert.Nil(t, err, "sign failed")
+
+		assert.Nil(t, VerifySignature(sv.Public(), payloadType, payload, sig), "unexpected error")
+		assert.NotNil(t, VerifySignature(sv.Public(), payloadType, []byte("goodbye world"), sig), "expected error")
+		assert.NotNil(t, VerifySignature(sv.Public(), "other", payload, sig), "expected error")
+	}
+
+	highS := highSSignature(t, ecKey, crypto.SHA384, PAE(payloadType, payload))
+	assert.Nil(t, VerifySignature(&ecKey.PublicKey, payloadType, payload, highS), "high-S signature rejected")
+
+	err = VerifySignature("not a key", payloadType, payload, []byte("sig"))
+	assert.True(t, errors.Is(err, ErrUnsupportedKey), "wrong error")
+}
+
+func TestVerifierKeys(t *testing.T) {
+	ed, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	ec, err := NewECDSAVerifier("", &newEcdsaKey().PublicKey)
+	assert.Nil(t, err, "unexpected error")
+
+	ev, err := NewEnvelopeVerifier(ed, ec)
+	assert.Nil(t, err, "unexpected error")
+
+	assert.Equal(t, []crypto.PublicKey{ed.Public(), ec.Public()}, ev.Keys(), "wrong keys")
+
+	ecKeyID, err := SHA256KeyID(ec.Public())
+	assert.Nil(t, err, "unexpected error")
+	keyIDs := ev.KeyIDs()
+	assert.Equal(t, []string{"ed", ecKeyID}, keyIDs, "wrong key IDs")
+	keyIDs[0] = "changed"
+	assert.Equal(t, "ed", ev.KeyIDs()[0], "key IDs shared")
+}
+
+func TestVerifyAndExtract(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = []byte("hello world")
+
+	sv, err := NewEd25519SignerVerifier("ed", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	es, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := es.SignPayload(payloadType, payload)
+	assert.Nil(t, err, "sign failed")
+
+	gotType, gotPayload, acceptedKeys, err := es.VerifyAndExtract(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, payloadType, gotType, "wrong payload type")
+	assert.Equal(t, payload, gotPayload, "wrong payload")
+	assert.Len(t, acceptedKeys, 1, "wrong number of keys")
+	assert.Equal(t, "ed", acceptedKeys[0].KeyID, "wrong keyid")
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		tampered := *env
+		tampered.Payload = base64.StdEncoding.EncodeToString([]byte("goodbye"))
+
+		gotType, gotPayload, acceptedKeys, err := es.VerifyAndExtract(&tampered)
+		assert.NotNil(t, err, "expected error")
+		assert.Empty(t, gotType, "unexpected payload type")
+		assert.Nil(t, gotPayload, "unexpected payload")
+		assert.Nil(t, acceptedKeys, "unexpected keys")
+	})
+}
+
+func TestWithLogger(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	sv, err := NewEd25519SignerVerifier("signer", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	other, err := NewEd25519SignerVerifier("other", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte("hello?"))
+	assert.Nil(t, err, "sign failed")
+
+	// Re-encode with the URL safe alphabet, which differs for this payload.
+	env.Payload = base64.URLEncoding.EncodeToString([]byte("hello?"))
+	assert.NotEqual(t, base64.StdEncoding.EncodeToString([]byte("hello?")), env.Payload, "payload encodings agree")
+
+	var buf bytes.Buffer
+	logger := bufLogger{&buf}
+	ev, err := NewEnvelopeVerifierWithOptions(1, []Verifier{other, sv}, WithLogger(logger))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+	assert.Contains(t, buf.String(), "trying URL safe alphabet", "fallback not logged")
+	assert.NotContains(t, buf.String(), "no verifier for signature", "unexpected log")
+
+	buf.Reset()
+	ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{other}, WithLogger(logger))
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.NotNil(t, err, "expected error")
+	assert.Contains(t, buf.String(), "no verifier for signature", "unknown key not logged")
+	assert.Contains(t, buf.String(), "signature_keyid=signer", "key ID not logged")
+
+	// Without a logger nothing is logged and verification is unchanged.
+	ev, err = NewEnvelopeVerifier(sv)
+	assert.Nil(t, err, "unexpected error")
+	_, err = ev.Verify(env)
+	assert.Nil(t, err, "unexpected error")
+}
+
+// bufLogger writes debug messages to a buffer, one per line, with the
+// arguments as key=value pairs.
+type bufLogger struct {
+	buf *bytes.Buffer
+}
+
+func (l bufLogger) Debug(msg string, args ...interface{}) {
+	l.buf.WriteString(msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(l.buf, " %v=%v", args[i], args[i+1])
+	}
+	l.buf.WriteByte('\n')
+}
+
+func TestWithVerifyAll(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+
+	sv, err := NewEd25519SignerVerifier("signer", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	signer, err := NewEnvelopeSigner(sv)
+	assert.Nil(t, err, "unexpected error")
+	env, err := signer.SignPayload(payloadType, []byte("hello world"))
+	assert.Nil(t, err, "sign failed")
+
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	assert.Nil(t, err, "unexpected error")
+	sig[0] ^= 0xff
+	tampered := env.Clone()
+	tampered.Signatures = append(tampered.Signatures, Signature{KeyID: "signer", Sig: base64.StdEncoding.EncodeToString(sig)})
+
+	// By default the second signature is not checked.
+	ev, err := NewEnvelopeVerifier(sv)
+	assert.Nil(t, err, "unexpected error")
+	result, err := ev.VerifyDetailed(tampered)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, result.Rejected, 1)
+	assert.ErrorIs(t, result.Rejected[0].Err, ErrUnknownKey, "wrong error")
+
+	ev, err = NewEnvelopeVerifierWithOptions(1, []Verifier{sv}, WithVerifyAll())
+	assert.Nil(t, err, "unexpected error")
+	result, err = ev.VerifyDetailed(tampered)
+	assert.Nil(t, err, "unexpected error")
+	assert.True(t, result.ThresholdMet)
+	assert.Len(t, result.Accepted, 1)
+	assert.Len(t, result.Rejected, 1)
+	assert.NotNil(t, result.Rejected[0].Err, "expected error")
+	assert.NotErrorIs(t, result.Rejected[0].Err, ErrUnknownKey, "wrong error")
+
+	// A second valid signature by the same key is counted once.
+	duplicated := env.Clone()
+	duplicated.Signatures = append(duplicated.Signatures, env.Signatures[0])
+	result, err = ev.VerifyDetailed(duplicated)
+	assert.Nil(t, err, "unexpected error")
+	assert.Len(t, result.Accepted, 1)
+	assert.Empty(t, result.Rejected)
+}
@@ -0,0 +1,84 @@
+package dsse
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotWrapped indicates that an envelope does not wrap another envelope.
+var ErrNotWrapped = errors.New("envelope does not wrap an envelope")
+
+/*
+WrapEnvelope counter-signs inner: it returns an envelope of type
+PayloadTypeDSSE whose payload is the canonical serialization of inner, as
+used by CanonicalHash, signed by signer. The signatures of inner are not
+verified.
+*/
+func WrapEnvelope(inner *Envelope, signer *EnvelopeSigner) (*Envelope, error) {
+	if inner == nil {
+		return nil, ErrNoEnvelopes
+	}
+	if signer == nil {
+		return nil, ErrNoSigners
+	}
+
+	data, err := inner.canonicalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return signer.SignPayload(PayloadTypeDSSE, data)
+}
+
+/*
+UnwrapEnvelope returns the envelope wrapped by outer with WrapEnvelope. No
+signature is verified; use VerifyWrapped to verify the envelopes as well.
+*/
+func UnwrapEnvelope(outer *Envelope) (*Envelope, error) {
+	if outer == nil {
+		return nil, ErrNoEnvelopes
+	}
+	if outer.PayloadType != PayloadTypeDSSE {
+		return nil, ErrNotWrapped
+	}
+
+	data, err := outer.DecodedPayload()
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateEnvelopeJSON(data); err != nil {
+		return nil, err
+	}
+
+	var inner Envelope
+	if err := json.Unmarshal(data, &inner); err != nil {
+		return nil, err
+	}
+
+	return &inner, nil
+}
+
+/*
+VerifyWrapped verifies outer with outerVerifier and returns the envelope it
+wraps. If innerVerifier is not nil, the wrapped envelope is verified with it
+as well, so that both the counter-signature and the original signatures are
+checked.
+*/
+func VerifyWrapped(outer *Envelope, outerVerifier, innerVerifier EnvelopeVerifier) (*Envelope, error) {
+	if _, err := outerVerifier.Verify(outer); err != nil {
+		return nil, err
+	}
+
+	inner, err := UnwrapEnvelope(outer)
+	if err != nil {
+		return nil, err
+	}
+
+	if innerVerifier != nil {
+		if _, err := innerVerifier.Verify(inner); err != nil {
+			return nil, err
+		}
+	}
+
+	return inner, nil
+}
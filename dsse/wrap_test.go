@@ -0,0 +1,64 @@
+package dsse
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapEnvelope(t *testing.T) {
+	var payloadType = "http://example.com/HelloWorld"
+	var payload = "hello world"
+
+	author, err := NewEd25519SignerVerifier("author", newEd25519Key())
+	assert.Nil(t, err, "unexpected error")
+	notaryKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err, "unexpected error")
+	notary, err := NewECDSASignerVerifier("notary", notaryKey)
+	assert.Nil(t, err, "unexpected error")
+
+	authorSigner, err := NewEnvelopeSigner(author)
+	assert.Nil(t, err, "unexpected error")
+	notarySigner, err := NewEnvelopeSigner(notary)
+	assert.Nil(t, err, "unexpected error")
+
+	inner, err := authorSigner.SignPayload(payloadType, []byte(payload))
+	assert.Nil(t, err, "sign failed")
+
+	outer, err := WrapEnvelope(inner, notarySigner)
+	assert.Nil(t, err, "unexpected error")
+	assert.Equal(t, PayloadTypeDSSE, outer.PayloadType, "wrong payload type")
+
+	unwrapped, err := UnwrapEnvelope(outer)
+	assert.Nil(t, err, "unexpected error")
+	assert.True(t, inner.Equal(unwrapped), "wrong inner envelope")
+
+	verified, err := VerifyWrapped(outer, notarySigner, authorSigner)
+	assert.Nil(t, err, "unexpected error")
+	assert.True(t, inner.Equal(verified), "wrong inner envelope")
+
+	_, err = VerifyWrapped(outer, notarySigner, nil)
+	assert.Nil(t, err, "unexpected error")
+
+	// The notary cannot vouch for the author.
+	_, err = VerifyWrapped(outer, notarySigner, notarySigner)
+	assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+	_, err = VerifyWrapped(outer, authorSigner, nil)
+	assert.True(t, errors.Is(err, ErrNoMatchingKey), "wrong error")
+
+	_, err = UnwrapEnvelope(inner)
+	assert.Equal(t, ErrNotWrapped, err, "wrong error")
+	_, err = UnwrapEnvelope(nil)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+	_, err = WrapEnvelope(nil, notarySigner)
+	assert.Equal(t, ErrNoEnvelopes, err, "wrong error")
+
+	notEnvelope, err := notarySigner.SignPayload(PayloadTypeDSSE, []byte("{}"))
+	assert.Nil(t, err, "sign failed")
+	_, err = UnwrapEnvelope(notEnvelope)
+	assert.True(t, errors.Is(err, ErrInvalidEnvelope), "wrong error")
+}
@@ -1,16 +1,19 @@
 module github.com/secure-systems-lab/go-securesystemslib
 
-go 1.17
+// Go 1.20 is required by the certificate and revocation checks of dsse/cosign
+// and dsse/bundle. The dsse package itself builds with Go 1.17, without
+// Ed25519ph.
+go 1.20
 
 require (
 	github.com/codahale/rfc6979 v0.0.0-20141003034818-6a90f24967eb
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.3.0
 	github.com/stretchr/testify v1.7.0
 	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 )
 
-
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
-)
\ No newline at end of file
+)